@@ -25,6 +25,8 @@ type anchor struct {
 	children map[string]*anchor
 	nhandle int
 	value interface{}
+	exited bool // true if the last process element stored at this anchor has exited
+	labels map[string]string
 	tx sync.Mutex
 }
 
@@ -63,7 +65,7 @@ func (a *anchor) Busy() bool {
 }
 
 func (a *anchor) busy() bool {
-	return a.nhandle > 0 || a.value != nil || len(a.children) > 0
+	return a.nhandle > 0 || a.value != nil || len(a.children) > 0 || len(a.labels) > 0
 }
 
 func (a *anchor) scrub(name string) {
@@ -137,3 +139,63 @@ func (a *anchor) Get() interface{} {
 	defer a.lk.Unlock()
 	return a.value
 }
+
+// SetExited records whether the last process element stored at this anchor has exited.
+func (a *anchor) SetExited(v bool) {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	a.exited = v
+}
+
+// Exited returns true if the last process element stored at this anchor has exited.
+func (a *anchor) Exited() bool {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	return a.exited
+}
+
+// SetLabel sets the label key to value. An empty value removes the label.
+// Labeled anchors are not garbage-collected.
+func (a *anchor) SetLabel(key, value string) {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	if value == "" {
+		delete(a.labels, key)
+		if !a.busy() && a.parent != nil {
+			go a.parent.scrub(a.name)
+		}
+		return
+	}
+	if a.labels == nil {
+		a.labels = make(map[string]string)
+	}
+	a.labels[key] = value
+}
+
+// Labels returns a copy of the labels of this anchor.
+func (a *anchor) Labels() map[string]string {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	r := make(map[string]string)
+	for k, v := range a.labels {
+		r[k] = v
+	}
+	return r
+}
+
+// labeled adds to r the paths of the anchors in this subtree that hold an element and carry the label key,
+// mapped to the label's value.
+func (a *anchor) labeled(key string, r map[string]string) {
+	a.lk.Lock()
+	if v, ok := a.labels[key]; ok && a.value != nil {
+		r[a.Path()] = v
+	}
+	children := make([]*anchor, 0, len(a.children))
+	for _, q := range a.children {
+		children = append(children, q)
+	}
+	a.lk.Unlock()
+	for _, q := range children {
+		q.labeled(key, r)
+	}
+}
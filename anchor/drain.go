@@ -0,0 +1,84 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package anchor
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gocircuit/circuit/element/proc"
+)
+
+// drain is shared by all terminals of a server, and is set once the server begins to drain.
+type drain struct {
+	sync.Mutex
+	on bool
+}
+
+func (d *drain) set() {
+	d.Lock()
+	defer d.Unlock()
+	d.on = true
+}
+
+func (d *drain) draining() bool {
+	if d == nil {
+		return false
+	}
+	d.Lock()
+	defer d.Unlock()
+	return d.on
+}
+
+// Drain stops this server from accepting new elements. If signal is not empty, the processes
+// of the server are sent the signal. Drain then waits for up to wait for the processes to exit,
+// and returns the number of those still running.
+func (t *Terminal) Drain(signal string, wait time.Duration) int {
+	t.drain.set()
+	log.Println("Draining; no new elements are accepted")
+	procs := t.root().procs(nil)
+	exit := make(chan struct{}, len(procs))
+	for _, p := range procs {
+		if signal != "" {
+			p.Signal(signal) // fails for processes that have exited
+		}
+		go func(p proc.Proc) {
+			p.Wait()
+			exit <- struct{}{}
+		}(p)
+	}
+	timeout := time.After(wait)
+	for n := len(procs); n > 0; n-- {
+		select {
+		case <-exit:
+		case <-timeout:
+			return n
+		}
+	}
+	return 0
+}
+
+// procs appends to r the process elements in this subtree.
+func (a *anchor) procs(r []proc.Proc) []proc.Proc {
+	a.lk.Lock()
+	if u, ok := a.value.(*urn); ok {
+		if p, ok := u.elem.(proc.Proc); ok {
+			r = append(r, p)
+		}
+	}
+	children := make([]*anchor, 0, len(a.children))
+	for _, q := range a.children {
+		children = append(children, q)
+	}
+	a.lk.Unlock()
+	for _, q := range children {
+		r = q.procs(r)
+	}
+	return r
+}
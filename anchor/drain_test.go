@@ -0,0 +1,38 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package anchor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocircuit/circuit/element/proc"
+	"github.com/gocircuit/circuit/kit/pubsub"
+)
+
+func TestDrain(t *testing.T) {
+	root := &Terminal{
+		events: pubsub.New("events", nil),
+		drain:  &drain{},
+		anchor: newAnchor(nil, "X1").use(),
+	}
+	p, err := root.Walk([]string{"job", "sleep"}).Make(Proc, proc.Cmd{Path: "/bin/sleep", Args: []string{"60"}})
+	if err != nil {
+		t.Fatalf("make proc (%s)", err)
+	}
+	p.(proc.Proc).Stdin().Close()
+	if n := root.Drain("", 0); n != 1 {
+		t.Fatalf("expecting 1 running process, got %d", n)
+	}
+	if _, err := root.Walk([]string{"other"}).Make(Chan, 0); err == nil {
+		t.Fatalf("draining server accepted a new element")
+	}
+	if n := root.Drain("TERM", 5*time.Second); n != 0 {
+		t.Fatalf("expecting signaled process to exit, %d still running", n)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package anchor
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gocircuit/circuit/kit/pubsub"
+	"github.com/gocircuit/circuit/use/circuit"
+)
+
+// Kinds of element lifecycle events
+const (
+	EventStart     = "start"     // a process or container was started
+	EventRestart   = "restart"   // a process was started at an anchor whose previous process had exited
+	EventExit      = "exit"      // a process or container exited
+	EventClose     = "close"     // a channel was closed
+	EventSet       = "set"       // a DNS record was set
+	EventUnset     = "unset"     // the DNS records for a name were removed
+	EventScrub     = "scrub"     // the element at an anchor was scrubbed
+	EventLabel     = "label"     // a label of an anchor was set or removed
+	EventLoad      = "load"      // the records of a nameserver zone were replaced
+	EventDocker    = "docker"    // the docker engine reported an event for a container, e.g. oom
+	EventPartition = "partition" // the server lost contact with a large part of the circuit at once
+	EventMerge     = "merge"     // the server rejoined a peer it had lost contact with
+)
+
+// Event describes a lifecycle event of the element stored at an anchor.
+type Event struct {
+	Anchor  string // path of the anchor, e.g. /X123/job
+	Element string // kind of the element, e.g. proc
+	Kind    string // kind of the event, e.g. exit
+	Detail  string // event-specific detail, e.g. the exit error of a process or a DNS record
+	Time    time.Time
+}
+
+func init() {
+	gob.Register(Event{})
+	gob.Register(EventSpec{})
+	gob.Register(SubscriptionSpec{})
+	circuit.RegisterValue(&eventSubscription{})
+}
+
+func (e Event) String() string {
+	s := fmt.Sprintf("%s %s %s %s", e.Time.Format(time.RFC3339Nano), e.Anchor, e.Element, e.Kind)
+	if e.Detail != "" {
+		s += " " + e.Detail
+	}
+	return s
+}
+
+// EventSpec is the argument for making an OnEvent subscription element.
+type EventSpec struct {
+	Source string   // anchor path of the event source; it must be hosted by the same server
+	Kinds  []string // kinds of events of interest; all kinds, if empty
+	Resume int64    // if positive, resume the stream after the event with this sequence number
+}
+
+// SubscriptionSpec is the argument for making OnJoin and OnLeave subscription elements.
+type SubscriptionSpec struct {
+	Prefix  []string // if not empty, only servers whose ID begins with one of these prefixes are reported
+	Resume  int64    // if positive, resume the stream after the event with this sequence number
+	Suspect bool     // if set, OnLeave reports servers when they become suspected of failure, rather than when they depart
+}
+
+// match returns a match for server anchor paths, or nil if all servers are of interest.
+func (spec SubscriptionSpec) match() pubsub.Match {
+	if len(spec.Prefix) == 0 {
+		return nil
+	}
+	return func(v interface{}) bool {
+		id := strings.TrimPrefix(v.(string), "/")
+		for _, p := range spec.Prefix {
+			if strings.HasPrefix(id, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// subscriptionSpec interprets the argument for making OnJoin and OnLeave elements.
+func subscriptionSpec(arg interface{}) (SubscriptionSpec, error) {
+	switch a := arg.(type) {
+	case string: // clients prior to subscription specs
+		return SubscriptionSpec{}, nil
+	case SubscriptionSpec:
+		return a, nil
+	}
+	return SubscriptionSpec{}, errors.New("invalid argument")
+}
+
+// Publish announces an event concerning the element of kind elem at this anchor.
+// It is used by the hosting server to report events not caused by element operations.
+func (t *Terminal) Publish(elem, kind, detail string) {
+	t.publish(elem, kind, detail)
+}
+
+// publish announces an event concerning the element at this anchor.
+func (t *Terminal) publish(elem, kind, detail string) {
+	t.events.Publish(Event{
+		Anchor:  t.Path(),
+		Element: elem,
+		Kind:    kind,
+		Detail:  detail,
+		Time:    time.Now(),
+	})
+}
+
+// subscribe creates a subscription to the events of the anchor described by spec.
+func (t *Terminal) subscribe(spec EventSpec) (*eventSubscription, error) {
+	walk := strings.Split(strings.Trim(spec.Source, "/"), "/")
+	if len(walk) < 2 || walk[0] != t.carrier().walk[0] {
+		return nil, errors.New("event source must be an anchor hosted by the same server")
+	}
+	source := t.root().Walk(walk[1:])
+	src, kinds := source.Path(), kindMatch(spec.Kinds)
+	sub, err := t.events.SubscribeFrom(spec.Resume, func(v interface{}) bool {
+		return v.(Event).Anchor == src && (kinds == nil || kinds(v))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &eventSubscription{Subscription: sub, source: source}, nil
+}
+
+// kindMatch returns a match for events of the given kinds, or nil if all kinds are of interest.
+func kindMatch(kinds []string) pubsub.Match {
+	if len(kinds) == 0 {
+		return nil
+	}
+	k := make(map[string]bool)
+	for _, x := range kinds {
+		k[x] = true
+	}
+	return func(v interface{}) bool {
+		return k[v.(Event).Kind]
+	}
+}
+
+// eventSubscription delivers the events concerning a single source anchor.
+type eventSubscription struct {
+	*pubsub.Subscription
+	source *Anchor // holding the source anchor keeps its event history alive
+}
+
+func (s *eventSubscription) Peek() pubsub.Stat {
+	stat := s.Subscription.Peek()
+	stat.Source = s.source.Path()
+	return stat
+}
+
+func (s *eventSubscription) X() circuit.X {
+	return circuit.Ref(s)
+}
@@ -0,0 +1,54 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package anchor
+
+import (
+	"testing"
+
+	"github.com/gocircuit/circuit/element/proc"
+	"github.com/gocircuit/circuit/kit/pubsub"
+)
+
+func TestOnEvent(t *testing.T) {
+	root := &Terminal{
+		events: pubsub.New("events", nil),
+		anchor: newAnchor(nil, "X1").use(),
+	}
+	if _, err := root.Walk([]string{"w"}).Make(OnEvent, EventSpec{Source: "/X2/job"}); err == nil {
+		t.Fatalf("expecting foreign source to be rejected")
+	}
+	elem, err := root.Walk([]string{"w"}).Make(OnEvent, EventSpec{Source: "/X1/job", Kinds: []string{EventStart, EventRestart, EventExit}})
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	sub := elem.(*eventSubscription)
+	expect := func(kind string) {
+		v, ok := sub.Consume()
+		if !ok {
+			t.Fatalf("subscription closed")
+		}
+		if e := v.(Event); e.Kind != kind || e.Anchor != "/X1/job" {
+			t.Fatalf("expecting %s event, got %v", kind, e)
+		}
+	}
+	job := root.Walk([]string{"job"})
+	run := func() {
+		p, err := job.Make(Proc, proc.Cmd{Path: "/bin/true"})
+		if err != nil {
+			t.Fatalf("make proc (%s)", err)
+		}
+		p.(proc.Proc).Stdin().Close()
+	}
+	run()
+	expect(EventStart)
+	expect(EventExit)
+	job.Scrub() // not subscribed to
+	run()
+	expect(EventRestart)
+	expect(EventExit)
+}
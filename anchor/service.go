@@ -0,0 +1,289 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package anchor
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocircuit/circuit/element/dns"
+)
+
+const (
+	// serviceTTL is the TTL, in seconds, of the records derived from the anchor namespace.
+	serviceTTL = 10
+	// serviceRefresh is the period of full refreshes, which catch changes whose events were missed.
+	serviceRefresh = 30 * time.Second
+	// serviceSettle is the delay that coalesces bursts of events into a single refresh.
+	serviceSettle = 250 * time.Millisecond
+)
+
+// serviceEvents are the kinds of events that may change the set of services.
+var serviceEvents = []string{EventStart, EventRestart, EventScrub, EventLabel}
+
+// serviceSync keeps the records of a nameserver under a service zone in sync with the live servers of
+// the circuit and the anchors labeled as services. Each server is published as <server-id>.<zone> and,
+// if its root anchor has an alias label, as <alias>.<zone>. Each service anchor is published
+// as <anchor-name>.<service>.<zone>. All names resolve to the IP address of the hosting server.
+type serviceSync struct {
+	t     *Terminal
+	ns    dns.Nameserver
+	zone  string
+	kick  chan struct{}
+	abort chan struct{}
+	stop  sync.Once
+	sync.Mutex
+	subs       []consumer          // subscriptions to local streams
+	watched    map[string]consumer // subscriptions to the events of remote servers, by server ID
+	unresolved map[string]bool     // IDs of servers without a specific address, which have been logged
+}
+
+type consumer interface {
+	Consume() (interface{}, bool)
+	Scrub()
+}
+
+func newServiceSync(t *Terminal, ns dns.Nameserver, zone string) *serviceSync {
+	if !strings.HasSuffix(zone, ".") {
+		zone += "."
+	}
+	s := &serviceSync{
+		t:          t,
+		ns:         ns,
+		zone:       strings.ToLower(zone),
+		kick:       make(chan struct{}, 1),
+		abort:      make(chan struct{}),
+		watched:    make(map[string]consumer),
+		unresolved: make(map[string]bool),
+	}
+	if t.genus != nil {
+		if sub, err := t.genus.NewArrivals(0, nil); err == nil {
+			s.subs = append(s.subs, sub)
+		}
+		if sub, err := t.genus.NewDepartures(0, nil); err == nil {
+			s.subs = append(s.subs, sub)
+		}
+	}
+	sub, _ := t.events.SubscribeFrom(0, kindMatch(serviceEvents))
+	s.subs = append(s.subs, sub)
+	for _, sub := range s.subs {
+		go s.watch(sub)
+	}
+	go s.loop()
+	return s
+}
+
+// Stop ends the synchronization, and scrubs the subscriptions of the watching goroutines, so that they exit.
+func (s *serviceSync) Stop() {
+	s.stop.Do(func() {
+		close(s.abort)
+		s.Lock()
+		subs := append([]consumer{}, s.subs...)
+		for _, c := range s.watched {
+			if c != nil {
+				subs = append(subs, c)
+			}
+		}
+		s.Unlock()
+		for _, c := range subs {
+			go scrub(c)
+		}
+	})
+}
+
+// scrub ends the subscription c, which may be hosted by a remote server.
+func scrub(c consumer) {
+	defer func() {
+		recover() // the remote server died
+	}()
+	c.Scrub()
+}
+
+func (s *serviceSync) trigger() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// watch triggers a refresh on every value consumed from c, until c ends or the synchronization stops.
+func (s *serviceSync) watch(c consumer) {
+	defer func() {
+		recover() // the stream of a remote server broke
+	}()
+	for {
+		if _, ok := c.Consume(); !ok {
+			return
+		}
+		select {
+		case <-s.abort:
+			return
+		default:
+		}
+		s.trigger()
+	}
+}
+
+// watchHost starts watching the service events of a remote server, unless already watched.
+func (s *serviceSync) watchHost(h Host) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.watched[h.ID]; ok {
+		return
+	}
+	s.watched[h.ID] = nil // the subscription is made outside the lock
+	go func() {
+		defer func() {
+			s.Lock()
+			delete(s.watched, h.ID)
+			s.Unlock()
+		}()
+		defer func() {
+			recover()
+		}()
+		c := YTerminal{h.Term}.Events(serviceEvents)
+		s.Lock()
+		select {
+		case <-s.abort: // stopped while subscribing
+			s.Unlock()
+			scrub(c)
+			return
+		default:
+		}
+		s.watched[h.ID] = c
+		s.Unlock()
+		s.watch(c)
+	}()
+}
+
+func (s *serviceSync) loop() {
+	for {
+		s.refresh()
+		select {
+		case <-s.abort:
+			return
+		case <-s.kick:
+		case <-time.After(serviceRefresh):
+		}
+		select {
+		case <-s.abort:
+			return
+		case <-time.After(serviceSettle):
+		}
+		select {
+		case <-s.kick:
+		default:
+		}
+	}
+}
+
+// refresh replaces the records under the service zone with ones reflecting the current state of the circuit.
+func (s *serviceSync) refresh() {
+	if s.t.genus == nil {
+		return
+	}
+	var rr []string
+	for _, h := range s.t.genus.Hosts() {
+		rr = append(rr, s.records(h)...)
+	}
+	if err := s.ns.Load(s.zone, rr); err != nil {
+		log.Printf("Updating service zone %s (%v)", s.zone, err)
+	}
+}
+
+// records returns the records describing the server h and its services.
+func (s *serviceSync) records(h Host) (rr []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			rr = nil // the server died
+		}
+	}()
+	var labels, services map[string]string
+	if h.ID == s.t.root().name {
+		labels, services = s.t.root().Labels(), s.t.Services()
+	} else {
+		s.watchHost(h)
+		y := YTerminal{h.Term}
+		labels, services = y.Labels(), y.Services()
+	}
+	ip := hostIP(h.Addr)
+	if ip == nil || ip.IsUnspecified() {
+		s.unresolvable(h)
+	}
+	return serviceRecords(s.zone, h.ID, ip, labels, services)
+}
+
+// unresolvable logs, once per server, that the server h gets no records, since it listens
+// on all interfaces of its host and so does not advertise a specific address.
+func (s *serviceSync) unresolvable(h Host) {
+	s.Lock()
+	defer s.Unlock()
+	if s.unresolved[h.ID] {
+		return
+	}
+	s.unresolved[h.ID] = true
+	log.Printf("Server %s has no specific address (%v), and no records under service zone %s", h.ID, h.Addr, s.zone)
+}
+
+func hostIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// serviceRecords returns the address records of the server with the given ID, root anchor labels and services.
+// Names that are not valid domain names are skipped.
+func serviceRecords(zone, id string, ip net.IP, labels, services map[string]string) (rr []string) {
+	if ip == nil || ip.IsUnspecified() {
+		return nil
+	}
+	typ := "A"
+	if ip.To4() == nil {
+		typ = "AAAA"
+	}
+	add := func(names ...string) {
+		for _, n := range names {
+			if !validDomain(n) {
+				return
+			}
+		}
+		rr = append(rr, fmt.Sprintf("%s.%s %d IN %s %s", strings.ToLower(strings.Join(names, ".")), zone, serviceTTL, typ, ip))
+	}
+	add(id)
+	if alias, ok := labels[AliasLabel]; ok {
+		add(alias)
+	}
+	for p, service := range services {
+		add(path.Base(p), service)
+	}
+	return rr
+}
+
+// validDomain returns true if s is a sequence of dot-separated labels, made of letters, digits and inner hyphens.
+func validDomain(s string) bool {
+	for _, l := range strings.Split(s, ".") {
+		if len(l) == 0 || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for _, c := range l {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package anchor
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gocircuit/circuit/element/dns"
+	"github.com/gocircuit/circuit/kit/pubsub"
+	"github.com/gocircuit/circuit/tissue/tube"
+)
+
+type testGenus []Host
+
+func (g testGenus) NewArrivals(int64, pubsub.Match) (pubsub.Consumer, error) {
+	return nil, errors.New("not supported")
+}
+
+func (g testGenus) NewDepartures(int64, pubsub.Match) (pubsub.Consumer, error) {
+	return nil, errors.New("not supported")
+}
+
+func (g testGenus) NewSuspects(int64, pubsub.Match) (pubsub.Consumer, error) {
+	return nil, errors.New("not supported")
+}
+
+func (g testGenus) Hosts() []Host {
+	return g
+}
+
+func (g testGenus) Tube(string) *tube.Tube {
+	return nil
+}
+
+func TestServiceZone(t *testing.T) {
+	root := &Terminal{
+		genus:  testGenus{{ID: "X1", Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1}}},
+		events: pubsub.New("events", nil),
+		anchor: newAnchor(nil, "X1").use(),
+	}
+	a := root.Walk([]string{"dns"})
+	elem, err := a.Make(Nameserver, dns.Spec{Addr: "127.0.0.1:0", ServiceZone: "circuit"})
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	defer elem.Scrub()
+	ns := elem.(dns.Nameserver)
+	if err = ns.Set("manual.circuit. 60 IN A 10.0.0.9"); err != nil {
+		t.Fatalf("set (%s)", err)
+	}
+	a.Label(ServiceLabel, "ns")
+	root.Label(AliasLabel, "Alpha")
+
+	want := []string{"x1.circuit.", "alpha.circuit.", "dns.ns.circuit."}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		rr := ns.Peek().Records
+		var n int
+		for _, name := range want {
+			if len(rr[name]) == 1 {
+				n++
+			}
+		}
+		if n == len(want) {
+			if len(rr["manual.circuit."]) != 0 {
+				t.Fatalf("records set manually within the service zone must be replaced")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("service records not published, got %v", rr)
+		}
+	}
+	a.Label(ServiceLabel, "")
+	for deadline := time.Now().Add(5 * time.Second); len(ns.Peek().Records["dns.ns.circuit."]) != 0; time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("service record not removed")
+		}
+	}
+}
+
+func TestServiceRecords(t *testing.T) {
+	rr := serviceRecords("circuit.", "X1", net.ParseIP("fe80::1"), nil, map[string]string{
+		"/X1/web":     "http",
+		"/X1/bad_one": "http",
+		"/X1/db":      "-db",
+	})
+	if len(rr) != 2 || rr[0] != "x1.circuit. 10 IN AAAA fe80::1" || rr[1] != "web.http.circuit. 10 IN AAAA fe80::1" {
+		t.Fatalf("unexpected records %v", rr)
+	}
+	if rr = serviceRecords("circuit.", "X1", net.IPv4zero, nil, nil); len(rr) != 0 {
+		t.Fatalf("expecting no records for unspecified address, got %v", rr)
+	}
+}
+
+func TestServiceStop(t *testing.T) {
+	root := &Terminal{
+		events: pubsub.New("events", nil),
+		anchor: newAnchor(nil, "X1").use(),
+	}
+	s := newServiceSync(root, nil, "circuit")
+	s.Stop()
+	done := make(chan bool)
+	go func() {
+		_, ok := s.subs[0].Consume()
+		done <- ok
+	}()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatalf("event subscription not scrubbed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("event subscription left open after stop")
+	}
+}
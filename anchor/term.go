@@ -9,16 +9,22 @@ package anchor
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"net"
+	"path"
+	"strings"
 
 	ds "github.com/gocircuit/circuit/client/docker"
 	"github.com/gocircuit/circuit/element/dns"
 	"github.com/gocircuit/circuit/element/docker"
 	"github.com/gocircuit/circuit/element/proc"
 	srv "github.com/gocircuit/circuit/element/server"
+	"github.com/gocircuit/circuit/element/table"
 	"github.com/gocircuit/circuit/element/valve"
 	"github.com/gocircuit/circuit/kit/pubsub"
+	"github.com/gocircuit/circuit/tissue/tube"
 	"github.com/gocircuit/circuit/use/circuit"
 )
 
@@ -33,25 +39,47 @@ const (
 	Proc       = "proc"
 	Docker     = "docker"
 	Nameserver = "dns"
+	Table      = "table"
 	OnJoin     = "@join"
 	OnLeave    = "@leave"
+	OnEvent    = "@event"
+)
+
+// Labels with special meaning
+const (
+	ServiceLabel = "service" // name of the service provided by the element at a labeled anchor
+	AliasLabel   = "alias"   // alternative name of a server, when set on its root anchor
 )
 
 // Terminal presents a facade to *Anchor with added element manipulation methods
 type Terminal struct {
 	genus  Genus
+	events *pubsub.PubSub // lifecycle events of all elements on this server
+	drain  *drain         // set once this server begins to drain
 	anchor *Anchor
 }
 
 type Genus interface {
-	NewArrivals() pubsub.Consumer
-	NewDepartures() pubsub.Consumer
+	NewArrivals(from int64, match pubsub.Match) (pubsub.Consumer, error)
+	NewDepartures(from int64, match pubsub.Match) (pubsub.Consumer, error)
+	NewSuspects(from int64, match pubsub.Match) (pubsub.Consumer, error)
+	Hosts() []Host
+	Tube(topic string) *tube.Tube // tube shared by all servers under topic, or nil
+}
+
+// Host describes a live server of the circuit.
+type Host struct {
+	ID   string    // ID of the server, which is also the name of its root anchor
+	Addr net.Addr  // networking address of the server
+	Term circuit.X // cross-interface to the root XTerminal of the server
 }
 
 // NewTerm create the root node of a new anchor file system.
 func NewTerm(name string, genus Genus) (*Terminal, circuit.PermX) {
 	t := &Terminal{
 		genus:  genus,
+		events: pubsub.New("events", nil),
+		drain:  &drain{},
 		anchor: newAnchor(nil, name).use(),
 	}
 	return t, circuit.PermRef(XTerminal{t})
@@ -61,9 +89,20 @@ func (t *Terminal) carrier() *Anchor {
 	return t.anchor
 }
 
+// root returns the root anchor of this server.
+func (t *Terminal) root() *anchor {
+	a := t.carrier().anchor
+	for a.parent != nil {
+		a = a.parent
+	}
+	return a
+}
+
 func (t *Terminal) Walk(walk []string) *Terminal {
 	return &Terminal{
 		genus:  t.genus,
+		events: t.events,
+		drain:  t.drain,
 		anchor: t.carrier().Walk(walk),
 	}
 }
@@ -77,12 +116,36 @@ func (t *Terminal) View() map[string]*Terminal {
 	for n, a := range t.carrier().View() {
 		r[n] = &Terminal{
 			genus:  t.genus,
+			events: t.events,
+			drain:  t.drain,
 			anchor: a,
 		}
 	}
 	return r
 }
 
+// Label sets the label key of this anchor to value. An empty value removes the label.
+// Anchors labeled as "service" publish their elements in the service DNS of the circuit,
+// and the "alias" label of a server's root anchor gives the server an alternative DNS name.
+func (t *Terminal) Label(key, value string) {
+	t.carrier().SetLabel(key, value)
+	kind, _ := t.Get()
+	t.publish(kind, EventLabel, key+"="+value)
+}
+
+// Labels returns the labels of this anchor.
+func (t *Terminal) Labels() map[string]string {
+	return t.carrier().Labels()
+}
+
+// Services returns the paths of the anchors on this server that hold an element and are labeled as services,
+// mapped to the names of their services.
+func (t *Terminal) Services() map[string]string {
+	r := make(map[string]string)
+	t.root().labeled(ServiceLabel, r)
+	return r
+}
+
 type urn struct {
 	kind string
 	elem Element // valve.Valve, proc.Proc, etc
@@ -112,15 +175,27 @@ func (t *Terminal) Make(kind string, arg interface{}) (elem Element, err error)
 	if t.carrier().Get() != nil {
 		return nil, errors.New("anchor already has an element")
 	}
+	if t.drain.draining() {
+		return nil, errors.New("server is draining")
+	}
 	switch kind {
 	case Chan:
-		capacity, ok := arg.(int)
-		if !ok {
+		var spec valve.Spec
+		switch a := arg.(type) {
+		case int:
+			spec.Cap = a
+		case valve.Spec:
+			spec = a
+		default:
 			return nil, errors.New("invalid argument")
 		}
+		v, err := valve.Make(t.relPath(), spec)
+		if err != nil {
+			return nil, err
+		}
 		u := &urn{
 			kind: Chan,
-			elem: &scrubValve{t, valve.MakeValve(capacity)},
+			elem: &scrubValve{t, v},
 		}
 		t.carrier().Set(u)
 		return u.elem, nil
@@ -135,6 +210,12 @@ func (t *Terminal) Make(kind string, arg interface{}) (elem Element, err error)
 			elem: proc.MakeProc(cmd),
 		}
 		t.carrier().Set(u)
+		if t.carrier().Exited() {
+			t.publish(Proc, EventRestart, "")
+		} else {
+			t.publish(Proc, EventStart, "")
+		}
+		t.carrier().SetExited(false)
 		go func() {
 			defer func() {
 				recover()
@@ -142,7 +223,16 @@ func (t *Terminal) Make(kind string, arg interface{}) (elem Element, err error)
 			if cmd.Scrub {
 				defer t.Scrub()
 			}
-			u.elem.(proc.Proc).Wait()
+			stat, err := u.elem.(proc.Proc).Wait()
+			if err != nil {
+				return // aborted
+			}
+			t.carrier().SetExited(true)
+			var detail string
+			if stat.Exit != nil {
+				detail = stat.Exit.Error()
+			}
+			t.publish(Proc, EventExit, detail)
 		}()
 		return u.elem, nil
 
@@ -160,6 +250,10 @@ func (t *Terminal) Make(kind string, arg interface{}) (elem Element, err error)
 			elem: x,
 		}
 		t.carrier().Set(u)
+		t.publish(Docker, EventStart, "")
+		docker.Watch(x, func(action string) {
+			t.publish(Docker, EventDocker, action)
+		})
 		go func() {
 			defer func() {
 				recover()
@@ -167,34 +261,124 @@ func (t *Terminal) Make(kind string, arg interface{}) (elem Element, err error)
 			if run.Scrub {
 				defer t.Scrub()
 			}
-			u.elem.(docker.Container).Wait()
+			stat, err := u.elem.(docker.Container).Wait()
+			var detail string
+			if err == nil && stat != nil {
+				detail = fmt.Sprintf("exit code %d", stat.State.ExitCode)
+			}
+			t.publish(Docker, EventExit, detail)
 		}()
 		return u.elem, nil
 
 	case Nameserver:
-		ns, err := dns.MakeNameserver(arg.(string))
+		var spec dns.Spec
+		switch a := arg.(type) {
+		case string:
+			spec.Addr = a
+		case dns.Spec:
+			spec = a
+		default:
+			return nil, errors.New("invalid argument")
+		}
+		var ns dns.Nameserver
+		var err error
+		if spec.Replicate != "" {
+			var tb *tube.Tube
+			if t.genus != nil {
+				tb = t.genus.Tube(dns.ReplicaTopic)
+			}
+			if tb == nil {
+				return nil, errors.New("nameserver replication not available")
+			}
+			ns, err = dns.MakeReplica(spec, tb)
+		} else {
+			ns, err = dns.Make(spec)
+		}
 		if err != nil {
 			return nil, err
 		}
+		ens := &eventNameserver{t: t, Nameserver: ns}
+		if spec.ServiceZone != "" {
+			ens.services = newServiceSync(t, ns, spec.ServiceZone)
+		}
 		u := &urn{
 			kind: Nameserver,
-			elem: ns,
+			elem: ens,
+		}
+		t.carrier().Set(u)
+		return u.elem, nil
+
+	case Table:
+		topic, ok := arg.(string)
+		if !ok {
+			return nil, errors.New("invalid argument")
+		}
+		var tb *tube.Tube
+		if t.genus != nil {
+			tb = t.genus.Tube(table.ReplicaTopic)
+		}
+		if tb == nil {
+			return nil, errors.New("table replication not available")
+		}
+		tbl, err := table.Make(topic, tb)
+		if err != nil {
+			return nil, err
+		}
+		u := &urn{
+			kind: Table,
+			elem: tbl,
 		}
 		t.carrier().Set(u)
 		return u.elem, nil
 
 	case OnJoin:
+		spec, err := subscriptionSpec(arg)
+		if err != nil {
+			return nil, err
+		}
+		sub, err := t.genus.NewArrivals(spec.Resume, spec.match())
+		if err != nil {
+			return nil, err
+		}
 		u := &urn{
 			kind: OnJoin,
-			elem: t.genus.NewArrivals(),
+			elem: sub,
 		}
 		t.carrier().Set(u)
 		return u.elem, nil
 
 	case OnLeave:
+		spec, err := subscriptionSpec(arg)
+		if err != nil {
+			return nil, err
+		}
+		newLeaves := t.genus.NewDepartures
+		if spec.Suspect {
+			newLeaves = t.genus.NewSuspects
+		}
+		sub, err := newLeaves(spec.Resume, spec.match())
+		if err != nil {
+			return nil, err
+		}
 		u := &urn{
 			kind: OnLeave,
-			elem: t.genus.NewDepartures(),
+			elem: sub,
+		}
+		t.carrier().Set(u)
+		return u.elem, nil
+
+	case OnEvent:
+		spec, ok := arg.(EventSpec)
+		if !ok {
+			return nil, errors.New("invalid argument")
+		}
+		sub, err := t.subscribe(spec)
+		if err != nil {
+			return nil, err
+		}
+		u := &urn{
+			kind: OnEvent,
+			elem: sub,
 		}
 		t.carrier().Set(u)
 		return u.elem, nil
@@ -202,6 +386,31 @@ func (t *Terminal) Make(kind string, arg interface{}) (elem Element, err error)
 	return nil, errors.New("element kind not known")
 }
 
+// relPath returns the path of this anchor relative to its server's root anchor.
+func (t *Terminal) relPath() string {
+	return path.Join(t.carrier().walk[1:]...)
+}
+
+// Revive reattaches the durable channels stored on this server's disk to their anchors.
+// It is called once, on the root terminal of a starting server.
+func (t *Terminal) Revive() {
+	for _, name := range valve.ListDurable() {
+		v, err := valve.OpenDurableValve(name)
+		if err != nil {
+			log.Printf("Cannot revive durable channel %s (%v)", name, err)
+			continue
+		}
+		log.Printf("Reviving durable channel %s", name)
+		s := t.Walk(strings.Split(name, "/"))
+		s.carrier().TxLock()
+		s.carrier().Set(&urn{
+			kind: Chan,
+			elem: &scrubValve{s, v},
+		})
+		s.carrier().TxUnlock()
+	}
+}
+
 func (t *Terminal) Get() (string, Element) {
 	t.carrier().TxLock()
 	defer t.carrier().TxUnlock()
@@ -225,6 +434,7 @@ func (t *Terminal) Scrub() {
 	}
 	u.elem.Scrub()
 	t.carrier().Set(nil)
+	t.publish(u.kind, EventScrub, "")
 }
 
 type scrubValve struct {
@@ -238,7 +448,11 @@ func (v *scrubValve) Close() error {
 			v.t.Scrub()
 		}
 	}()
-	return v.Valve.Close()
+	if err := v.Valve.Close(); err != nil {
+		return err
+	}
+	v.t.publish(Chan, EventClose, "")
+	return nil
 }
 
 func (v *scrubValve) Recv() (io.ReadCloser, error) {
@@ -249,3 +463,47 @@ func (v *scrubValve) Recv() (io.ReadCloser, error) {
 	}()
 	return v.Valve.Recv()
 }
+
+func (v *scrubValve) X() circuit.X {
+	return circuit.Ref(valve.XValve{Valve: v})
+}
+
+// eventNameserver announces changes to the records of a nameserver,
+// and keeps its service zone, if any, in sync with the circuit.
+type eventNameserver struct {
+	t *Terminal
+	dns.Nameserver
+	services *serviceSync
+}
+
+func (ns *eventNameserver) Set(rr string) error {
+	if err := ns.Nameserver.Set(rr); err != nil {
+		return err
+	}
+	ns.t.publish(Nameserver, EventSet, rr)
+	return nil
+}
+
+func (ns *eventNameserver) Load(origin string, rr []string) error {
+	if err := ns.Nameserver.Load(origin, rr); err != nil {
+		return err
+	}
+	ns.t.publish(Nameserver, EventLoad, origin)
+	return nil
+}
+
+func (ns *eventNameserver) Unset(name string) {
+	ns.Nameserver.Unset(name)
+	ns.t.publish(Nameserver, EventUnset, name)
+}
+
+func (ns *eventNameserver) Scrub() {
+	if ns.services != nil {
+		ns.services.Stop()
+	}
+	ns.Nameserver.Scrub()
+}
+
+func (ns *eventNameserver) X() circuit.X {
+	return circuit.Ref(dns.XNameserver{Nameserver: ns})
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/gocircuit/circuit/element/docker"
 	"github.com/gocircuit/circuit/element/valve"
 	"github.com/gocircuit/circuit/element/dns"
+	"github.com/gocircuit/circuit/element/table"
 	"github.com/gocircuit/circuit/kit/pubsub"
 	"github.com/gocircuit/circuit/use/circuit"
 	xerrors "github.com/gocircuit/circuit/use/errors"
@@ -68,6 +69,24 @@ func (x XTerminal) Scrub() {
 	x.t.Scrub()
 }
 
+func (x XTerminal) Label(key, value string) {
+	x.t.Label(key, value)
+}
+
+func (x XTerminal) Labels() map[string]string {
+	return x.t.Labels()
+}
+
+func (x XTerminal) Services() map[string]string {
+	return x.t.Services()
+}
+
+// Events returns a subscription to the events of the given kinds concerning any element on this server.
+func (x XTerminal) Events(kinds []string) circuit.X {
+	sub, _ := x.t.events.SubscribeFrom(0, kindMatch(kinds))
+	return circuit.Ref(sub)
+}
+
 // YTerminal…
 type YTerminal struct {
 	X circuit.X
@@ -102,10 +121,14 @@ func (y YTerminal) Make(kind string, arg interface{}) (yelm interface{}, err err
 		return docker.YContainer{r[0].(circuit.X)}, nil
 	case Nameserver:
 		return dns.YNameserver{r[0].(circuit.X)}, nil
+	case Table:
+		return table.YTable{r[0].(circuit.X)}, nil
 	case OnJoin:
 		return pubsub.YSubscription{r[0].(circuit.X)}, nil
 	case OnLeave:
 		return pubsub.YSubscription{r[0].(circuit.X)}, nil
+	case OnEvent:
+		return pubsub.YSubscription{r[0].(circuit.X)}, nil
 	}
 	return nil, errors.New("element kind not supported")
 }
@@ -122,12 +145,16 @@ func (y YTerminal) Get() (kind string, yelm interface{}) {
 		return Proc, proc.YProc{r[1].(circuit.X)}
 	case Nameserver:
 		return Nameserver, dns.YNameserver{r[1].(circuit.X)}
+	case Table:
+		return Table, table.YTable{r[1].(circuit.X)}
 	case Docker:
 		return Docker, docker.YContainer{r[1].(circuit.X)}
 	case OnJoin:
 		return OnJoin, pubsub.YSubscription{r[1].(circuit.X)}
 	case OnLeave:
 		return OnLeave, pubsub.YSubscription{r[1].(circuit.X)}
+	case OnEvent:
+		return OnEvent, pubsub.YSubscription{r[1].(circuit.X)}
 	}
 	return "", nil
 }
@@ -139,3 +166,19 @@ func (y YTerminal) Scrub() {
 func (y YTerminal) Path() string {
 	return y.X.Call("Path")[0].(string)
 }
+
+func (y YTerminal) Label(key, value string) {
+	y.X.Call("Label", key, value)
+}
+
+func (y YTerminal) Labels() map[string]string {
+	return y.X.Call("Labels")[0].(map[string]string)
+}
+
+func (y YTerminal) Services() map[string]string {
+	return y.X.Call("Services")[0].(map[string]string)
+}
+
+func (y YTerminal) Events(kinds []string) pubsub.YSubscription {
+	return pubsub.YSubscription{X: y.X.Call("Events", kinds)[0].(circuit.X)}
+}
@@ -9,6 +9,7 @@ package client
 
 import (
 	"io"
+	"time"
 
 	"github.com/gocircuit/circuit/element/valve"
 )
@@ -27,6 +28,8 @@ type Chan interface {
 	// until it can be accommodated in the channel's buffer.
 	// It returns a WriteCloser representing a byte pipe to the receiver, or a non-nil error
 	// if the channel has already been closed.
+	// For durable channels, the message is committed to disk when the WriteCloser is closed,
+	// and Close returns only after the message is safely stored.
 	Send() (io.WriteCloser, error)
 
 	// Scrub aborts and abandons the channel. Any buffered send operations are lost.
@@ -40,6 +43,12 @@ type Chan interface {
 	// channel has been closed.
 	Recv() (io.ReadCloser, error)
 
+	// Lease blocks until a message is available on a channel in acknowledgement mode, and leases it
+	// to the caller. Unless the lease is acknowledged before the channel's visibility timeout expires,
+	// or if the caller's runtime dies, the message is redelivered to another receiver.
+	// For channels in acknowledgement mode, Recv acknowledges messages as soon as they are received.
+	Lease() (Lease, error)
+
 	// Cap reports the capacity of the channel.
 	Cap() int
 
@@ -47,6 +56,56 @@ type Chan interface {
 	Stat() ChanStat
 }
 
+// ChanSpec describes a channel element to be created.
+type ChanSpec struct {
+
+	// Cap is the channel capacity.
+	Cap int
+
+	// Durable channels keep their buffer on the disk of the hosting server. Buffered messages
+	// survive the restart of a server that reuses its working directory.
+	Durable bool
+
+	// Ack channels lease received messages to their receivers until acknowledged.
+	Ack bool
+
+	// Visibility is the lease duration of an Ack channel. If zero, a default of 30 seconds is used.
+	Visibility time.Duration
+
+	// MaxBytes, if positive, limits the size of individual messages.
+	// Messages exceeding the limit are rejected and never delivered.
+	MaxBytes int64
+
+	// MaxDuration, if positive, limits the time from Send to the closing of the message by the sender.
+	// Messages exceeding the limit are aborted and reported as failed to their receiver.
+	MaxDuration time.Duration
+}
+
+func (s ChanSpec) retype() valve.Spec {
+	return valve.Spec{
+		Cap: s.Cap,
+		Durable: s.Durable,
+		Ack: s.Ack,
+		Visibility: s.Visibility,
+		MaxBytes: s.MaxBytes,
+		MaxDuration: s.MaxDuration,
+	}
+}
+
+// Lease is a message received from a channel in acknowledgement mode.
+type Lease interface {
+
+	// Read and Close access the contents of the message.
+	io.ReadCloser
+
+	// Ack confirms the message has been processed, so that it is never redelivered.
+	// An error is returned if the lease has already expired.
+	Ack() error
+
+	// Release gives up the lease, making the message available for redelivery right away.
+	Release()
+}
+
 // ChanStat describes the state of a channel.
 type ChanStat struct {
 
@@ -66,6 +125,41 @@ type ChanStat struct {
 
 	// NumRecv is the number of completed invocations to Recv.
 	NumRecv int
+
+	// Durable is set if the channel buffer is kept on disk.
+	Durable bool
+
+	// Ack is set if the channel is in acknowledgement mode.
+	Ack bool
+
+	// NumLeased is the number of messages currently leased to receivers.
+	NumLeased int
+
+	// NumAck is the number of acknowledged messages.
+	NumAck int
+
+	// NumRedeliver is the number of messages redelivered after their lease ended unacknowledged.
+	NumRedeliver int
+
+	// MaxBytes and MaxDuration are the message limits of the channel, if positive.
+	MaxBytes int64
+	MaxDuration time.Duration
+
+	// BytesSent and BytesRecv are the total number of bytes written by senders and read by receivers.
+	BytesSent int64
+	BytesRecv int64
+
+	// InFlight is the number of messages whose senders have not yet finished writing.
+	InFlight int
+
+	// NumRejected is the number of messages rejected for violating a limit.
+	NumRejected int
+
+	// LatencyP50, LatencyP90 and LatencyP99 are percentiles of the message latency,
+	// measured from Send to the moment the receiver reads the end of the message.
+	LatencyP50 time.Duration
+	LatencyP90 time.Duration
+	LatencyP99 time.Duration
 }
 
 func retypeChanStat(s valve.Stat) ChanStat {
@@ -75,6 +169,20 @@ func retypeChanStat(s valve.Stat) ChanStat {
 		Aborted: s.Aborted,
 		NumSend: s.NumSend,
 		NumRecv: s.NumRecv,
+		Durable: s.Durable,
+		Ack: s.Ack,
+		NumLeased: s.NumLeased,
+		NumAck: s.NumAck,
+		NumRedeliver: s.NumRedeliver,
+		MaxBytes: s.MaxBytes,
+		MaxDuration: s.MaxDuration,
+		BytesSent: s.BytesSent,
+		BytesRecv: s.BytesRecv,
+		InFlight: s.InFlight,
+		NumRejected: s.NumRejected,
+		LatencyP50: s.LatencyP50,
+		LatencyP90: s.LatencyP90,
+		LatencyP99: s.LatencyP99,
 	}
 }
 
@@ -85,3 +193,7 @@ type yvalveChan struct {
 func (y yvalveChan) Stat() ChanStat {
 	return retypeChanStat(y.YValve.Stat())
 }
+
+func (y yvalveChan) Lease() (Lease, error) {
+	return y.YValve.Lease()
+}
@@ -40,7 +40,8 @@ func _init(key []byte) {
 
 // Client is a live session with a circuit server.
 type Client struct {
-	y locus.YLocus
+	y          locus.YLocus
+	candidates []n.Addr // other discovered servers, in order of preference
 }
 
 // Dial establishes a connection to a circuit server specified by a circuit address.
@@ -66,20 +67,111 @@ func Dial(addr string, authkey []byte) *Client {
 	return c
 }
 
+// DialDiscover establishes a connection to a circuit server of the default cluster,
+// discovered over the UDP multicast address multicast.
+// The authkey argument is as for Dial.
 func DialDiscover(multicast string, authkey []byte) *Client {
-	mcast, err := net.ResolveUDPAddr("udp", multicast)
+	return DialDiscoverCluster(multicast, "", authkey)
+}
+
+// DialDiscoverCluster is like DialDiscover, but it discovers a server of the named cluster,
+// ignoring the servers of other clusters that share the multicast address.
+// If both cluster and authkey are given, the discovery beacon is signed with authkey,
+// as expected by servers started with the same cluster name and key.
+func DialDiscoverCluster(multicast, cluster string, authkey []byte) *Client {
+	return DialDiscoverSpec(DiscoverSpec{Multicast: multicast, Cluster: cluster}, authkey)
+}
+
+// Criteria for choosing among discovered servers.
+const (
+	ByLatency = "latency" // prefer the servers that respond first
+	ByLoad    = "load"    // prefer the servers with the lowest load average per CPU
+)
+
+// DefaultDiscoverWindow is the duration for which offers of servers are collected
+// after the first one arrives, unless specified otherwise.
+const DefaultDiscoverWindow = 250 * time.Millisecond
+
+// DiscoverSpec describes how to discover a circuit server.
+type DiscoverSpec struct {
+
+	// Multicast is the UDP multicast discovery address of the cluster.
+	Multicast string
+
+	// Cluster is the name of the cluster, or empty for the default cluster.
+	Cluster string
+
+	// Window is the duration for which offers of servers are collected after the first one arrives.
+	// If zero, DefaultDiscoverWindow is used.
+	Window time.Duration
+
+	// By is the criterion for choosing among the offering servers, ByLatency or ByLoad.
+	// If empty, servers are chosen by latency.
+	By string
+}
+
+// DialDiscoverSpec establishes a connection to a circuit server discovered as described by spec.
+// Of the servers offering themselves within the discovery window, the client connects to the
+// preferred one that responds, and keeps the others as candidates for Failover.
+// The authkey argument is as for DialDiscoverCluster.
+func DialDiscoverSpec(spec DiscoverSpec, authkey []byte) *Client {
+	mcast, err := net.ResolveUDPAddr("udp", spec.Multicast)
 	if err != nil {
 		panic(err)
 	}
 	_once.Do(func() {
 		_init(authkey)
 	})
+	var beaconKey []byte
+	if spec.Cluster != "" {
+		beaconKey = authkey
+	}
+	window := spec.Window
+	if window == 0 {
+		window = DefaultDiscoverWindow
+	}
+	offers := assemble.NewAssembler(circuit.ServerAddr(), mcast, spec.Cluster, beaconKey).AssembleClientOffers(window)
+	switch spec.By {
+	case ByLoad:
+		assemble.SortByLoad(offers)
+	default:
+		assemble.SortByLatency(offers)
+	}
 	c := &Client{}
-	dialback := assemble.NewAssembler(circuit.ServerAddr(), mcast).AssembleClient()
-	c.y = locus.YLocus{circuit.Dial(dialback, "locus")}
+	for _, o := range offers {
+		c.candidates = append(c.candidates, o.Addr)
+	}
+	if err := c.Failover(); err != nil {
+		panic(err)
+	}
 	return c
 }
 
+// Candidates returns the circuit addresses of the discovered servers that this client can fail over to,
+// in order of preference.
+func (c *Client) Candidates() []string {
+	r := make([]string, len(c.candidates))
+	for i, a := range c.candidates {
+		r[i] = a.String()
+	}
+	return r
+}
+
+// Failover connects the client to the next candidate server that responds, after the one it is connected to has failed.
+// Candidates are only obtained by discovery. Anchors obtained before the failover remain bound to their servers.
+// Failover must not be called concurrently with other methods of the client.
+func (c *Client) Failover() error {
+	for len(c.candidates) > 0 {
+		addr := c.candidates[0]
+		c.candidates = c.candidates[1:]
+		if x, err := circuit.TryDial(addr, "locus"); err == nil {
+			c.y = locus.YLocus{x}
+			return nil
+		}
+	}
+	return errors.New("no responding candidate servers left")
+}
+
 // Address returns the circuit address of the server that this client is connected to.
 func (c *Client) Addr() string {
 	return c.y.X.Addr().String()
@@ -107,15 +199,23 @@ func (c *Client) Path() string {
 }
 
 // View returns a map of all currently-live circuit server anchors.
+// Servers suspected of failure are included, and their anchors report so via Suspect.
 // Errors in communication are reported as panics.
 func (c *Client) View() map[string]Anchor {
 	var r = make(map[string]Anchor)
 	for k, p := range c.y.GetPeers() {
-		r[k] = c.newTerminal(p.Term, p.Kin)
+		t := c.newTerminal(p.Term, p.Kin)
+		t.suspect = p.Suspect
+		r[k] = t
 	}
 	return r
 }
 
+// Suspect is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) Suspect() bool {
+	return false
+}
+
 func (c *Client) newTerminal(xterm circuit.X, xkin tissue.KinAvatar) terminal {
 	return terminal{
 		y: anchor.YTerminal{xterm},
@@ -133,6 +233,11 @@ func (c *Client) MakeChan(n int) (Chan, error) {
 	return nil, errors.New("cannot create elements outside of servers")
 }
 
+// MakeChanSpec is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) MakeChanSpec(ChanSpec) (Chan, error) {
+	return nil, errors.New("cannot create elements outside of servers")
+}
+
 // MakeProc is an Anchor interface method, not applicable to the root-level anchor.
 func (c *Client) MakeProc(cmd Cmd) (Proc, error) {
 	return nil, errors.New("cannot create elements outside of servers")
@@ -148,6 +253,11 @@ func (c *Client) MakeNameserver(string) (Nameserver, error) {
 	return nil, errors.New("cannot create elements outside of servers")
 }
 
+// MakeNameserverSpec is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) MakeNameserverSpec(NameserverSpec) (Nameserver, error) {
+	return nil, errors.New("cannot create elements outside of servers")
+}
+
 // MakeOnJoin is an Anchor interface method, not applicable to the root-level anchor.
 func (c *Client) MakeOnJoin() (Subscription, error) {
 	return nil, errors.New("cannot create elements outside of servers")
@@ -158,6 +268,31 @@ func (c *Client) MakeOnLeave() (Subscription, error) {
 	return nil, errors.New("cannot create elements outside of servers")
 }
 
+// MakeOnJoinSpec is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) MakeOnJoinSpec(SubscriptionSpec) (Subscription, error) {
+	return nil, errors.New("cannot create elements outside of servers")
+}
+
+// MakeOnLeaveSpec is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) MakeOnLeaveSpec(SubscriptionSpec) (Subscription, error) {
+	return nil, errors.New("cannot create elements outside of servers")
+}
+
+// MakeOnEvent is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) MakeOnEvent(string, []string) (Subscription, error) {
+	return nil, errors.New("cannot create elements outside of servers")
+}
+
+// MakeOnEventSpec is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) MakeOnEventSpec(EventSpec) (Subscription, error) {
+	return nil, errors.New("cannot create elements outside of servers")
+}
+
+// MakeTable is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) MakeTable(string) (Table, error) {
+	return nil, errors.New("cannot create elements outside of servers")
+}
+
 // Get is an Anchor interface method, not applicable to the root-level anchor.
 func (c *Client) Get() interface{} {
 	return nil
@@ -165,3 +300,11 @@ func (c *Client) Get() interface{} {
 
 // Scrub is an Anchor interface method, not applicable to the root-level anchor.
 func (c *Client) Scrub() {}
+
+// SetLabel is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) SetLabel(key, value string) {}
+
+// Labels is an Anchor interface method, not applicable to the root-level anchor.
+func (c *Client) Labels() map[string]string {
+	return nil
+}
@@ -8,6 +8,8 @@
 package client
 
 import (
+	"io"
+
 	"github.com/gocircuit/circuit/element/dns"
 )
 
@@ -17,6 +19,26 @@ type NameserverStat struct {
 	// IP address of the nameserver
 	Address string
 
+	// Zone is the apex of the zone the nameserver is authoritative for.
+	// It is the owner of an explicitly set SOA record, if any, and otherwise
+	// the longest domain common to all names with records.
+	Zone string
+
+	// Serial is incremented on every change to the records.
+	Serial uint32
+
+	// Upstream lists the resolvers that queries for names outside of the nameserver's records are forwarded to.
+	Upstream []string
+
+	// Forwarded is the number of queries forwarded to upstream resolvers.
+	Forwarded int64
+
+	// CacheHits is the number of forwarded queries answered from the cache of upstream responses.
+	CacheHits int64
+
+	// Replicate is the group of replicated nameservers this nameserver belongs to, if any.
+	Replicate string
+
 	// Resource records resolved by this nameserver
 	Records map[string][]string
 }
@@ -24,16 +46,65 @@ type NameserverStat struct {
 func nameserverStat(s dns.Stat) NameserverStat {
 	return NameserverStat{
 		Address: s.Address,
+		Zone: s.Zone,
+		Serial: s.Serial,
+		Upstream: s.Upstream,
+		Forwarded: s.Forwarded,
+		CacheHits: s.CacheHits,
+		Replicate: s.Replicate,
 		Records: s.Records,
 	}
 }
 
+// NameserverSpec describes a nameserver element.
+type NameserverSpec struct {
+
+	// Addr is the address the nameserver listens on, over both UDP and TCP.
+	// If empty, an available port on all interfaces is picked.
+	Addr string
+
+	// Upstream lists resolvers, as host or host:port, to which queries for names outside of
+	// the nameserver's records are forwarded. Their responses are cached for the duration of their TTLs.
+	Upstream []string
+
+	// ServiceZone, if not empty, is a domain (e.g. circuit.) under which the nameserver automatically
+	// publishes the address of every live server as <server-id>.<zone>, and of every anchor labeled
+	// with service=<name> as <anchor-name>.<name>.<zone>. A server whose root anchor is labeled
+	// with alias=<alias> is also published as <alias>.<zone>.
+	ServiceZone string
+
+	// Replicate, if not empty, names a group of nameservers on different servers, which share
+	// their records. Changes made through any replica of the group propagate to all others.
+	Replicate string
+}
+
+func (spec NameserverSpec) retype() dns.Spec {
+	return dns.Spec{
+		Addr: spec.Addr,
+		Upstream: spec.Upstream,
+		ServiceZone: spec.ServiceZone,
+		Replicate: spec.Replicate,
+	}
+}
+
 type Nameserver interface {
 
 	Set(rr string) error
 
 	Unset(name string)
 
+	// Load replaces all records at or below the domain origin with rr, at once.
+	// Resolvers never observe a partial update.
+	Load(origin string, rr []string) error
+
+	// LoadZone reads an RFC 1035 master file from r, whose relative names are relative to origin,
+	// and replaces the records of its zone with the file's records, at once. The zone's apex,
+	// which is the owner of the file's SOA record, if any, is returned.
+	LoadZone(r io.Reader, origin string) (apex string, err error)
+
+	// DumpZone returns the records of the nameserver as an RFC 1035 master file.
+	DumpZone() string
+
 	// Peek asynchronously returns the current state of the server.
 	Peek() NameserverStat
 
@@ -48,3 +119,15 @@ type yNameserver struct {
 func (y yNameserver) Peek() NameserverStat {
 	return nameserverStat(y.YNameserver.Peek())
 }
+
+func (y yNameserver) LoadZone(r io.Reader, origin string) (string, error) {
+	apex, rr, err := dns.ParseZone(r, origin, "")
+	if err != nil {
+		return "", err
+	}
+	return apex, y.YNameserver.Load(apex, rr)
+}
+
+func (y yNameserver) DumpZone() string {
+	return dns.DumpZone(y.YNameserver.Peek())
+}
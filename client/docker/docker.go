@@ -20,4 +20,6 @@ type Container interface {
 	Stdin() io.WriteCloser
 	Stdout() io.ReadCloser
 	Stderr() io.ReadCloser
+	Exec(cmd Exec) (Process, error)
+	Stats() (*Stats, error)
 }
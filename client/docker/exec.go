@@ -0,0 +1,58 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"encoding/gob"
+	"io"
+	"time"
+)
+
+func init() {
+	gob.Register(Exec{})
+}
+
+// Exec describes a command to be executed within a running container.
+type Exec struct {
+	Path string   `json:"path"`
+	Args []string `json:"args"`
+	User string   `json:"user"` // user, or user:group, to run the command as; the container's user by default
+}
+
+// ExecStat describes the state of a command executed within a container.
+type ExecStat struct {
+	ID       string
+	Running  bool
+	ExitCode int
+	Pid      int
+}
+
+// Process is a command executing within a container, started by Container.Exec.
+// Its standard output and error are closed when the command exits.
+type Process interface {
+	IsDone() bool
+	Peek() (*ExecStat, error)
+	Wait() (*ExecStat, error)
+	Stdin() io.WriteCloser
+	Stdout() io.ReadCloser
+	Stderr() io.ReadCloser
+}
+
+// Stats is a sample of the resource usage of a container.
+type Stats struct {
+	Read        time.Time
+	CPUPercent  float64 // share of the host's CPU time, since the previous sample, times the number of CPUs
+	CPUTotal    uint64  // total CPU time consumed, in nanoseconds
+	MemoryUsage uint64  // bytes
+	MemoryLimit uint64  // bytes
+	NetworkRx   uint64  // bytes received over all interfaces
+	NetworkTx   uint64  // bytes sent over all interfaces
+	BlockRead   uint64  // bytes read from block devices
+	BlockWrite  uint64  // bytes written to block devices
+	Pids        uint64
+}
@@ -0,0 +1,41 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"time"
+)
+
+// Image describes an image present on a host, as listed by the Docker engine.
+type Image struct {
+	ID          string `json:"Id"`
+	ParentID    string `json:"ParentId"`
+	RepoTags    []string
+	RepoDigests []string
+	Created     int64 // seconds since the epoch
+	Size        int64
+	VirtualSize int64
+	Labels      map[string]string
+}
+
+// ImageStat is a detailed description of an image, as inspected by the Docker engine.
+type ImageStat struct {
+	ID            string `json:"Id"`
+	RepoTags      []string
+	RepoDigests   []string
+	Parent        string
+	Comment       string
+	Created       time.Time
+	DockerVersion string
+	Author        string
+	Config        *Config
+	Architecture  string
+	Os            string
+	Size          int64
+	VirtualSize   int64
+}
@@ -11,6 +11,7 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
+	"sort"
 )
 
 func init() {
@@ -30,6 +31,34 @@ type Run struct {
 	Path string
 	Args []string
 	Scrub bool
+
+	// Ports lists published ports, as [[host-ip:]host-port:]container-port[/protocol], e.g. 8080:80/tcp.
+	Ports []string `json:",omitempty"`
+	// Network is the network the container connects to, e.g. bridge, host, none or a user-defined network.
+	Network string `json:",omitempty"`
+	Hostname string `json:",omitempty"`
+	Labels map[string]string `json:",omitempty"`
+	// User is the user, and optionally the group, that the container process runs as, e.g. nobody:nogroup.
+	User string `json:",omitempty"`
+	// ReadOnly mounts the root filesystem of the container read-only.
+	ReadOnly bool `json:",omitempty"`
+	// CapDrop lists Linux capabilities to drop, e.g. NET_RAW, or ALL.
+	CapDrop []string `json:",omitempty"`
+	Health *Health `json:",omitempty"`
+	// Restart is the restart policy: no, always, unless-stopped, on-failure or on-failure:max-retries.
+	Restart string `json:",omitempty"`
+}
+
+// Health describes a health check, run periodically within the container.
+type Health struct {
+	// Cmd is a shell command, whose successful exit indicates that the container is healthy.
+	Cmd string
+	// Interval, Timeout and StartPeriod are durations, like 30s. Empty values use the engine's defaults.
+	Interval string `json:",omitempty"`
+	Timeout string `json:",omitempty"`
+	StartPeriod string `json:",omitempty"`
+	// Retries is the number of consecutive failures after which the container is unhealthy.
+	Retries int `json:",omitempty"`
 }
 
 func ParseRun(src string) (*Run, error) {
@@ -64,6 +93,50 @@ func (x *Run) Arg(name string) []string {
 	if x.Entry != "" {
 		r = append(r, "--entrypoint", fmt.Sprintf("%s", x.Entry))
 	}
+	for _, p := range x.Ports {
+		r = append(r, "--publish", p)
+	}
+	if x.Network != "" {
+		r = append(r, "--network", x.Network)
+	}
+	if x.Hostname != "" {
+		r = append(r, "--hostname", x.Hostname)
+	}
+	var labels []string
+	for k, v := range x.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	for _, l := range labels {
+		r = append(r, "--label", l)
+	}
+	if x.User != "" {
+		r = append(r, "--user", x.User)
+	}
+	if x.ReadOnly {
+		r = append(r, "--read-only")
+	}
+	for _, c := range x.CapDrop {
+		r = append(r, "--cap-drop", c)
+	}
+	if h := x.Health; h != nil {
+		r = append(r, "--health-cmd", h.Cmd)
+		if h.Interval != "" {
+			r = append(r, "--health-interval", h.Interval)
+		}
+		if h.Timeout != "" {
+			r = append(r, "--health-timeout", h.Timeout)
+		}
+		if h.StartPeriod != "" {
+			r = append(r, "--health-start-period", h.StartPeriod)
+		}
+		if h.Retries > 0 {
+			r = append(r, "--health-retries", fmt.Sprintf("%d", h.Retries))
+		}
+	}
+	if x.Restart != "" {
+		r = append(r, "--restart", x.Restart)
+	}
 	r = append(r, x.Image) // image
 	if x.Path != "" {
 		r = append(r, x.Path) // command path
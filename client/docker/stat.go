@@ -31,6 +31,7 @@ type Stat struct {
 	Volumes map[string]string
 	VolumesRW map[string]bool
 	HostConfig HostConfig
+	RestartCount int
 }
 
 func ParseStat(buf []byte) (s *Stat, err error) {
@@ -84,6 +85,17 @@ type Config struct {
 	Entrypoint      []string
 	NetworkDisabled bool
 	OnBuild         []string
+	Labels          map[string]string
+	Healthcheck     *HealthConfig
+}
+
+// HealthConfig is a health check definition. Durations are in nanoseconds.
+type HealthConfig struct {
+	Test        []string
+	Interval    int64
+	Timeout     int64
+	StartPeriod int64
+	Retries     int
 }
 
 type State struct {
@@ -94,6 +106,23 @@ type State struct {
 	StartedAt  time.Time
 	FinishedAt time.Time
 	Ghost bool
+	Restarting bool
+	OOMKilled  bool
+	Health     *HealthState
+}
+
+// HealthState is the current outcome of the health checks of a container.
+type HealthState struct {
+	Status        string // starting, healthy or unhealthy
+	FailingStreak int
+	Log           []HealthResult
+}
+
+type HealthResult struct {
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+	Output   string
 }
 
 type NetworkSettings struct {
@@ -103,6 +132,17 @@ type NetworkSettings struct {
 	Bridge      string
 	PortMapping map[string]PortMapping // Deprecated
 	Ports       PortMap
+	Networks    map[string]EndpointSettings
+}
+
+// EndpointSettings describes the connection of a container to a network.
+type EndpointSettings struct {
+	NetworkID   string
+	IPAddress   string
+	IPPrefixLen int
+	Gateway     string
+	MacAddress  string
+	Aliases     []string
 }
 
 type PortMapping map[string]string // Deprecated
@@ -119,6 +159,14 @@ type HostConfig struct {
 	DnsSearch       []string
 	VolumesFrom     []string
 	NetworkMode     NetworkMode
+	ReadonlyRootfs  bool
+	CapDrop         []string
+	RestartPolicy   RestartPolicy
+}
+
+type RestartPolicy struct {
+	Name              string
+	MaximumRetryCount int
 }
 
 type KeyValuePair struct {
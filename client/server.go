@@ -11,6 +11,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/gocircuit/circuit/client/docker"
 	srv "github.com/gocircuit/circuit/element/server"
 )
 
@@ -18,12 +19,60 @@ import (
 type ServerStat struct {
 	Addr   string
 	Joined time.Time
+	// Announce is the interval between announcements of the server's membership in the circuit.
+	Announce time.Duration
+	// Expire is the age after which the server forgets peers which have not re-announced themselves.
+	Expire time.Duration
+	// ExpansionLow, ExpansionHigh, Spread and Depth are the topology parameters of the server's tissue system.
+	ExpansionLow  int
+	ExpansionHigh int
+	Spread        int
+	Depth         int
+	// Neighbors lists the IDs of the servers in the neighborhood of the server's tissue system.
+	Neighbors []string
+	// Folk lists the IDs of the peers of each of the server's replicated tubes, by topic.
+	Folk map[string][]string
+	// Revisions holds the revisions of the records of the server's tubes, by topic and key.
+	Revisions map[string]map[string]uint64
+	// Repair holds the anti-entropy traffic of the server's replicated state, by tube topic.
+	Repair map[string]RepairStat
+}
+
+// RepairStat holds counters of the reconciliations of a server's replicated state with its peers.
+type RepairStat struct {
+	// Rounds is the number of reconciliations attempted, and Diverged the number of those
+	// that found the server and its peer divergent.
+	Rounds, Diverged int64
+	// Buckets is the number of divergent buckets of records exchanged.
+	Buckets int64
+	// Pulled and Pushed are the numbers of records repaired at the server and at its peers.
+	Pulled, Pushed int64
 }
 
 func srvStat(s srv.Stat) ServerStat {
+	repair := make(map[string]RepairStat)
+	for topic, r := range s.Repair {
+		repair[topic] = RepairStat{
+			Rounds:   r.Rounds,
+			Diverged: r.Diverged,
+			Buckets:  r.Buckets,
+			Pulled:   r.Pulled,
+			Pushed:   r.Pushed,
+		}
+	}
 	return ServerStat{
-		Addr:   s.Addr,
-		Joined: s.Joined,
+		Addr:          s.Addr,
+		Joined:        s.Joined,
+		Announce:      s.Announce,
+		Expire:        s.Expire,
+		ExpansionLow:  s.ExpansionLow,
+		ExpansionHigh: s.ExpansionHigh,
+		Spread:        s.Spread,
+		Depth:         s.Depth,
+		Neighbors:     s.Neighbors,
+		Folk:          s.Folk,
+		Revisions:     s.Revisions,
+		Repair:        repair,
 	}
 }
 
@@ -34,6 +83,30 @@ type Server interface {
 	Peek() ServerStat
 	Rejoin(string) error
 	Suicide()
+	// Drain gracefully removes the server from the circuit. The server stops accepting new elements,
+	// signals and waits for its processes as described by spec, and withdraws its membership record,
+	// so that its peers learn of its departure at once. The server exits shortly after Drain returns.
+	Drain(spec DrainSpec) error
+	// PullImage pulls a docker image onto the server. The returned reader streams the progress
+	// of the pull, one message per line; a failed pull ends with a line beginning with "error:".
+	PullImage(image string) (io.ReadCloser, error)
+	// Images lists the docker images present on the server.
+	Images() ([]docker.Image, error)
+	// RemoveImage removes a docker image from the server. Unless force is set, images used by containers are kept.
+	RemoveImage(image string, force bool) error
+	// InspectImage describes a docker image present on the server.
+	InspectImage(image string) (*docker.ImageStat, error)
+}
+
+// DrainSpec describes how a draining server treats its running processes.
+type DrainSpec struct {
+
+	// Signal, if not empty, is sent to all running processes of the server, e.g. "TERM".
+	Signal string
+
+	// Wait is the duration for which the server waits for its processes to exit before leaving.
+	// Processes still running when the server exits are left running.
+	Wait time.Duration
 }
 
 type ysrvSrv struct {
@@ -43,3 +116,7 @@ type ysrvSrv struct {
 func (y ysrvSrv) Peek() ServerStat {
 	return srvStat(y.YServer.Peek())
 }
+
+func (y ysrvSrv) Drain(spec DrainSpec) error {
+	return y.YServer.Drain(srv.DrainSpec{Signal: spec.Signal, Wait: spec.Wait})
+}
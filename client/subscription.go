@@ -8,6 +8,11 @@
 package client
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/gocircuit/circuit/anchor"
+	"github.com/gocircuit/circuit/element/table"
 	"github.com/gocircuit/circuit/kit/pubsub"
 )
 
@@ -20,6 +25,9 @@ type SubscriptionStat struct {
 	// Pending equals the number of messages waiting to be consumed.
 	Pending int
 
+	// Seq is the sequence number of the last consumed message.
+	Seq int64
+
 	// Closed is true if the publisher stream has marked an end.
 	Closed bool
 }
@@ -28,6 +36,7 @@ func subscriptionStat(s pubsub.Stat) SubscriptionStat {
 	return SubscriptionStat{
 		Source: s.Source,
 		Pending: s.Pending,
+		Seq: s.Seq,
 		Closed: s.Closed,
 	}
 }
@@ -39,6 +48,14 @@ type Subscription interface {
 	// Consume blocks until the next message is available on the channel.
 	Consume() (interface{}, bool)
 
+	// ConsumeSeq is like Consume, but it also returns the sequence number of the message.
+	// Sequence numbers increase monotonically within the event stream of a server.
+	// A new subscription can resume the stream right after a given sequence number,
+	// as long as the server still retains the messages that follow it.
+	// Messages summarizing the state of the cluster, delivered first by join subscriptions,
+	// carry the sequence number of the last event that state reflects.
+	ConsumeSeq() (int64, interface{}, bool)
+
 	// Peek asynchronously returns the current state of the process.
 	Peek() SubscriptionStat
 
@@ -53,3 +70,119 @@ type ysubSub struct {
 func (y ysubSub) Peek() SubscriptionStat {
 	return subscriptionStat(y.YSubscription.Peek())
 }
+
+func (y ysubSub) Consume() (interface{}, bool) {
+	_, v, ok := y.ConsumeSeq()
+	return v, ok
+}
+
+func (y ysubSub) ConsumeSeq() (int64, interface{}, bool) {
+	seq, v, ok := y.YSubscription.ConsumeSeq()
+	switch e := v.(type) {
+	case anchor.Event:
+		return seq, retypeEvent(e), ok
+	case table.Entry:
+		return seq, retypeEntry(e), ok
+	}
+	return seq, v, ok
+}
+
+// SubscriptionSpec describes a join or leave subscription.
+type SubscriptionSpec struct {
+
+	// Prefix, if not empty, restricts the subscription to servers whose ID begins with one of the given prefixes, e.g. "X12".
+	Prefix []string
+
+	// Resume, if positive, is the sequence number of the last message seen by a prior subscription.
+	// The new subscription resumes the stream right after it.
+	Resume int64
+
+	// Suspect, if set, makes a leave subscription report servers as soon as they become suspected of failure,
+	// rather than when they are declared dead. Suspected servers may recover without ever leaving.
+	// Suspect has no effect on join subscriptions.
+	Suspect bool
+}
+
+func (spec SubscriptionSpec) retype() anchor.SubscriptionSpec {
+	return anchor.SubscriptionSpec{
+		Prefix:  spec.Prefix,
+		Resume:  spec.Resume,
+		Suspect: spec.Suspect,
+	}
+}
+
+// EventSpec describes a subscription to element lifecycle events.
+type EventSpec struct {
+
+	// Source is the anchor path of the element whose events are delivered.
+	// It must be hosted by the same server as the subscription.
+	Source string
+
+	// Kinds lists the event kinds of interest. All kinds are delivered if empty.
+	Kinds []string
+
+	// Resume, if positive, is the sequence number of the last event seen by a prior subscription.
+	// The new subscription resumes the stream right after it.
+	Resume int64
+}
+
+func (spec EventSpec) retype() anchor.EventSpec {
+	return anchor.EventSpec{
+		Source: spec.Source,
+		Kinds: spec.Kinds,
+		Resume: spec.Resume,
+	}
+}
+
+// Kinds of element lifecycle events, delivered by subscriptions made with MakeOnEvent.
+const (
+	EventStart     = anchor.EventStart     // a process or container was started
+	EventRestart   = anchor.EventRestart   // a process was started at an anchor whose previous process had exited
+	EventExit      = anchor.EventExit      // a process or container exited
+	EventClose     = anchor.EventClose     // a channel was closed
+	EventSet       = anchor.EventSet       // a DNS record was set
+	EventUnset     = anchor.EventUnset     // the DNS records for a name were removed
+	EventScrub     = anchor.EventScrub     // the element at an anchor was scrubbed
+	EventLabel     = anchor.EventLabel     // a label of an anchor was set or removed
+	EventLoad      = anchor.EventLoad      // the records of a nameserver zone were replaced
+	EventDocker    = anchor.EventDocker    // the docker engine reported an event for a container, e.g. oom
+	EventPartition = anchor.EventPartition // the server lost contact with a large part of the circuit at once
+	EventMerge     = anchor.EventMerge     // the server rejoined a peer it had lost contact with
+)
+
+// Event is the message delivered by subscriptions made with MakeOnEvent.
+type Event struct {
+
+	// Anchor is the path of the anchor whose element the event concerns.
+	Anchor string
+
+	// Element is the kind of the element, e.g. "proc", "chan", "docker" or "dns".
+	Element string
+
+	// Kind is the kind of the event, e.g. EventExit.
+	Kind string
+
+	// Detail holds event-specific information, like the exit error of a process or a DNS record.
+	Detail string
+
+	// Time is when the event occurred.
+	Time time.Time
+}
+
+func retypeEvent(e anchor.Event) Event {
+	return Event{
+		Anchor: e.Anchor,
+		Element: e.Element,
+		Kind: e.Kind,
+		Detail: e.Detail,
+		Time: e.Time,
+	}
+}
+
+func (e Event) String() string {
+	s := fmt.Sprintf("%s %s %s %s", e.Time.Format(time.RFC3339Nano), e.Anchor, e.Element, e.Kind)
+	if e.Detail != "" {
+		s += " " + e.Detail
+	}
+	return s
+}
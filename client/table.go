@@ -0,0 +1,112 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package client
+
+import (
+	"time"
+
+	"github.com/gocircuit/circuit/element/table"
+)
+
+// TableEntry is a key-value pair of a table.
+type TableEntry struct {
+
+	// Key and Value of the entry
+	Key, Value string
+
+	// Rev is the revision of the entry. Later changes to a key have higher revisions.
+	Rev uint64
+
+	// Expires is the time after which the entry is removed, or zero if the entry does not expire.
+	Expires time.Time
+
+	// Deleted is set in entries delivered by watch subscriptions, which report the removal of a key, deleted or expired.
+	Deleted bool
+}
+
+func retypeEntry(e table.Entry) TableEntry {
+	return TableEntry{
+		Key:     e.Key,
+		Value:   e.Value,
+		Rev:     e.Rev,
+		Expires: e.Expires,
+		Deleted: e.Deleted,
+	}
+}
+
+// TableStat encloses table state information.
+type TableStat struct {
+
+	// Topic of the table
+	Topic string
+
+	// Len is the number of live entries in the table.
+	Len int
+}
+
+// Table provides access to a circuit table element.
+// All tables of the same topic, across all servers of a circuit, share their entries.
+// Changes made through any table propagate to all others, and concurrent changes
+// to the same key are resolved in favor of the later one.
+// All methods panic if the hosting circuit server dies.
+type Table interface {
+
+	// Put sets the value of key. If ttl is positive, the entry is removed after ttl elapses.
+	Put(key, value string, ttl time.Duration) error
+
+	// Get returns the entry of key, if present.
+	Get(key string) (TableEntry, bool)
+
+	// Delete removes the entry of key.
+	Delete(key string)
+
+	// List returns the entries whose keys begin with prefix, in order of key.
+	List(prefix string) []TableEntry
+
+	// Watch returns a subscription, which delivers a TableEntry for each change to the entries
+	// whose keys begin with prefix. The subscription begins with the current entries, as listed by List.
+	// Keys removed long ago (see the table documentation) may reappear as new entries.
+	// The subscription is not an element and is not stored at any anchor.
+	Watch(prefix string) (Subscription, error)
+
+	// Peek asynchronously returns the current state of the table.
+	Peek() TableStat
+
+	// Scrub removes the table element. Its entries remain with the other tables of its topic.
+	Scrub()
+}
+
+type yTable struct {
+	table.YTable
+}
+
+func (y yTable) Get(key string) (TableEntry, bool) {
+	e, ok := y.YTable.Get(key)
+	return retypeEntry(e), ok
+}
+
+func (y yTable) List(prefix string) []TableEntry {
+	var r []TableEntry
+	for _, e := range y.YTable.List(prefix) {
+		r = append(r, retypeEntry(e))
+	}
+	return r
+}
+
+func (y yTable) Watch(prefix string) (Subscription, error) {
+	ysub, err := y.YTable.Watch(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return ysubSub{ysub}, nil
+}
+
+func (y yTable) Peek() TableStat {
+	s := y.YTable.Peek()
+	return TableStat{Topic: s.Topic, Len: s.Len}
+}
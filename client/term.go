@@ -14,6 +14,7 @@ import (
 	"github.com/gocircuit/circuit/element/dns"
 	"github.com/gocircuit/circuit/element/proc"
 	srv "github.com/gocircuit/circuit/element/server"
+	"github.com/gocircuit/circuit/element/table"
 	"github.com/gocircuit/circuit/element/valve"
 	"github.com/gocircuit/circuit/kit/pubsub"
 	"github.com/gocircuit/circuit/tissue"
@@ -56,11 +57,19 @@ type Anchor interface {
 	// View returns the set of this anchor's sub-anchors.
 	View() map[string]Anchor
 
+	// Suspect returns true if this anchor is a server anchor, obtained from the View of the root anchor,
+	// whose server was suspected of failure at the time.
+	// Suspected servers are still listed, since they may recover after a brief pause.
+	Suspect() bool
+
 	// MakeChan creates a new circuit channel element at this anchor with a given capacity n.
 	// If the anchor already stores an element, a non-nil error is returned.
 	// Panics indicate that the server hosting the anchor is gone.
 	MakeChan(n int) (Chan, error)
 
+	// MakeChanSpec creates a new circuit channel element at this anchor, as described by spec.
+	MakeChanSpec(spec ChanSpec) (Chan, error)
+
 	// MakeProc issues the execution of an OS process, described by cmd, at the server hosting the anchor
 	// and creates a corresponding circuit process element at this anchor.
 	// If the anchor already stores an element, a non-nil error is returned.
@@ -73,12 +82,34 @@ type Anchor interface {
 	// MakeNameserver…
 	MakeNameserver(addr string) (Nameserver, error)
 
+	// MakeNameserverSpec creates a new nameserver element at this anchor, as described by spec.
+	MakeNameserverSpec(spec NameserverSpec) (Nameserver, error)
+
 	// MakeOnJoin…
 	MakeOnJoin() (Subscription, error)
 
 	// MakeOnLeave…
 	MakeOnLeave() (Subscription, error)
 
+	// MakeOnJoinSpec creates a join subscription element at this anchor, as described by spec.
+	MakeOnJoinSpec(spec SubscriptionSpec) (Subscription, error)
+
+	// MakeOnLeaveSpec creates a leave subscription element at this anchor, as described by spec.
+	MakeOnLeaveSpec(spec SubscriptionSpec) (Subscription, error)
+
+	// MakeOnEvent creates a subscription element at this anchor, which delivers an Event each time
+	// the element at the anchor path source undergoes a lifecycle event of one of the given kinds.
+	// All kinds of events are delivered if kinds is empty.
+	// The source anchor must be hosted by the same circuit server as this anchor.
+	MakeOnEvent(source string, kinds []string) (Subscription, error)
+
+	// MakeOnEventSpec creates an event subscription element at this anchor, as described by spec.
+	MakeOnEventSpec(spec EventSpec) (Subscription, error)
+
+	// MakeTable creates a table element at this anchor, which shares its entries with all tables
+	// of the given topic across the circuit.
+	MakeTable(topic string) (Table, error)
+
 	// Get returns a handle for the circuit element (Chan, Proc, Subscription, Server, etc)
 	// stored at this anchor, and nil otherwise.
 	// Panics indicate that the server hosting the anchor and its element has already died.
@@ -90,6 +121,14 @@ type Anchor interface {
 
 	// Path returns the path to this anchor
 	Path() string
+
+	// SetLabel sets the label key of this anchor to value. An empty value removes the label.
+	// Labeled anchors are not garbage-collected. Anchors holding an element and labeled with service=<name>
+	// are published by nameservers with a service zone, as are servers whose root anchor is labeled alias=<alias>.
+	SetLabel(key, value string)
+
+	// Labels returns the labels of this anchor.
+	Labels() map[string]string
 }
 
 // Split breaks up an anchor path into components.
@@ -111,8 +150,9 @@ func Split(walk string) (r []string) {
 }
 
 type terminal struct {
-	y anchor.YTerminal
-	k tissue.KinAvatar
+	y       anchor.YTerminal
+	k       tissue.KinAvatar
+	suspect bool
 }
 
 func (t terminal) Addr() string {
@@ -123,6 +163,10 @@ func (t terminal) ServerID() string {
 	return t.k.ID.String()
 }
 
+func (t terminal) Suspect() bool {
+	return t.suspect
+}
+
 func (t terminal) Walk(walk []string) Anchor {
 	return terminal{y: t.y.Walk(walk), k: t.k}
 }
@@ -148,6 +192,14 @@ func (t terminal) MakeChan(n int) (Chan, error) {
 	return yvalveChan{yvalve.(valve.YValve)}, nil
 }
 
+func (t terminal) MakeChanSpec(spec ChanSpec) (Chan, error) {
+	yvalve, err := t.y.Make(anchor.Chan, spec.retype())
+	if err != nil {
+		return nil, err
+	}
+	return yvalveChan{yvalve.(valve.YValve)}, nil
+}
+
 func (t terminal) MakeProc(cmd Cmd) (Proc, error) {
 	yproc, err := t.y.Make(anchor.Proc, cmd.retype())
 	if err != nil {
@@ -164,6 +216,14 @@ func (t terminal) MakeNameserver(addr string) (Nameserver, error) {
 	return yNameserver{ydns.(dns.YNameserver)}, nil
 }
 
+func (t terminal) MakeNameserverSpec(spec NameserverSpec) (Nameserver, error) {
+	ydns, err := t.y.Make(anchor.Nameserver, spec.retype())
+	if err != nil {
+		return nil, err
+	}
+	return yNameserver{ydns.(dns.YNameserver)}, nil
+}
+
 func (t terminal) MakeDocker(run cdocker.Run) (cdocker.Container, error) {
 	ydkr, err := t.y.Make(anchor.Docker, run)
 	if err != nil {
@@ -188,6 +248,42 @@ func (t terminal) MakeOnLeave() (Subscription, error) {
 	return ysubSub{ysub.(pubsub.YSubscription)}, nil
 }
 
+func (t terminal) MakeOnJoinSpec(spec SubscriptionSpec) (Subscription, error) {
+	ysub, err := t.y.Make(anchor.OnJoin, spec.retype())
+	if err != nil {
+		return nil, err
+	}
+	return ysubSub{ysub.(pubsub.YSubscription)}, nil
+}
+
+func (t terminal) MakeOnLeaveSpec(spec SubscriptionSpec) (Subscription, error) {
+	ysub, err := t.y.Make(anchor.OnLeave, spec.retype())
+	if err != nil {
+		return nil, err
+	}
+	return ysubSub{ysub.(pubsub.YSubscription)}, nil
+}
+
+func (t terminal) MakeOnEvent(source string, kinds []string) (Subscription, error) {
+	return t.MakeOnEventSpec(EventSpec{Source: source, Kinds: kinds})
+}
+
+func (t terminal) MakeOnEventSpec(spec EventSpec) (Subscription, error) {
+	ysub, err := t.y.Make(anchor.OnEvent, spec.retype())
+	if err != nil {
+		return nil, err
+	}
+	return ysubSub{ysub.(pubsub.YSubscription)}, nil
+}
+
+func (t terminal) MakeTable(topic string) (Table, error) {
+	ytbl, err := t.y.Make(anchor.Table, topic)
+	if err != nil {
+		return nil, err
+	}
+	return yTable{ytbl.(table.YTable)}, nil
+}
+
 func (t terminal) Get() interface{} {
 	kind, y := t.y.Get()
 	if y == nil {
@@ -208,6 +304,10 @@ func (t terminal) Get() interface{} {
 		return ysubSub{y.(pubsub.YSubscription)}
 	case anchor.OnLeave:
 		return ysubSub{y.(pubsub.YSubscription)}
+	case anchor.OnEvent:
+		return ysubSub{y.(pubsub.YSubscription)}
+	case anchor.Table:
+		return yTable{y.(table.YTable)}
 	}
 	panic("client/circuit mismatch")
 }
@@ -215,3 +315,11 @@ func (t terminal) Get() interface{} {
 func (t terminal) Scrub() {
 	t.y.Scrub()
 }
+
+func (t terminal) SetLabel(key, value string) {
+	t.y.Label(key, value)
+}
+
+func (t terminal) Labels() map[string]string {
+	return t.y.Labels()
+}
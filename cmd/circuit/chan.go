@@ -12,6 +12,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/gocircuit/circuit/client"
 	"github.com/pkg/errors"
@@ -19,7 +20,7 @@ import (
 	"github.com/urfave/cli"
 )
 
-// circuit mkchan /X1234/hola/charlie 0
+// circuit mkchan [--durable] [--ack [--visibility 30s]] [--max-bytes 1048576] [--max-duration 1m] /X1234/hola/charlie 0
 func mkchan(x *cli.Context) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -38,7 +39,26 @@ func mkchan(x *cli.Context) (err error) {
 	if err != nil || n < 0 {
 		return errors.New("second argument to mkchan must be a non-negative integral capacity")
 	}
-	if _, err = a.MakeChan(n); err != nil {
+	spec := client.ChanSpec{Cap: n, Durable: x.Bool("durable"), Ack: x.Bool("ack")}
+	if spec.Durable && n == 0 {
+		return errors.New("durable channels must have a positive capacity")
+	}
+	if x.IsSet("visibility") {
+		if spec.Visibility, err = time.ParseDuration(x.String("visibility")); err != nil || spec.Visibility <= 0 {
+			return errors.New("visibility must be a positive duration, like 30s")
+		}
+	}
+	if x.IsSet("max-bytes") {
+		if spec.MaxBytes, err = strconv.ParseInt(x.String("max-bytes"), 10, 64); err != nil || spec.MaxBytes <= 0 {
+			return errors.New("max-bytes must be a positive integer")
+		}
+	}
+	if x.IsSet("max-duration") {
+		if spec.MaxDuration, err = time.ParseDuration(x.String("max-duration")); err != nil || spec.MaxDuration <= 0 {
+			return errors.New("max-duration must be a positive duration, like 1m")
+		}
+	}
+	if _, err = a.MakeChanSpec(spec); err != nil {
 		return errors.Wrapf(err, "mkchan error: %s", err)
 	}
 	return
@@ -8,6 +8,10 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/gocircuit/circuit/client"
 	"github.com/pkg/errors"
 
@@ -32,7 +36,13 @@ func mkdns(x *cli.Context) (err error) {
 	}
 	w, _ := parseGlob(args[0])
 
-	if _, err = c.Walk(w).MakeNameserver(addr); err != nil {
+	spec := client.NameserverSpec{
+		Addr:        addr,
+		Upstream:    x.StringSlice("upstream"),
+		ServiceZone: x.String("service-zone"),
+		Replicate:   x.String("replicate"),
+	}
+	if _, err = c.Walk(w).MakeNameserverSpec(spec); err != nil {
 		return errors.Wrapf(err, "mkdns error: %s", err)
 	}
 	return
@@ -84,3 +94,60 @@ func nunset(x *cli.Context) (err error) {
 	}
 	return
 }
+
+func nload(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	args := x.Args()
+	if len(args) != 2 {
+		return errors.New("dns-load needs an anchor and a zone file (or -) arguments")
+	}
+	var r io.Reader = os.Stdin
+	if args[1] != "-" {
+		f, err := os.Open(args[1])
+		if err != nil {
+			return errors.Wrapf(err, "zone file: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	w, _ := parseGlob(args[0])
+	switch u := c.Walk(w).Get().(type) {
+	case client.Nameserver:
+		apex, err := u.LoadZone(r, x.String("origin"))
+		if err != nil {
+			return errors.Wrapf(err, "load zone error: %v", err)
+		}
+		fmt.Printf("loaded zone %s\n", apex)
+	default:
+		return errors.New("not a nameserver element")
+	}
+	return
+}
+
+func ndump(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	args := x.Args()
+	if len(args) != 1 {
+		return errors.New("dns-dump needs an anchor argument")
+	}
+	w, _ := parseGlob(args[0])
+	switch u := c.Walk(w).Get().(type) {
+	case client.Nameserver:
+		fmt.Print(u.DumpZone())
+	default:
+		return errors.New("not a nameserver element")
+	}
+	return
+}
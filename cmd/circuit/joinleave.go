@@ -8,6 +8,7 @@
 package main
 
 import (
+	"github.com/gocircuit/circuit/client"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 )
@@ -26,7 +27,7 @@ func mkonjoin(x *cli.Context) (err error) {
 		return errors.New("mk@join needs an anchor argument")
 	}
 	w, _ := parseGlob(args[0])
-	if _, err = c.Walk(w).MakeOnJoin(); err != nil {
+	if _, err = c.Walk(w).MakeOnJoinSpec(subscriptionSpec(x)); err != nil {
 		return errors.Wrapf(err, "mk@join error: %s", err)
 	}
 	return
@@ -46,8 +47,37 @@ func mkonleave(x *cli.Context) (err error) {
 		return errors.New("mk@leave needs an anchor argument")
 	}
 	w, _ := parseGlob(args[0])
-	if _, err = c.Walk(w).MakeOnLeave(); err != nil {
+	if _, err = c.Walk(w).MakeOnLeaveSpec(subscriptionSpec(x)); err != nil {
 		return errors.Wrapf(err, "mk@leave error: %s", err)
 	}
 	return
 }
+
+// circuit mk@event /X1234/hola/watch /X1234/hola/job exit restart
+func mkonevent(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	args := x.Args()
+	if len(args) < 2 {
+		return errors.New("mk@event needs an anchor and a source anchor argument, followed by optional event kinds")
+	}
+	w, _ := parseGlob(args[0])
+	spec := client.EventSpec{Source: args[1], Kinds: args[2:], Resume: x.Int64("resume")}
+	if _, err = c.Walk(w).MakeOnEventSpec(spec); err != nil {
+		return errors.Wrapf(err, "mk@event error: %s", err)
+	}
+	return
+}
+
+func subscriptionSpec(x *cli.Context) client.SubscriptionSpec {
+	return client.SubscriptionSpec{
+		Prefix:  x.StringSlice("prefix"),
+		Resume:  x.Int64("resume"),
+		Suspect: x.Bool("suspect"),
+	}
+}
@@ -25,7 +25,8 @@ import (
 	"github.com/gocircuit/circuit/use/n"
 )
 
-func load(addr *net.TCPAddr, vardir string, key []byte) n.Addr {
+// load starts the circuit runtime, returning its address and the server's resolved working directory.
+func load(addr *net.TCPAddr, vardir string, key []byte) (n.Addr, string) {
 	//debug.InstallCtrlCPanic()
 
 	// Randomize execution
@@ -53,5 +54,5 @@ func load(addr *net.TCPAddr, vardir string, key []byte) n.Addr {
 
 	// Initialize language runtime
 	circuit.Bind(lang.New(t))
-	return t.Addr()
+	return t.Addr(), dir
 }
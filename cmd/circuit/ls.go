@@ -50,6 +50,9 @@ func list(level int, prefix string, anchor client.Anchor, recurse, long, depth b
 		switch t := v.(type) {
 		case client.Server:
 			e.k = "server"
+			if a.Suspect() {
+				e.k = "suspect"
+			}
 		case client.Chan:
 			e.k = "chan"
 		case client.Proc:
@@ -63,6 +66,8 @@ func list(level int, prefix string, anchor client.Anchor, recurse, long, depth b
 			e.k = "dns"
 		case docker.Container:
 			e.k = "docker"
+		case client.Table:
+			e.k = "table"
 		case client.Subscription:
 			e.k = "@" + t.Peek().Source
 		default:
@@ -11,6 +11,7 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/urfave/cli"
 )
@@ -29,11 +30,29 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "addr, a", Value: "0.0.0.0:0", Usage: "Address of circuit server."},
 				cli.StringFlag{Name: "if", Value: "", Usage: "Bind any available port on the specified interface."},
-				cli.StringFlag{Name: "var", Value: "", Usage: "Lock and log directory for the circuit server."},
+				cli.StringFlag{Name: "var", Value: "", Usage: "Lock, log and durable channel directory for the circuit server."},
 				cli.StringFlag{Name: "join, j", Value: "", Usage: "Join a circuit through a current member by address."},
+				cli.StringFlag{Name: "seeds", Value: "", Usage: "Comma-separated addresses of circuit members, tried in turn until a join succeeds"},
+				cli.StringFlag{Name: "seeds-file", Value: "", Usage: "File listing addresses of circuit members, one per line; re-read on change"},
+				cli.StringFlag{Name: "seeds-dns", Value: "", Usage: "DNS SRV name, e.g. _circuit._tcp.example.com, or host:port whose A records list circuit members"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File with HMAC credentials for HMAC/RC4 transport security.", EnvVar: "CIRCUIT_HMAC"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
-				cli.BoolFlag{Name: "docker", Usage: "Enable docker elements; docker command must be executable"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.BoolFlag{Name: "docker", Usage: "Enable docker elements, run through the Docker Engine API"},
+				cli.StringFlag{Name: "docker-host", Value: "", Usage: "Docker Engine API address, e.g. unix:///var/run/docker.sock", EnvVar: "DOCKER_HOST"},
+				cli.StringFlag{Name: "runtime", Value: "", Usage: "Enable docker elements, run by the container runtime docker, podman or chroot"},
+				cli.StringFlag{Name: "rootfs", Value: "", Usage: "Directory of root filesystems used as images by the chroot runtime, /var/lib/circuit/rootfs by default"},
+				cli.DurationFlag{Name: "announce", Value: 2 * time.Second, Usage: "Interval between announcements of this server's membership"},
+				cli.DurationFlag{Name: "expire", Value: 4 * time.Second, Usage: "Age after which unannounced peers become suspicious, twice the announce interval by default"},
+				cli.Float64Flag{Name: "suspect-phi", Value: 3, Usage: "Failure detector level at which peers are suspected"},
+				cli.Float64Flag{Name: "fail-phi", Value: 8, Usage: "Failure detector level at which peers are declared dead and forgotten"},
+				cli.DurationFlag{Name: "heal", Value: 10 * time.Second, Usage: "Interval between attempts to rejoin departed peers after a network partition, 0 to disable"},
+				cli.DurationFlag{Name: "remember", Value: time.Hour, Usage: "Duration for which departed peers are remembered and rejoin attempted"},
+				cli.DurationFlag{Name: "entropy", Value: 30 * time.Second, Usage: "Interval between reconciliations of replicated state with a random peer, 0 to disable"},
+				cli.IntFlag{Name: "expansion-low", Value: 7, Usage: "Neighborhood size below which this server seeks more tissue peers"},
+				cli.IntFlag{Name: "expansion-high", Value: 11, Usage: "Neighborhood size up to which this server seeks tissue peers"},
+				cli.IntFlag{Name: "spread", Value: 5, Usage: "Number of random peers exchanged when joining a circuit"},
+				cli.IntFlag{Name: "depth", Value: 6, Usage: "Random walk length used to sample random peers"},
 			},
 		},
 		{
@@ -48,6 +67,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.BoolFlag{Name: "long, l", Usage: "show detailed anchor information"},
 				cli.BoolFlag{Name: "depth, de", Usage: "traverse anchors in depth-first order (leaves first)"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
@@ -59,8 +79,11 @@ func main() {
 			Usage:  "Create a subscription element, receiving server join events",
 			Action: mkonjoin,
 			Flags: []cli.Flag{
+				cli.StringSliceFlag{Name: "prefix", Usage: "only report servers whose ID begins with this prefix (repeatable)"},
+				cli.Int64Flag{Name: "resume", Usage: "resume the event stream after this sequence number"},
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -69,8 +92,24 @@ func main() {
 			Usage:  "Create a subscription element, receiving server leave events",
 			Action: mkonleave,
 			Flags: []cli.Flag{
+				cli.StringSliceFlag{Name: "prefix", Usage: "only report servers whose ID begins with this prefix (repeatable)"},
+				cli.Int64Flag{Name: "resume", Usage: "resume the event stream after this sequence number"},
+				cli.BoolFlag{Name: "suspect", Usage: "report servers as soon as they are suspected of failure, rather than when they are declared dead"},
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "mk@event",
+			Usage:  "Create a subscription element, receiving lifecycle events (start, restart, exit, close, set, unset, scrub) of the element at a source anchor",
+			Action: mkonevent,
+			Flags: []cli.Flag{
+				cli.Int64Flag{Name: "resume", Usage: "resume the event stream after this sequence number"},
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -82,6 +121,20 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "topology",
+			Usage:  "Print the overlay network connecting the circuit servers, and flag disconnected components",
+			Action: topology,
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "dot", Usage: "print the overlay in the Graphviz DOT language"},
+				cli.StringFlag{Name: "topic", Value: "", Usage: "print the peers of the replicated tube of this topic, e.g. locus, instead of the tissue neighborhoods"},
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -92,6 +145,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -102,17 +156,37 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
 		// channel-specific
+		{
+			Name:   "drain",
+			Usage:  "Gracefully remove a circuit server from its circuit, then stop it",
+			Action: drain,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "signal", Value: "", Usage: "signal sent to the server's running processes, e.g. TERM"},
+				cli.DurationFlag{Name: "wait", Usage: "duration for which the server waits for its processes to exit"},
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
 		{
 			Name:   "mkchan",
 			Usage:  "Create a channel element",
 			Action: mkchan,
 			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "durable", Usage: "keep buffered messages on the disk of the hosting server"},
+				cli.BoolFlag{Name: "ack", Usage: "lease received messages until acknowledged, redelivering them otherwise"},
+				cli.StringFlag{Name: "visibility", Value: "30s", Usage: "lease duration of a channel in acknowledgement mode"},
+				cli.StringFlag{Name: "max-bytes", Value: "", Usage: "reject messages larger than this many bytes"},
+				cli.StringFlag{Name: "max-duration", Value: "", Usage: "abort messages whose transmission takes longer than this"},
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -123,6 +197,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -131,8 +206,11 @@ func main() {
 			Usage:  "Receive data from a channel or a subscription on stadard output",
 			Action: recv,
 			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "ack", Usage: "acknowledge the message only after it has been written out"},
+				cli.BoolFlag{Name: "seq", Usage: "precede a subscription message with its sequence number"},
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -143,6 +221,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -154,6 +233,18 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "label",
+			Usage:  "Show or set the labels of an anchor",
+			Action: label,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -164,6 +255,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -173,8 +265,12 @@ func main() {
 			Usage:  "Create a nameserver element",
 			Action: mkdns,
 			Flags: []cli.Flag{
+				cli.StringSliceFlag{Name: "upstream", Usage: "forward queries for unknown names to this resolver (repeatable)"},
+				cli.StringFlag{Name: "service-zone", Usage: "publish the circuit's servers and service anchors under this domain, e.g. circuit."},
+				cli.StringFlag{Name: "replicate", Usage: "share records with the nameservers of this replica group on other servers"},
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -185,6 +281,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -195,6 +292,98 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "dns-load",
+			Usage:  "Replace the records of a zone in a nameserver element with those of an RFC 1035 zone file",
+			Action: nload,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "origin", Usage: "origin of relative names in the zone file, unless set by $ORIGIN"},
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "dns-dump",
+			Usage:  "Print the records of a nameserver element as an RFC 1035 zone file",
+			Action: ndump,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		// table-specific
+		{
+			Name:   "mktable",
+			Usage:  "Create a table element, sharing its entries with all tables of the same topic",
+			Action: mktable,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "table-put",
+			Usage:  "Set the value of a key in a table element",
+			Action: tput,
+			Flags: []cli.Flag{
+				cli.DurationFlag{Name: "ttl", Usage: "remove the entry after this duration"},
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "table-get",
+			Usage:  "Print the value of a key in a table element",
+			Action: tget,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "table-del",
+			Usage:  "Remove a key from a table element",
+			Action: tdel,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "table-ls",
+			Usage:  "List the entries of a table element, optionally restricted to keys with a prefix",
+			Action: tls,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "table-watch",
+			Usage:  "Print the changes to the entries of a table element, optionally restricted to keys with a prefix",
+			Action: twatch,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -206,10 +395,46 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.BoolFlag{Name: "scrub", Usage: "scrub the process anchor automatically on exit"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
+		{
+			Name:   "dkr-exec",
+			Usage:  "Execute a command within a docker container element, attached to this tool's standard streams",
+			Action: dkrexec,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "user, u", Value: "", Usage: "user, or user:group, to run the command as"},
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "dkr-stats",
+			Usage:  "Print the CPU, memory, network and block I/O usage of a docker container element",
+			Action: dkrstats,
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
+		{
+			Name:   "dkr-pull",
+			Usage:  "Pull a docker image onto a server, or onto all servers",
+			Action: dkrpull,
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "all", Usage: "pull the image onto every server of the circuit"},
+				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
+				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
+				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
+			},
+		},
 		{
 			Name:   "mkproc",
 			Usage:  "Create a process element",
@@ -217,6 +442,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.BoolFlag{Name: "scrub", Usage: "scrub the process anchor automatically on exit"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
@@ -228,6 +454,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -238,6 +465,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -248,6 +476,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -259,6 +488,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -269,6 +499,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
@@ -279,6 +510,7 @@ func main() {
 			Flags: []cli.Flag{
 				cli.StringFlag{Name: "dial, d", Value: "", Usage: "circuit member to dial into"},
 				cli.StringFlag{Name: "discover", Value: "228.8.8.8:8822", Usage: "Multicast address for peer server discovery", EnvVar: "CIRCUIT_DISCOVER"},
+				cli.StringFlag{Name: "cluster", Value: "", Usage: "Name of the cluster, telling it apart from others discovered over the same multicast address", EnvVar: "CIRCUIT_CLUSTER"},
 				cli.StringFlag{Name: "hmac", Value: "", Usage: "File containing HMAC credentials. Use RC4 encryption.", EnvVar: "CIRCUIT_HMAC"},
 			},
 		},
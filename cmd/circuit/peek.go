@@ -10,6 +10,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/gocircuit/circuit/client"
 	"github.com/gocircuit/circuit/client/docker"
@@ -55,6 +56,9 @@ func peek(x *cli.Context) (err error) {
 	case client.Subscription:
 		buf, _ := json.MarshalIndent(t.Peek(), "", "\t")
 		fmt.Println(string(buf))
+	case client.Table:
+		buf, _ := json.MarshalIndent(t.Peek(), "", "\t")
+		fmt.Println(string(buf))
 	case nil:
 		buf, _ := json.MarshalIndent(nil, "", "\t")
 		fmt.Println(string(buf))
@@ -80,3 +84,32 @@ func scrb(x *cli.Context) (err error) {
 	c.Walk(w).Scrub()
 	return
 }
+
+func label(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	args := x.Args()
+	if len(args) < 1 {
+		return errors.New("label needs an anchor and optional key=value arguments")
+	}
+	w, _ := parseGlob(args[0])
+	a := c.Walk(w)
+	if len(args) == 1 {
+		buf, _ := json.MarshalIndent(a.Labels(), "", "\t")
+		fmt.Println(string(buf))
+		return
+	}
+	for _, kv := range args[1:] {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			return errors.New("labels must be given as key=value, or key= to remove a label")
+		}
+		a.SetLabel(kv[:i], kv[i+1:])
+	}
+	return
+}
@@ -8,10 +8,15 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/gocircuit/circuit/client"
 	"github.com/gocircuit/circuit/client/docker"
@@ -138,3 +143,153 @@ func wait(x *cli.Context) (err error) {
 	fmt.Println(string(buf))
 	return
 }
+
+// circuit dkr-exec /X1234/hola/charlie ps aux
+func dkrexec(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	args := x.Args()
+	if len(args) < 2 {
+		return errors.New("dkr-exec needs an anchor and a command arguments")
+	}
+	w, _ := parseGlob(args[0])
+	u, ok := c.Walk(w).Get().(docker.Container)
+	if !ok {
+		return errors.New("not a docker container")
+	}
+	p, err := u.Exec(docker.Exec{Path: args[1], Args: args[2:], User: x.String("user")})
+	if err != nil {
+		return errors.Wrapf(err, "exec error: %v", err)
+	}
+	go func() {
+		q := p.Stdin()
+		io.Copy(q, os.Stdin)
+		q.Close()
+	}()
+	done := make(chan struct{})
+	go func() {
+		io.Copy(os.Stderr, p.Stderr())
+		close(done)
+	}()
+	io.Copy(os.Stdout, p.Stdout())
+	<-done
+	stat, err := p.Wait()
+	if err != nil {
+		return errors.Wrapf(err, "exec error: %v", err)
+	}
+	if stat.ExitCode != 0 {
+		os.Exit(stat.ExitCode)
+	}
+	return
+}
+
+// circuit dkr-stats /X1234/hola/charlie
+func dkrstats(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	args := x.Args()
+	if len(args) != 1 {
+		return errors.New("dkr-stats needs one anchor argument")
+	}
+	w, _ := parseGlob(args[0])
+	u, ok := c.Walk(w).Get().(docker.Container)
+	if !ok {
+		return errors.New("not a docker container")
+	}
+	stats, err := u.Stats()
+	if err != nil {
+		return errors.Wrapf(err, "stats error: %v", err)
+	}
+	buf, _ := json.MarshalIndent(stats, "", "\t")
+	fmt.Println(string(buf))
+	return
+}
+
+// circuit dkr-pull /X1234 ubuntu:14.04
+// circuit dkr-pull --all ubuntu:14.04
+func dkrpull(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	args := x.Args()
+	servers := make(map[string]client.Anchor)
+	switch {
+	case x.Bool("all") && len(args) == 1:
+		servers = c.View()
+	case !x.Bool("all") && len(args) == 2:
+		w, _ := parseGlob(args[0])
+		servers[args[0]] = c.Walk(w)
+		args = args[1:]
+	default:
+		return errors.New("dkr-pull needs a server anchor and an image arguments, or --all and an image argument")
+	}
+	image := args[0]
+	var (
+		wg     sync.WaitGroup
+		lk     sync.Mutex
+		failed []string
+	)
+	for name, a := range servers {
+		wg.Add(1)
+		go func(name string, a client.Anchor) {
+			defer wg.Done()
+			prefix := ""
+			if x.Bool("all") {
+				prefix = name + ": "
+			}
+			if err := pull(a, image, prefix); err != nil {
+				lk.Lock()
+				defer lk.Unlock()
+				fmt.Fprintf(os.Stderr, "%s%v\n", prefix, err)
+				failed = append(failed, name)
+			}
+		}(name, a)
+	}
+	wg.Wait()
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return errors.Errorf("pull of %s failed on %s", image, strings.Join(failed, ", "))
+	}
+	return
+}
+
+// pull pulls image onto the server at anchor a, printing the progress of the pull with the given prefix.
+func pull(a client.Anchor, image, prefix string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("server not reachable: %v", r)
+		}
+	}()
+	u, ok := a.Get().(client.Server)
+	if !ok {
+		return errors.New("not a server")
+	}
+	r, err := u.PullImage(image)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "error:") {
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "error:")))
+		}
+		fmt.Printf("%s%s\n", prefix, line)
+	}
+	return s.Err()
+}
@@ -33,16 +33,22 @@ func recv(x *cli.Context) (err error) {
 	w, _ := parseGlob(args[0])
 	switch u := c.Walk(w).Get().(type) {
 	case client.Chan:
+		if x.Bool("ack") {
+			return recvAck(u)
+		}
 		msgr, err := u.Recv()
 		if err != nil {
 			return errors.Wrapf(err, "recv error: %v", err)
 		}
 		io.Copy(os.Stdout, msgr)
 	case client.Subscription:
-		v, ok := u.Consume()
+		seq, v, ok := u.ConsumeSeq()
 		if !ok {
 			return errors.New("eof")
 		}
+		if x.Bool("seq") {
+			fmt.Printf("%d ", seq)
+		}
 		fmt.Println(v)
 		os.Stdout.Sync()
 	default:
@@ -50,3 +56,20 @@ func recv(x *cli.Context) (err error) {
 	}
 	return
 }
+
+// recvAck leases a message and acknowledges it only after it has been written to standard output.
+func recvAck(u client.Chan) error {
+	l, err := u.Lease()
+	if err != nil {
+		return errors.Wrapf(err, "lease error: %v", err)
+	}
+	if _, err = io.Copy(os.Stdout, l); err != nil {
+		l.Release()
+		return errors.Wrapf(err, "output error: %v", err)
+	}
+	os.Stdout.Sync()
+	if err = l.Ack(); err != nil {
+		return errors.Wrapf(err, "ack error: %v", err)
+	}
+	return nil
+}
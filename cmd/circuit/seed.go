@@ -0,0 +1,183 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package main
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocircuit/circuit/tissue"
+	"github.com/gocircuit/circuit/use/n"
+
+	"github.com/urfave/cli"
+)
+
+// seedRetry is the pause between rounds of join attempts, and between checks of the seeds file for changes.
+const seedRetry = 5 * time.Second
+
+// seeds is a list of addresses of circuit servers, through which a starting server joins its circuit.
+// Seeds are given on the command line, read from a file, or looked up in the DNS.
+// Each seed is a circuit address or a bare network address, like 10.0.0.1:11022.
+type seeds struct {
+	list []string  // seeds given on the command line
+	file string    // file listing seeds, one per line
+	mod  time.Time // modification time of file, when last read
+	dns  string    // DNS name of seeds, either an SRV name like _circuit._tcp.example.com or host:port
+}
+
+// parseSeeds returns the seeds given on the command line, or nil if none are given.
+func parseSeeds(c *cli.Context) *seeds {
+	return newSeeds(c.String("seeds"), c.String("seeds-file"), c.String("seeds-dns"))
+}
+
+// newSeeds returns the seeds in the comma-separated list, the file and under the DNS name, or nil if there are none.
+func newSeeds(list, file, dns string) *seeds {
+	s := &seeds{
+		file: file,
+		dns:  dns,
+	}
+	for _, a := range strings.Split(list, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			s.list = append(s.list, a)
+		}
+	}
+	if len(s.list) == 0 && s.file == "" && s.dns == "" {
+		return nil
+	}
+	return s
+}
+
+// joiner is the part of *tissue.Kin used to join through seeds.
+type joiner interface {
+	ReJoin(n.Addr) error
+}
+
+// join joins kin into a circuit through the first seed that accepts it, retrying until one does.
+// If seeds are read from a file, join then watches the file and joins again through the listed seeds when it changes.
+func (s *seeds) join(kin joiner) {
+	for {
+		for !s.joinAny(kin) {
+			time.Sleep(seedRetry)
+		}
+		if s.file == "" {
+			return
+		}
+		for !s.changed() {
+			time.Sleep(seedRetry)
+		}
+		log.Printf("Seeds file %s changed", s.file)
+	}
+}
+
+// joinAny tries the current seeds in turn, and returns true once a join succeeds.
+// If this server is the only seed, it is the first server of the circuit, and joinAny returns true as well.
+func (s *seeds) joinAny(kin joiner) bool {
+	list, self := s.resolve(), 0
+	for _, a := range list {
+		addr, err := n.ParseAddr(a)
+		if err != nil {
+			log.Printf("Seed %s does not parse (%v)", a, err)
+			continue
+		}
+		switch err = kin.ReJoin(addr); err {
+		case nil:
+			log.Printf("Joined circuit through seed %s", a)
+			return true
+		case tissue.ErrSelf:
+			self++
+		default:
+			log.Printf("Seed %s not joined (%v)", a, err)
+		}
+	}
+	if self > 0 && self == len(list) {
+		log.Printf("This server is the only seed; starting a new circuit")
+		return true
+	}
+	return false
+}
+
+// resolve returns the current list of seeds from all sources.
+func (s *seeds) resolve() []string {
+	r := append([]string{}, s.list...)
+	if s.file != "" {
+		r = append(r, s.read()...)
+	}
+	if s.dns != "" {
+		r = append(r, lookupSeeds(s.dns)...)
+	}
+	return r
+}
+
+// read returns the seeds listed in the seeds file. Blank lines and lines beginning with # are ignored.
+func (s *seeds) read() []string {
+	f, err := os.Open(s.file)
+	if err != nil {
+		log.Printf("Seeds file not readable (%v)", err)
+		return nil
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil {
+		s.mod = fi.ModTime()
+	}
+	var r []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r = append(r, line)
+	}
+	return r
+}
+
+// changed returns true if the seeds file was modified since it was last read.
+func (s *seeds) changed() bool {
+	fi, err := os.Stat(s.file)
+	if err != nil {
+		return false
+	}
+	return !fi.ModTime().Equal(s.mod)
+}
+
+// lookupSeeds returns the seeds published in the DNS under name.
+// Names beginning with an underscore are looked up as SRV records, whose targets and ports are the seeds.
+// Otherwise, name is of the form host:port, and the seeds are the addresses of host at port.
+func lookupSeeds(name string) []string {
+	var r []string
+	if strings.HasPrefix(name, "_") {
+		_, srv, err := net.LookupSRV("", "", name)
+		if err != nil {
+			log.Printf("Seeds SRV lookup failed (%v)", err)
+			return nil
+		}
+		for _, rr := range srv {
+			r = append(r, net.JoinHostPort(strings.TrimSuffix(rr.Target, "."), strconv.Itoa(int(rr.Port))))
+		}
+		return r
+	}
+	host, port, err := net.SplitHostPort(name)
+	if err != nil {
+		log.Printf("Seeds DNS name must be an SRV name or host:port (%v)", err)
+		return nil
+	}
+	addrs, err := net.LookupHost(host)
+	if err != nil {
+		log.Printf("Seeds lookup failed (%v)", err)
+		return nil
+	}
+	for _, a := range addrs {
+		r = append(r, net.JoinHostPort(a, port))
+	}
+	return r
+}
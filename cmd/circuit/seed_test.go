@@ -0,0 +1,86 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocircuit/circuit/tissue"
+	"github.com/gocircuit/circuit/use/n"
+)
+
+func TestNewSeeds(t *testing.T) {
+	if s := newSeeds(" , ", "", ""); s != nil {
+		t.Fatalf("expecting no seeds, got %v", s.list)
+	}
+	s := newSeeds("10.0.0.1:11022, circuit://10.0.0.2:11022/1/Q1,", "", "")
+	if s == nil || strings.Join(s.list, " ") != "10.0.0.1:11022 circuit://10.0.0.2:11022/1/Q1" {
+		t.Fatalf("unexpected seeds %v", s)
+	}
+	if s = newSeeds("", "/etc/circuit/seeds", ""); s == nil || s.file != "/etc/circuit/seeds" {
+		t.Fatalf("expecting a seeds file, got %v", s)
+	}
+}
+
+func TestSeedsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := newSeeds("", filepath.Join(dir, "seeds"), "")
+	if r := s.read(); len(r) != 0 {
+		t.Fatalf("expecting no seeds from a missing file, got %v", r)
+	}
+	if err = ioutil.WriteFile(s.file, []byte("# seeds\n\n 10.0.0.1:11022 \n\t\n10.0.0.2:11022\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !s.changed() {
+		t.Fatalf("new seeds file not reported as changed")
+	}
+	if r := s.read(); strings.Join(r, " ") != "10.0.0.1:11022 10.0.0.2:11022" {
+		t.Fatalf("unexpected seeds %v", r)
+	}
+	if s.changed() {
+		t.Fatalf("seeds file reported as changed after reading")
+	}
+	later := time.Now().Add(time.Minute)
+	if err = os.Chtimes(s.file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !s.changed() {
+		t.Fatalf("modified seeds file not reported as changed")
+	}
+}
+
+// testJoiner fails to join all seeds, either because they are down or because they are itself.
+type testJoiner struct {
+	self string
+}
+
+func (j testJoiner) ReJoin(addr n.Addr) error {
+	if addr.NetAddr().String() == j.self {
+		return tissue.ErrSelf
+	}
+	return os.ErrNotExist
+}
+
+func TestJoinSelf(t *testing.T) {
+	j := testJoiner{self: "127.0.0.1:11022"}
+	if !newSeeds("127.0.0.1:11022", "", "").joinAny(j) {
+		t.Errorf("server that is its only seed did not start a circuit")
+	}
+	if newSeeds("127.0.0.1:11022,127.0.0.1:11023", "", "").joinAny(j) {
+		t.Errorf("server started a circuit before other seeds were reached")
+	}
+}
@@ -66,6 +66,35 @@ func suicide(x *cli.Context) (err error) {
 	return
 }
 
+// circuit drain /X1234
+// circuit drain --signal TERM --wait 30s /X1234
+func drain(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	args := x.Args()
+	if len(args) != 1 {
+		return errors.New("drain needs one server anchor argument")
+	}
+	w, _ := parseGlob(args[0])
+	u, ok := c.Walk(w).Get().(client.Server)
+	if !ok {
+		return errors.New("not a server")
+	}
+	spec := client.DrainSpec{
+		Signal: x.String("signal"),
+		Wait:   x.Duration("wait"),
+	}
+	if err = u.Drain(spec); err != nil {
+		return errors.Wrapf(err, "drain error: %v", err)
+	}
+	return
+}
+
 func join(x *cli.Context) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
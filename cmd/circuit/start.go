@@ -16,6 +16,7 @@ import (
 	"path"
 
 	"github.com/gocircuit/circuit/element/docker"
+	"github.com/gocircuit/circuit/element/valve"
 	"github.com/gocircuit/circuit/kit/assemble"
 	"github.com/gocircuit/circuit/tissue"
 	"github.com/gocircuit/circuit/tissue/locus"
@@ -29,14 +30,22 @@ import (
 func server(c *cli.Context) (err error) {
 	println("CIRCUIT 2015 gocircuit.org")
 
-	if c.Bool("docker") {
-		cmd, e := docker.Init()
+	if runtime := c.String("runtime"); c.Bool("docker") || runtime != "" {
+		host := c.String("docker-host")
+		if runtime == "chroot" {
+			host = c.String("rootfs")
+		}
+		desc, e := docker.Init(runtime, host)
 		if e != nil {
-			return errors.Wrapf(e, "cannot use docker: %v", e)
+			return errors.Wrapf(e, "cannot use container runtime: %v", e)
 		}
-		log.Printf("Enabling docker elements, using %s", cmd)
+		log.Printf("Enabling docker elements, using %s", desc)
 	}
 	// parse arguments
+	tcfg, lcfg, err := parseTissue(c)
+	if err != nil {
+		return err
+	}
 	var tcpaddr = parseAddr(c) // server bind address
 	var join n.Addr            // join address of another circuit server
 	if c.IsSet("join") {
@@ -44,6 +53,7 @@ func server(c *cli.Context) (err error) {
 			return errors.Wrapf(err, "join address does not parse (%s)", err)
 		}
 	}
+	var seeds = parseSeeds(c)
 	var multicast = parseDiscover(c)
 	// server instance working directory
 	var varDir string
@@ -54,19 +64,27 @@ func server(c *cli.Context) (err error) {
 	}
 
 	// start circuit runtime
-	addr := load(tcpaddr, varDir, readkey(c))
+	addr, varDir := load(tcpaddr, varDir, readkey(c))
+
+	// durable channels are kept in the working directory
+	if err = valve.Init(path.Join(varDir, "chan")); err != nil {
+		return errors.Wrapf(err, "cannot use %s for durable channels: %v", varDir, err)
+	}
 
 	// tissue + locus
-	kin, xkin, rip := tissue.NewKin()
-	xlocus := locus.NewLocus(kin, rip)
+	kin, xkin, rip := tissue.NewKin(tcfg)
+	xlocus := locus.NewLocus(kin, rip, lcfg)
 
 	// joining
 	switch {
 	case join != nil:
 		kin.ReJoin(join)
+	case seeds != nil:
+		go seeds.join(kin)
 	case multicast != nil:
-		log.Printf("Using UDP multicast discovery on address %s", multicast.String())
-		go assemble.NewAssembler(addr, multicast).AssembleServer(
+		cluster, beaconKey := parseCluster(c)
+		log.Printf("Using UDP multicast discovery on address %s for cluster %q", multicast.String(), cluster)
+		go assemble.NewAssembler(addr, multicast, cluster, beaconKey).AssembleServer(
 			func(joinAddr n.Addr) {
 				kin.ReJoin(joinAddr)
 			},
@@ -82,6 +100,64 @@ func server(c *cli.Context) (err error) {
 	return nil
 }
 
+// parseTissue returns the topology and membership timing given on the command line, or their defaults.
+func parseTissue(c *cli.Context) (tcfg tissue.Config, lcfg locus.Config, err error) {
+	tcfg, lcfg = tissue.DefaultConfig, locus.DefaultConfig
+	if c.IsSet("expansion-low") {
+		tcfg.ExpansionLow = c.Int("expansion-low")
+	}
+	if c.IsSet("expansion-high") {
+		tcfg.ExpansionHigh = c.Int("expansion-high")
+	}
+	if c.IsSet("spread") {
+		tcfg.Spread = c.Int("spread")
+	}
+	if c.IsSet("depth") {
+		tcfg.Depth = c.Int("depth")
+	}
+	if c.IsSet("announce") {
+		lcfg.Announce = c.Duration("announce")
+	}
+	switch {
+	case c.IsSet("expire"):
+		lcfg.Expire = c.Duration("expire")
+	case c.IsSet("announce"):
+		lcfg.Expire = 2 * lcfg.Announce // unless given, expiry follows the announce interval
+	}
+	if c.IsSet("suspect-phi") {
+		lcfg.SuspectPhi = c.Float64("suspect-phi")
+	}
+	if c.IsSet("fail-phi") {
+		lcfg.FailPhi = c.Float64("fail-phi")
+	}
+	if c.IsSet("heal") {
+		lcfg.Heal = c.Duration("heal")
+	}
+	if c.IsSet("remember") {
+		lcfg.Remember = c.Duration("remember")
+	}
+	if c.IsSet("entropy") {
+		lcfg.Entropy = c.Duration("entropy")
+	}
+	if err = tcfg.Validate(); err != nil {
+		return tcfg, lcfg, errors.Wrapf(err, "tissue topology not valid: %v", err)
+	}
+	if err = lcfg.Validate(); err != nil {
+		return tcfg, lcfg, errors.Wrapf(err, "membership timing not valid: %v", err)
+	}
+	return tcfg, lcfg, nil
+}
+
+// parseCluster returns the name of the cluster, used to tell apart clusters discovering peers over
+// the same multicast address, and the key signing discovery beacons. Beacons are signed
+// with the HMAC key, if one is given along with a cluster name.
+func parseCluster(c *cli.Context) (cluster string, key []byte) {
+	if cluster = c.String("cluster"); cluster == "" {
+		return "", nil
+	}
+	return cluster, readkey(c)
+}
+
 func parseDiscover(c *cli.Context) *net.UDPAddr {
 	src := c.String("discover")
 	if src == "" {
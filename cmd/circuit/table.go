@@ -0,0 +1,172 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/gocircuit/circuit/client"
+	"github.com/pkg/errors"
+
+	"github.com/urfave/cli"
+)
+
+func mktable(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	args := x.Args()
+	if len(args) != 2 {
+		return errors.New("mktable needs an anchor and a topic arguments")
+	}
+	w, _ := parseGlob(args[0])
+	if _, err = c.Walk(w).MakeTable(args[1]); err != nil {
+		return errors.Wrapf(err, "mktable error: %s", err)
+	}
+	return
+}
+
+// getTable returns the table element at the anchor named by the first argument.
+func getTable(x *cli.Context) (client.Table, error) {
+	c := dial(x)
+	w, _ := parseGlob(x.Args()[0])
+	t, ok := c.Walk(w).Get().(client.Table)
+	if !ok {
+		return nil, errors.New("not a table element")
+	}
+	return t, nil
+}
+
+func tput(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	args := x.Args()
+	if len(args) != 3 {
+		return errors.New("table-put needs an anchor, a key and a value arguments")
+	}
+	t, err := getTable(x)
+	if err != nil {
+		return err
+	}
+	if err = t.Put(args[1], args[2], x.Duration("ttl")); err != nil {
+		return errors.Wrapf(err, "table-put error: %v", err)
+	}
+	return
+}
+
+func tget(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	args := x.Args()
+	if len(args) != 2 {
+		return errors.New("table-get needs an anchor and a key arguments")
+	}
+	t, err := getTable(x)
+	if err != nil {
+		return err
+	}
+	e, ok := t.Get(args[1])
+	if !ok {
+		return errors.New("no such key")
+	}
+	fmt.Println(e.Value)
+	return
+}
+
+func tdel(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	args := x.Args()
+	if len(args) != 2 {
+		return errors.New("table-del needs an anchor and a key arguments")
+	}
+	t, err := getTable(x)
+	if err != nil {
+		return err
+	}
+	t.Delete(args[1])
+	return
+}
+
+func tls(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	args := x.Args()
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("table-ls needs an anchor and an optional key prefix arguments")
+	}
+	var prefix string
+	if len(args) == 2 {
+		prefix = args[1]
+	}
+	t, err := getTable(x)
+	if err != nil {
+		return err
+	}
+	for _, e := range t.List(prefix) {
+		fmt.Printf("%s\t%s\n", e.Key, e.Value)
+	}
+	return
+}
+
+func twatch(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	args := x.Args()
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("table-watch needs an anchor and an optional key prefix arguments")
+	}
+	var prefix string
+	if len(args) == 2 {
+		prefix = args[1]
+	}
+	t, err := getTable(x)
+	if err != nil {
+		return err
+	}
+	sub, err := t.Watch(prefix)
+	if err != nil {
+		return errors.Wrapf(err, "table-watch error: %v", err)
+	}
+	for {
+		v, ok := sub.Consume()
+		if !ok {
+			return
+		}
+		e := v.(client.TableEntry)
+		if e.Deleted {
+			fmt.Printf("del\t%s\n", e.Key)
+			continue
+		}
+		fmt.Printf("put\t%s\t%s\n", e.Key, e.Value)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gocircuit/circuit/client"
+	"github.com/pkg/errors"
+
+	"github.com/urfave/cli"
+)
+
+// graph is the overlay of a circuit, as the adjacency lists of its servers.
+// Servers that did not respond have no adjacency list; neither do the neighbors
+// of servers that are not among the live servers of the circuit.
+type graph map[string][]string
+
+// circuit topology
+// circuit topology --dot --topic locus
+func topology(x *cli.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrapf(r.(error), "error, likely due to missing server or misspelled anchor: %v", r)
+		}
+	}()
+
+	c := dial(x)
+	if len(x.Args()) != 0 {
+		return errors.New("topology takes no arguments")
+	}
+	topic := x.String("topic")
+	var (
+		wg sync.WaitGroup
+		lk sync.Mutex
+		g  = make(graph)
+	)
+	for name, a := range c.View() {
+		wg.Add(1)
+		go func(name string, a client.Anchor) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Fprintf(os.Stderr, "%s: not responding\n", name)
+				}
+			}()
+			s, ok := a.Get().(client.Server)
+			if !ok {
+				return
+			}
+			stat := s.Peek()
+			adj := stat.Neighbors
+			if topic != "" {
+				adj = stat.Folk[topic]
+			}
+			lk.Lock()
+			defer lk.Unlock()
+			g[name] = append([]string{}, adj...)
+			sort.Strings(g[name])
+		}(name, a)
+	}
+	wg.Wait()
+	if x.Bool("dot") {
+		g.writeDot(os.Stdout)
+	} else {
+		g.write(os.Stdout)
+	}
+	if cc := g.components(); len(cc) > 1 {
+		return errors.Errorf("circuit overlay has %d disconnected components", len(cc))
+	}
+	return
+}
+
+// nodes returns the servers of the graph and their neighbors, in order.
+func (g graph) nodes() []string {
+	seen := make(map[string]bool)
+	for u, adj := range g {
+		seen[u] = true
+		for _, v := range adj {
+			seen[v] = true
+		}
+	}
+	var r []string
+	for u := range seen {
+		r = append(r, u)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// components returns the connected components of the graph, ignoring the direction of its edges.
+// Components are in order of their first server, and the servers of each component are in order.
+func (g graph) components() [][]string {
+	undirected := make(map[string][]string)
+	for u, adj := range g {
+		for _, v := range adj {
+			undirected[u] = append(undirected[u], v)
+			undirected[v] = append(undirected[v], u)
+		}
+	}
+	var r [][]string
+	seen := make(map[string]bool)
+	for _, u := range g.nodes() {
+		if seen[u] {
+			continue
+		}
+		var comp []string
+		stack := []string{u}
+		seen[u] = true
+		for len(stack) > 0 {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			comp = append(comp, w)
+			for _, v := range undirected[w] {
+				if !seen[v] {
+					seen[v] = true
+					stack = append(stack, v)
+				}
+			}
+		}
+		sort.Strings(comp)
+		r = append(r, comp)
+	}
+	return r
+}
+
+// write prints the adjacency list of each server, followed by notes on missing servers and disconnected components.
+func (g graph) write(w io.Writer) {
+	for _, u := range g.nodes() {
+		adj, ok := g[u]
+		if !ok {
+			fmt.Fprintf(w, "# %s is a neighbor, but not a live server\n", u)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", u, strings.Join(adj, " "))
+	}
+	if cc := g.components(); len(cc) > 1 {
+		fmt.Fprintf(w, "# %d disconnected components\n", len(cc))
+		for i, comp := range cc {
+			fmt.Fprintf(w, "# component %d: %s\n", i+1, strings.Join(comp, " "))
+		}
+	}
+}
+
+// writeDot prints the graph in the Graphviz DOT language. Disconnected components are drawn as separate clusters,
+// and neighbors that are not live servers are drawn dashed.
+func (g graph) writeDot(w io.Writer) {
+	fmt.Fprintf(w, "digraph circuit {\n")
+	cc := g.components()
+	for i, comp := range cc {
+		indent := "\t"
+		if len(cc) > 1 {
+			fmt.Fprintf(w, "\tsubgraph cluster_%d {\n\t\tlabel=\"component %d\";\n", i+1, i+1)
+			indent = "\t\t"
+		}
+		for _, u := range comp {
+			if _, ok := g[u]; ok {
+				fmt.Fprintf(w, "%s%q;\n", indent, u)
+			} else {
+				fmt.Fprintf(w, "%s%q [style=dashed];\n", indent, u)
+			}
+		}
+		if len(cc) > 1 {
+			fmt.Fprintf(w, "\t}\n")
+		}
+	}
+	for _, u := range g.nodes() {
+		for _, v := range g[u] {
+			fmt.Fprintf(w, "\t%q -> %q;\n", u, v)
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+}
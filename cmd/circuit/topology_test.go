@@ -0,0 +1,45 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTopology(t *testing.T) {
+	g := graph{
+		"X1": {"X2"},
+		"X2": {"X3"},
+		"X3": {},
+		"X4": {"X9"}, // X9 is not live
+	}
+	cc := g.components()
+	if len(cc) != 2 || strings.Join(cc[0], " ") != "X1 X2 X3" || strings.Join(cc[1], " ") != "X4 X9" {
+		t.Fatalf("components: %v", cc)
+	}
+	var w bytes.Buffer
+	g.write(&w)
+	for _, s := range []string{"X1\tX2\n", "# X9 is a neighbor, but not a live server\n", "# 2 disconnected components\n"} {
+		if !strings.Contains(w.String(), s) {
+			t.Errorf("adjacency list missing %q:\n%s", s, w.String())
+		}
+	}
+	w.Reset()
+	g.writeDot(&w)
+	for _, s := range []string{"subgraph cluster_2", "\"X9\" [style=dashed];", "\"X4\" -> \"X9\";"} {
+		if !strings.Contains(w.String(), s) {
+			t.Errorf("DOT output missing %q:\n%s", s, w.String())
+		}
+	}
+	delete(g, "X4")
+	if cc := g.components(); len(cc) != 1 {
+		t.Errorf("expecting a connected graph, got %v", cc)
+	}
+}
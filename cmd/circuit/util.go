@@ -55,7 +55,7 @@ func dial(x *cli.Context) *client.Client {
 				fatalf("multicast address is unresponsive or authentication failed")
 			}
 		}()
-		return client.DialDiscover(x.String("discover"), readkey(x))
+		return client.DialDiscoverCluster(x.String("discover"), x.String("cluster"), readkey(x))
 
 	case os.Getenv("CIRCUIT") != "":
 		buf, err := ioutil.ReadFile(os.Getenv("CIRCUIT"))
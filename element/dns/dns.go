@@ -8,7 +8,9 @@
 package dns
 
 import (
+	"errors"
 	"net"
+	"strings"
 	"sync"
 
 	"github.com/gocircuit/circuit/github.com/miekg/dns"
@@ -18,75 +20,115 @@ import (
 type Nameserver interface {
 	Scrub()
 	Set(rr string) error
+	Load(origin string, rr []string) error
 	Unset(name string)
 	Peek() Stat
 	X() circuit.X
 }
 
+// Spec describes a nameserver.
+type Spec struct {
+	// Addr is the address the nameserver listens on, over both UDP and TCP.
+	// If empty, an available port on all interfaces is picked.
+	Addr string
+	// Upstream lists resolvers, as host or host:port, to which queries for names
+	// outside of the nameserver's records are forwarded.
+	Upstream []string
+	// ServiceZone, if not empty, is the domain (e.g. circuit.) under which the hosting circuit
+	// automatically publishes the addresses of its servers and services.
+	ServiceZone string
+	// Replicate, if not empty, names a group of nameservers on different servers, which share their records.
+	Replicate string
+}
+
 type nameserver struct {
 	sync.Mutex
-	server *dns.Server
+	udp *dns.Server
+	tcp *dns.Server
+	fwd *forwarder // nil, unless upstream resolvers are configured
 	addr net.Addr
-	rr map[string][]dns.RR // name -> rr
+	rr map[string][]dns.RR // lower-case name -> rr
+	serial uint32 // incremented on every change to the records
+	rot uint32 // round-robin counter for address answers
 }
 
 func MakeNameserver(addr string) (_ Nameserver, err error) {
+	return Make(Spec{Addr: addr})
+}
+
+// Make starts a nameserver, as described by spec. Replicated nameservers are made with MakeReplica.
+func Make(spec Spec) (_ Nameserver, err error) {
+	if spec.Replicate != "" {
+		return nil, errors.New("replicated nameservers need a replicator")
+	}
+	return makeNameserver(spec)
+}
+
+func makeNameserver(spec Spec) (_ *nameserver, err error) {
 	ns := &nameserver{
 		rr: make(map[string][]dns.RR),
 	}
-	if err = ns.startUdpServer(addr); err != nil {
+	if len(spec.Upstream) > 0 {
+		ns.fwd = newForwarder(spec.Upstream)
+	}
+	if err = ns.startServers(spec.Addr); err != nil {
 		return nil, err
 	}
 	return ns, nil
 }
 
-func (ns *nameserver) startUdpServer(addr string) error {
-	pc, err := net.ListenPacket("udp", addr) // empty-string address picks an available port on 0.0.0.0
-	if err != nil {
-		return err
-	}
-	ns.server = &dns.Server{
-		PacketConn: pc,
-		Handler: ns,
+// startServers starts serving over UDP and TCP on the same address.
+func (ns *nameserver) startServers(addr string) error {
+	for i := 0; ; i++ {
+		pc, err := net.ListenPacket("udp", addr) // empty-string address picks an available port on 0.0.0.0
+		if err != nil {
+			return err
+		}
+		l, err := net.Listen("tcp", pc.LocalAddr().String())
+		if err != nil {
+			pc.Close()
+			if i < 8 && anyPort(addr) {
+				continue // the port picked for UDP is taken for TCP
+			}
+			return err
+		}
+		udp := &dns.Server{
+			PacketConn: pc,
+			Handler: ns,
+		}
+		tcp := &dns.Server{
+			Listener: l,
+			Handler: ns,
+		}
+		ns.udp, ns.tcp, ns.addr = udp, tcp, pc.LocalAddr()
+		go func() {
+			udp.ActivateAndServe()
+			pc.Close()
+		}()
+		go func() {
+			tcp.ActivateAndServe()
+			l.Close()
+		}()
+		return nil
 	}
-	ns.addr = pc.LocalAddr()
-	go func() {
-		ns.server.ActivateAndServe()
-		pc.Close()
-	}()
-	return nil
 }
 
-func (ns *nameserver) lookup(q string) []dns.RR {
-	ns.Lock()
-	defer ns.Unlock()
-	return ns.rr[q]
-}
-
-func (ns *nameserver) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
-	msg := new(dns.Msg)
-	msg.SetReply(req)
-	q := msg.Question[0].Name // question, e.g. "miek.nl."
-
-	rr := ns.lookup(q)
-	if len(rr) == 0 { // no entry
-		w.Close()
-		return
-	}
-
-	msg.Answer = make([]dns.RR, 1)
-	msg.Answer[0] = rr[0]
-	w.WriteMsg(msg)
+func anyPort(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	return addr == "" || (err == nil && (port == "" || port == "0"))
 }
 
 func (ns *nameserver) Scrub() {
 	ns.Lock()
-	defer ns.Unlock()
-	if ns.server == nil {
+	udp, tcp := ns.udp, ns.tcp
+	ns.udp, ns.tcp = nil, nil
+	ns.Unlock()
+	if udp == nil {
 		return
 	}
-	ns.server.Shutdown()
-	ns.server = nil
+	// Shut down without holding the lock, since queries in progress need it.
+	udp.Shutdown()
+	tcp.Shutdown()
 }
 
 func (ns *nameserver) X() circuit.X {
@@ -98,16 +140,130 @@ func (ns *nameserver) Set(rr string) error {
 	if err != nil {
 		return err
 	}
+	name := strings.ToLower(ss.Header().Name)
 	ns.Lock()
 	defer ns.Unlock()
-	ns.rr[ss.Header().Name] = append(ns.rr[ss.Header().Name], ss)
+	for _, r := range ns.rr[name] {
+		if r.String() == ss.String() {
+			return nil // already set
+		}
+	}
+	ns.rr[name] = append(ns.rr[name], ss)
+	ns.serial++
 	return nil
 }
 
+// Load replaces all records at or below the domain origin with rr, in a single step.
+// Records in rr must be owned by names at or below origin.
+func (ns *nameserver) Load(origin string, rr []string) error {
+	origin = strings.ToLower(dns.Fqdn(origin))
+	load, n, err := parseRecords(origin, rr)
+	if err != nil {
+		return err
+	}
+	ns.Lock()
+	defer ns.Unlock()
+	var old int
+	changed := false
+	for name, rr := range ns.rr {
+		if !dns.IsSubDomain(origin, name) {
+			continue
+		}
+		for _, r := range rr {
+			old++
+			changed = changed || !hasRecord(load[name], r)
+		}
+		delete(ns.rr, name)
+	}
+	for name, rr := range load {
+		ns.rr[name] = rr
+	}
+	if changed || old != n {
+		ns.serial++
+	}
+	return nil
+}
+
+// parseRecords parses the textual records rr, which must be owned by names at or below origin,
+// and groups them by owner name. Duplicate records are dropped. The number of distinct records is returned.
+func parseRecords(origin string, rr []string) (load map[string][]dns.RR, n int, err error) {
+	load = make(map[string][]dns.RR)
+	for _, s := range rr {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		r, err := dns.NewRR(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		if r == nil {
+			continue // comment
+		}
+		name := strings.ToLower(r.Header().Name)
+		if !dns.IsSubDomain(origin, name) {
+			return nil, 0, errors.New("record " + name + " outside of " + origin)
+		}
+		if hasRecord(load[name], r) {
+			continue
+		}
+		load[name] = append(load[name], r)
+		n++
+	}
+	return load, n, nil
+}
+
+func hasRecord(rr []dns.RR, r dns.RR) bool {
+	for _, x := range rr {
+		if x.String() == r.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// sameRRs returns true if a and b hold the same distinct records.
+func sameRRs(a, b []dns.RR) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, r := range a {
+		if !hasRecord(b, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// replace sets the records owned by each name in sets, or removes them if its set is empty, in a single step.
+func (ns *nameserver) replace(sets map[string][]dns.RR) {
+	ns.Lock()
+	defer ns.Unlock()
+	changed := false
+	for name, rr := range sets {
+		if sameRRs(rr, ns.rr[name]) {
+			continue
+		}
+		if len(rr) == 0 {
+			delete(ns.rr, name)
+		} else {
+			ns.rr[name] = rr
+		}
+		changed = true
+	}
+	if changed {
+		ns.serial++
+	}
+}
+
 func (ns *nameserver) Unset(name string) {
+	name = strings.ToLower(dns.Fqdn(name))
 	ns.Lock()
 	defer ns.Unlock()
+	if _, ok := ns.rr[name]; !ok {
+		return
+	}
 	delete(ns.rr, name)
+	ns.serial++
 }
 
 func (ns *nameserver) Peek() Stat {
@@ -115,6 +271,8 @@ func (ns *nameserver) Peek() Stat {
 	defer ns.Unlock()
 	var stat Stat
 	stat.Address = ns.addr.String()
+	stat.Zone = ns.apex()
+	stat.Serial = ns.serial
 	stat.Records = make(map[string][]string)
 	for name, rr := range ns.rr {
 		var ss []string
@@ -123,5 +281,8 @@ func (ns *nameserver) Peek() Stat {
 		}
 		stat.Records[name] = ss
 	}
+	if ns.fwd != nil {
+		ns.fwd.stat(&stat)
+	}
 	return stat
 }
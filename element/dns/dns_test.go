@@ -0,0 +1,338 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package dns
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocircuit/circuit/github.com/miekg/dns"
+	"github.com/gocircuit/circuit/tissue/tube"
+)
+
+func query(t *testing.T, addr, name string, qtype uint16) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion(name, qtype)
+	r, err := dns.Exchange(req, addr)
+	if err != nil {
+		t.Fatalf("exchange %s (%s)", name, err)
+	}
+	return r
+}
+
+func TestNameserver(t *testing.T) {
+	x, err := MakeNameserver("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	defer x.Scrub()
+	for _, rr := range []string{
+		"a.circuit.test. 60 IN A 10.0.0.1",
+		"a.circuit.test. 60 IN A 10.0.0.2",
+		"a.circuit.test. 60 IN TXT \"hello\"",
+		"www.circuit.test. 60 IN CNAME a.circuit.test.",
+		"_http._tcp.circuit.test. 60 IN SRV 0 0 80 a.circuit.test.",
+		"x.y.circuit.test. 60 IN A 10.0.0.3",
+	} {
+		if err = x.Set(rr); err != nil {
+			t.Fatalf("set %s (%s)", rr, err)
+		}
+	}
+	addr := x.Peek().Address
+	if z := x.Peek().Zone; z != "circuit.test." {
+		t.Fatalf("unexpected zone %s", z)
+	}
+
+	// all addresses, round-robin
+	r1, r2 := query(t, addr, "a.circuit.test.", dns.TypeA), query(t, addr, "A.circuit.test.", dns.TypeA)
+	if len(r1.Answer) != 2 || len(r2.Answer) != 2 || !r1.Authoritative {
+		t.Fatalf("expecting two authoritative answers, got %v and %v", r1, r2)
+	}
+	if r1.Answer[0].String() == r2.Answer[0].String() {
+		t.Fatalf("answers not rotated")
+	}
+	// qtype filtering
+	if r := query(t, addr, "a.circuit.test.", dns.TypeTXT); len(r.Answer) != 1 || r.Answer[0].Header().Rrtype != dns.TypeTXT {
+		t.Fatalf("expecting TXT answer, got %v", r)
+	}
+	// no data
+	if r := query(t, addr, "a.circuit.test.", dns.TypeMX); r.Rcode != dns.RcodeSuccess || len(r.Answer) != 0 || len(r.Ns) != 1 {
+		t.Fatalf("expecting no data with SOA, got %v", r)
+	}
+	// CNAME chasing
+	if r := query(t, addr, "www.circuit.test.", dns.TypeA); len(r.Answer) != 3 || r.Answer[0].Header().Rrtype != dns.TypeCNAME {
+		t.Fatalf("expecting CNAME and two addresses, got %v", r)
+	}
+	// SRV with additional addresses
+	if r := query(t, addr, "_http._tcp.circuit.test.", dns.TypeSRV); len(r.Answer) != 1 || len(r.Extra) != 2 {
+		t.Fatalf("expecting SRV with two additional addresses, got %v", r)
+	}
+	// synthesized SOA
+	if r := query(t, addr, "circuit.test.", dns.TypeSOA); len(r.Answer) != 1 || r.Answer[0].(*dns.SOA).Serial != 6 {
+		t.Fatalf("expecting SOA with serial 6, got %v", r)
+	}
+	// empty non-terminal, missing name, name outside the zone
+	if r := query(t, addr, "y.circuit.test.", dns.TypeA); r.Rcode != dns.RcodeSuccess {
+		t.Fatalf("expecting no data, got %v", r)
+	}
+	if r := query(t, addr, "b.circuit.test.", dns.TypeA); r.Rcode != dns.RcodeNameError || len(r.Ns) != 1 {
+		t.Fatalf("expecting NXDOMAIN with SOA, got %v", r)
+	}
+	if r := query(t, addr, "example.com.", dns.TypeA); r.Rcode != dns.RcodeRefused {
+		t.Fatalf("expecting REFUSED, got %v", r)
+	}
+	// unsupported opcode
+	req := new(dns.Msg)
+	req.SetNotify("circuit.test.")
+	if r, err := dns.Exchange(req, addr); err != nil || r.Rcode != dns.RcodeNotImplemented {
+		t.Fatalf("expecting NOTIMP, got %v (%v)", r, err)
+	}
+}
+
+func TestForward(t *testing.T) {
+	up, err := MakeNameserver("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("make upstream (%s)", err)
+	}
+	defer up.Scrub()
+	if err = up.Set("www.example.org. 300 IN A 10.1.1.1"); err != nil {
+		t.Fatalf("set (%s)", err)
+	}
+	x, err := Make(Spec{Addr: "127.0.0.1:0", Upstream: []string{up.Peek().Address}})
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	defer x.Scrub()
+	if err = x.Set("a.circuit.test. 60 IN A 10.0.0.1"); err != nil {
+		t.Fatalf("set (%s)", err)
+	}
+	addr := x.Peek().Address
+
+	// local names are served over TCP as well
+	req := new(dns.Msg)
+	req.SetQuestion("a.circuit.test.", dns.TypeA)
+	c := &dns.Client{Net: "tcp"}
+	if r, _, err := c.Exchange(req, addr); err != nil || len(r.Answer) != 1 {
+		t.Fatalf("expecting answer over TCP, got %v (%v)", r, err)
+	}
+	// other names are forwarded, then answered from the cache
+	for i := 0; i < 2; i++ {
+		if r := query(t, addr, "www.example.org.", dns.TypeA); len(r.Answer) != 1 || r.Answer[0].(*dns.A).A.String() != "10.1.1.1" {
+			t.Fatalf("expecting forwarded answer, got %v", r)
+		}
+	}
+	// names missing from the zone are not forwarded
+	if r := query(t, addr, "b.a.circuit.test.", dns.TypeA); r.Rcode != dns.RcodeNameError || !r.Authoritative {
+		t.Fatalf("expecting authoritative NXDOMAIN, got %v", r)
+	}
+	if s := x.Peek(); s.Forwarded != 1 || s.CacheHits != 1 {
+		t.Fatalf("expecting one forwarded query and one cache hit, got %d and %d", s.Forwarded, s.CacheHits)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	msg := new(dns.Msg)
+	msg.SetQuestion("a.circuit.test.", dns.TypeTXT)
+	for i := 0; i < 20; i++ {
+		rr, _ := dns.NewRR("a.circuit.test. 60 IN TXT \"0123456789012345678901234567890123456789\"")
+		msg.Answer = append(msg.Answer, rr)
+	}
+	truncate(msg, dns.MinMsgSize)
+	if !msg.Truncated || msg.Len() > dns.MinMsgSize || len(msg.Answer) == 0 {
+		t.Fatalf("bad truncation to %d bytes with %d answers", msg.Len(), len(msg.Answer))
+	}
+}
+
+const testZone = `$ORIGIN circuit.test.
+$TTL 300
+@	IN SOA ns hostmaster 7 3600 600 86400 60
+	IN NS ns
+ns	IN A 10.0.0.53
+www	IN A 10.0.0.1
+	IN A 10.0.0.2 ; second address
+api	60 IN CNAME www
+`
+
+func TestLoadZone(t *testing.T) {
+	x, err := MakeNameserver("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	defer x.Scrub()
+	for _, rr := range []string{"old.circuit.test. 60 IN A 10.9.9.9", "other.test. 60 IN A 10.9.9.8"} {
+		if err = x.Set(rr); err != nil {
+			t.Fatalf("set (%s)", err)
+		}
+	}
+	apex, rr, err := ParseZone(strings.NewReader(testZone), "", "")
+	if err != nil || apex != "circuit.test." || len(rr) != 6 {
+		t.Fatalf("parse: apex %s, %d records (%v)", apex, len(rr), err)
+	}
+	for _, z := range []string{"", "a.example.org. 60 IN A 10.1.1.1\nb.example.com. 60 IN A 10.1.1.2\n"} {
+		if _, _, err := ParseZone(strings.NewReader(z), "", ""); err == nil {
+			t.Fatalf("expecting zone at the root to be rejected:\n%s", z)
+		}
+		if apex, _, err := ParseZone(strings.NewReader(z), ".", ""); err != nil || apex != "." {
+			t.Fatalf("expecting root zone with explicit origin, got %q (%v)", apex, err)
+		}
+	}
+	if err = x.Load(apex, append(rr, "a.example.org. 60 IN A 10.1.1.1")); err == nil {
+		t.Fatalf("expecting record outside of origin to be rejected")
+	}
+	if n := len(x.Peek().Records); n != 2 {
+		t.Fatalf("failed load changed records")
+	}
+	if err = x.Load(apex, rr); err != nil {
+		t.Fatalf("load (%s)", err)
+	}
+	stat := x.Peek()
+	if len(stat.Records["old.circuit.test."]) != 0 || len(stat.Records["other.test."]) != 1 || len(stat.Records["www.circuit.test."]) != 2 {
+		t.Fatalf("unexpected records after load %v", stat.Records)
+	}
+	serial := stat.Serial
+	if err = x.Load(apex, rr); err != nil || x.Peek().Serial != serial {
+		t.Fatalf("reloading the same records must not change the serial (%v)", err)
+	}
+	if r := query(t, stat.Address, "api.circuit.test.", dns.TypeA); len(r.Answer) != 3 || r.Answer[0].Header().Ttl != 60 {
+		t.Fatalf("expecting CNAME and two addresses, got %v", r)
+	}
+
+	// dumped records load back unchanged
+	dump := DumpZone(stat)
+	if !strings.HasPrefix(dump, "; zone ") {
+		t.Fatalf("missing dump header")
+	}
+	_, rr2, err := ParseZone(strings.NewReader(dump), "", "")
+	if err != nil || len(rr2) != 7 {
+		t.Fatalf("dump does not parse back: %d records (%v)\n%s", len(rr2), err, dump)
+	}
+}
+
+type testReplicator struct {
+	*tube.View
+}
+
+func (r testReplicator) BulkRead() []*tube.Record {
+	return r.Peek()
+}
+
+func (r testReplicator) BulkWrite(bulk []*tube.Record) {
+	for _, x := range bulk {
+		r.Update(x)
+	}
+}
+
+func TestReplica(t *testing.T) {
+	repl := testReplicator{tube.NewView()}
+	mkReplica := func(group string) Nameserver {
+		x, err := MakeReplica(Spec{Addr: "127.0.0.1:0", Replicate: group}, repl)
+		if err != nil {
+			t.Fatalf("make (%s)", err)
+		}
+		return x
+	}
+	eventually := func(x Nameserver, name string, n int) {
+		for deadline := time.Now().Add(5 * time.Second); len(x.Peek().Records[name]) != n; time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expecting %d records for %s, got %v", n, name, x.Peek().Records)
+			}
+		}
+	}
+	a, other := mkReplica("g"), mkReplica("h")
+	defer a.Scrub()
+	defer other.Scrub()
+	if err := a.Set("a.circuit.test. 60 IN A 10.0.0.1"); err != nil {
+		t.Fatalf("set (%s)", err)
+	}
+	if err := a.Set("a.circuit.test. 60 IN A 10.0.0.2"); err != nil {
+		t.Fatalf("set (%s)", err)
+	}
+	// replicas made later receive the records of their group
+	b := mkReplica("g")
+	defer b.Scrub()
+	eventually(b, "a.circuit.test.", 2)
+	if r := query(t, b.Peek().Address, "a.circuit.test.", dns.TypeA); len(r.Answer) != 2 {
+		t.Fatalf("expecting two answers from replica, got %v", r)
+	}
+	b.Unset("a.circuit.test.")
+	eventually(a, "a.circuit.test.", 0)
+	if err := b.Load("circuit.test.", []string{"b.circuit.test. 60 IN A 10.0.0.3", "c.circuit.test. 60 IN A 10.0.0.4"}); err != nil {
+		t.Fatalf("load (%s)", err)
+	}
+	eventually(a, "b.circuit.test.", 1)
+	eventually(a, "c.circuit.test.", 1)
+	if err := a.Load("circuit.test.", []string{"b.circuit.test. 60 IN A 10.0.0.3"}); err != nil {
+		t.Fatalf("load (%s)", err)
+	}
+	eventually(b, "c.circuit.test.", 0)
+	// removals are kept for TombstoneTTL, then forgotten
+	tombstones := func() (n int) {
+		for _, rec := range repl.BulkRead() {
+			if len(rec.Value.(*rrset).RR) == 0 {
+				n++
+			}
+		}
+		return n
+	}
+	reap(repl, time.Now())
+	if n := tombstones(); n != 2 {
+		t.Fatalf("expecting 2 removals, got %d", n)
+	}
+	reap(repl, time.Now().Add(TombstoneTTL+time.Second))
+	if n := tombstones(); n != 0 || len(repl.BulkRead()) != 1 {
+		t.Fatalf("expecting removals forgotten and 1 name left, got %d removals of %d names", n, len(repl.BulkRead()))
+	}
+	eventually(a, "b.circuit.test.", 1)
+	if s := other.Peek(); len(s.Records) != 0 || s.Replicate != "h" {
+		t.Fatalf("records leaked to another group: %v", s)
+	}
+	// scrubbed replicas stop receiving the updates of their group
+	other.Scrub()
+	for i := 0; !other.(*replica).sub.Peek().Closed; i++ {
+		if i == 100 {
+			t.Fatalf("subscription of scrubbed replica left open")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReplicaBatch(t *testing.T) {
+	repl := testReplicator{tube.NewView()}
+	x, err := MakeReplica(Spec{Addr: "127.0.0.1:0", Replicate: "g"}, repl)
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	defer x.Scrub()
+	write := func(batch string, size int, names ...string) {
+		var bulk []*tube.Record
+		for _, name := range names {
+			rr := []string{name + " 60 IN A 10.0.0.1"}
+			bulk = append(bulk, &tube.Record{Key: "g/" + name, Rev: 1, Value: &rrset{RR: rr, Batch: batch, Size: size}})
+		}
+		repl.BulkWrite(bulk)
+	}
+	// a complete batch is applied at once
+	write("b1", 2, "a.circuit.test.", "b.circuit.test.")
+	for deadline := time.Now().Add(batchWait / 2); len(x.Peek().Records) != 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("complete batch not applied, got %v", x.Peek().Records)
+		}
+	}
+	// an incomplete batch is held back until batchWait
+	write("b2", 3, "c.circuit.test.", "d.circuit.test.")
+	time.Sleep(batchWait / 4)
+	if n := len(x.Peek().Records); n != 2 {
+		t.Fatalf("incomplete batch applied early, got %d names", n)
+	}
+	for deadline := time.Now().Add(5 * batchWait); len(x.Peek().Records) != 4; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("incomplete batch never applied, got %v", x.Peek().Records)
+		}
+	}
+}
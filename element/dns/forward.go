@@ -0,0 +1,212 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package dns
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocircuit/circuit/github.com/miekg/dns"
+)
+
+const (
+	// cacheSize bounds the number of upstream responses held in the cache.
+	cacheSize = 4096
+	// maxCacheTTL bounds the time an upstream response is cached.
+	maxCacheTTL = time.Hour
+	// upstreamTimeout bounds each exchange with an upstream resolver.
+	upstreamTimeout = 2 * time.Second
+)
+
+type cacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+type cacheEntry struct {
+	msg     *dns.Msg
+	stored  time.Time
+	expires time.Time
+}
+
+// forwarder resolves queries via upstream resolvers, caching their responses for the duration of their TTLs.
+type forwarder struct {
+	upstream []string
+	udp      *dns.Client
+	tcp      *dns.Client
+	sync.Mutex
+	cache        map[cacheKey]*cacheEntry
+	numForwarded int64
+	numCacheHits int64
+}
+
+func newForwarder(upstream []string) *forwarder {
+	f := &forwarder{
+		udp:   &dns.Client{DialTimeout: upstreamTimeout, ReadTimeout: upstreamTimeout, WriteTimeout: upstreamTimeout},
+		tcp:   &dns.Client{Net: "tcp", DialTimeout: upstreamTimeout, ReadTimeout: upstreamTimeout, WriteTimeout: upstreamTimeout},
+		cache: make(map[cacheKey]*cacheEntry),
+	}
+	for _, u := range upstream {
+		if _, _, err := net.SplitHostPort(u); err != nil {
+			u = net.JoinHostPort(u, "53")
+		}
+		f.upstream = append(f.upstream, u)
+	}
+	return f
+}
+
+// forward answers req from the cache, or else from the first upstream resolver that responds.
+func (f *forwarder) forward(req *dns.Msg) *dns.Msg {
+	q := req.Question[0]
+	key := cacheKey{strings.ToLower(q.Name), q.Qtype, q.Qclass}
+	if r := f.lookup(key, req.Id); r != nil {
+		return r
+	}
+	f.Lock()
+	f.numForwarded++
+	f.Unlock()
+	for _, u := range f.upstream {
+		r, _, err := f.udp.Exchange(req, u)
+		if err == nil && r.Truncated {
+			r, _, err = f.tcp.Exchange(req, u)
+		}
+		if err != nil {
+			continue
+		}
+		f.store(key, r)
+		return r
+	}
+	msg := new(dns.Msg)
+	msg.SetRcode(req, dns.RcodeServerFailure)
+	return msg
+}
+
+// lookup returns a copy of a cached response with the given id, and with TTLs reduced by the time spent in the cache.
+func (f *forwarder) lookup(key cacheKey, id uint16) *dns.Msg {
+	f.Lock()
+	defer f.Unlock()
+	e, ok := f.cache[key]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if !now.Before(e.expires) {
+		delete(f.cache, key)
+		return nil
+	}
+	f.numCacheHits++
+	r := e.msg.Copy()
+	r.Id = id
+	age := uint32(now.Sub(e.stored) / time.Second)
+	for _, s := range [][]dns.RR{r.Answer, r.Ns, r.Extra} {
+		for _, rr := range s {
+			if h := rr.Header(); h.Rrtype != dns.TypeOPT {
+				h.Ttl -= age
+			}
+		}
+	}
+	return r
+}
+
+// store caches a successful or negative response for its lowest TTL.
+func (f *forwarder) store(key cacheKey, r *dns.Msg) {
+	if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+		return
+	}
+	ttl := cacheTTL(r)
+	if ttl <= 0 {
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	now := time.Now()
+	if len(f.cache) >= cacheSize {
+		for k, e := range f.cache {
+			if !now.Before(e.expires) {
+				delete(f.cache, k)
+			}
+		}
+	}
+	for k := range f.cache { // evict an arbitrary entry, if still full
+		if len(f.cache) < cacheSize {
+			break
+		}
+		delete(f.cache, k)
+	}
+	f.cache[key] = &cacheEntry{msg: r.Copy(), stored: now, expires: now.Add(ttl)}
+}
+
+// cacheTTL returns the lowest TTL of the records in r, or the negative-caching TTL of the
+// zone's SOA record for responses without answers.
+func cacheTTL(r *dns.Msg) time.Duration {
+	var ttl uint32
+	var found bool
+	min := func(t uint32) {
+		if !found || t < ttl {
+			ttl, found = t, true
+		}
+	}
+	if len(r.Answer) == 0 {
+		for _, rr := range r.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				min(soa.Hdr.Ttl)
+				min(soa.Minttl)
+			}
+		}
+	} else {
+		for _, s := range [][]dns.RR{r.Answer, r.Ns, r.Extra} {
+			for _, rr := range s {
+				if h := rr.Header(); h.Rrtype != dns.TypeOPT {
+					min(h.Ttl)
+				}
+			}
+		}
+	}
+	d := time.Duration(ttl) * time.Second
+	if d > maxCacheTTL {
+		d = maxCacheTTL
+	}
+	return d
+}
+
+func (f *forwarder) stat(s *Stat) {
+	f.Lock()
+	defer f.Unlock()
+	s.Upstream = f.upstream
+	s.Forwarded = f.numForwarded
+	s.CacheHits = f.numCacheHits
+}
+
+// truncate trims msg to fit in size bytes, setting the TC bit if anything was removed,
+// so that resolvers retry over TCP.
+func truncate(msg *dns.Msg, size int) {
+	for msg.Len() > size {
+		msg.Truncated = true
+		switch {
+		case len(msg.Extra) > 0:
+			msg.Extra = msg.Extra[:len(msg.Extra)-1]
+		case len(msg.Ns) > 0:
+			msg.Ns = msg.Ns[:len(msg.Ns)-1]
+		case len(msg.Answer) > 0:
+			msg.Answer = msg.Answer[:len(msg.Answer)-1]
+		default:
+			return
+		}
+	}
+}
+
+// udpSize returns the largest UDP response the sender of req accepts.
+func udpSize(req *dns.Msg) int {
+	if opt := req.IsEdns0(); opt != nil && int(opt.UDPSize()) > dns.MinMsgSize {
+		return int(opt.UDPSize())
+	}
+	return dns.MinMsgSize
+}
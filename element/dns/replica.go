@@ -0,0 +1,323 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package dns
+
+import (
+	"encoding/gob"
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocircuit/circuit/github.com/miekg/dns"
+	"github.com/gocircuit/circuit/kit/pubsub"
+	"github.com/gocircuit/circuit/tissue/tube"
+	"github.com/gocircuit/circuit/use/circuit"
+)
+
+// ReplicaTopic is the topic of the tube that carries the records of all replicated nameservers.
+const ReplicaTopic = "dns"
+
+// Replicator is a key-value table shared among the servers of a circuit, like tube.Tube.
+type Replicator interface {
+	Lookup(key string) *tube.Record
+	BulkRead() []*tube.Record
+	BulkWrite(bulk []*tube.Record)
+	Forget(key string, notAfterRev tube.Rev, notAfterUpdated time.Time) bool
+	NewUpdates(from int64, match pubsub.Match) (*pubsub.Subscription, error)
+}
+
+// rrset is the replicated value for a name, holding all of its records.
+// An empty set records the removal of the name's records, and is kept for TombstoneTTL.
+// Names changed together, as by Load, are marked with the same batch, so that replicas apply their changes at once.
+type rrset struct {
+	RR    []string
+	Batch string // identifies the change of several names, if not empty
+	Size  int    // number of names changed in the batch
+}
+
+// batchWait bounds the time for which the changes of a batch are held back, until all of them arrive.
+// Changes of a batch that have been superseded by later ones in transit never arrive.
+const batchWait = time.Second
+
+// TombstoneTTL is the duration for which the removal of a name's records is remembered.
+// A change of the name that is older than its removal, and reaches a server later than TombstoneTTL
+// after the removal, revives the name's records.
+const TombstoneTTL = time.Hour
+
+// Reap periodically forgets the removals of records older than TombstoneTTL, from the replicated
+// nameservers of all groups. Removals are forgotten by each server independently.
+func Reap(repl Replicator, interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		reap(repl, time.Now())
+	}
+}
+
+// reap forgets the removals of records that are older than TombstoneTTL at time now.
+func reap(repl Replicator, now time.Time) {
+	for _, rec := range repl.BulkRead() {
+		if set, ok := rec.Value.(*rrset); ok && len(set.RR) == 0 && now.Sub(rec.Updated) > TombstoneTTL {
+			repl.Forget(rec.Key, rec.Rev, time.Time{})
+		}
+	}
+}
+
+func init() {
+	gob.Register(&rrset{})
+}
+
+// replica is a nameserver whose records are kept in a replicator, under keys of the form group/name.
+// Changes are written to the replicator, and applied to the local records as they are reported back
+// by the replicator, so all replicas of a group converge to the same records. Concurrent changes to
+// the same name are resolved in favor of the later one.
+//
+// Revisions are taken from the wall clocks of the servers making the changes, so the clocks of
+// servers should agree. A change made on a server whose clock runs behind, within the skew of
+// a concurrent change of the same name made elsewhere, loses to the latter even if it is later.
+type replica struct {
+	*nameserver
+	group string
+	repl  Replicator
+	wlk   sync.Mutex // serializes read-modify-write cycles on the replicator
+	sub   *pubsub.Subscription
+	stop  sync.Once
+	abort chan struct{}
+}
+
+// MakeReplica starts a nameserver, as described by spec, which shares its records with the other
+// nameservers of the group spec.Replicate through repl.
+func MakeReplica(spec Spec, repl Replicator) (_ Nameserver, err error) {
+	if spec.Replicate == "" || strings.Contains(spec.Replicate, "/") {
+		return nil, errors.New("invalid replica group name")
+	}
+	r := &replica{
+		group: spec.Replicate,
+		repl:  repl,
+		abort: make(chan struct{}),
+	}
+	prefix := r.key("")
+	if r.sub, err = repl.NewUpdates(0, func(v interface{}) bool {
+		return strings.HasPrefix(v.(*tube.Record).Key, prefix)
+	}); err != nil {
+		return nil, err
+	}
+	spec.Replicate = ""
+	if r.nameserver, err = makeNameserver(spec); err != nil {
+		r.sub.Scrub()
+		return nil, err
+	}
+	go r.loop(r.sub)
+	return r, nil
+}
+
+func (r *replica) key(name string) string {
+	return r.group + "/" + name
+}
+
+// loop applies the changes reported by the replicator to the local records, until the replica is scrubbed.
+// The loop begins with the records present in the replicator. The changes of a batch are applied
+// together, once all of them have arrived, or after batchWait.
+func (r *replica) loop(sub *pubsub.Subscription) {
+	ch := make(chan *tube.Record)
+	go func() {
+		defer close(ch)
+		for {
+			v, ok := sub.Consume()
+			if !ok {
+				return
+			}
+			select {
+			case ch <- v.(*tube.Record):
+			case <-r.abort:
+				return
+			}
+		}
+	}()
+	batches := make(map[string][]string) // names received so far, by incomplete batch
+	var flush <-chan time.Time
+	for {
+		select {
+		case rec, ok := <-ch:
+			if !ok {
+				return
+			}
+			name := strings.TrimPrefix(rec.Key, r.key(""))
+			set, _ := rec.Value.(*rrset)
+			if set == nil || set.Batch == "" {
+				r.apply([]string{name})
+				continue
+			}
+			names := append(batches[set.Batch], name)
+			if len(names) < set.Size {
+				batches[set.Batch] = names
+				if flush == nil {
+					flush = time.After(batchWait)
+				}
+				continue
+			}
+			delete(batches, set.Batch)
+			r.apply(names)
+		case <-flush:
+			flush = nil
+			var names []string
+			for _, b := range batches {
+				names = append(names, b...)
+			}
+			batches = make(map[string][]string)
+			r.apply(names)
+		case <-r.abort:
+			return
+		}
+	}
+}
+
+// apply replaces the local records of the given names with their current replicated records, in a single step.
+func (r *replica) apply(names []string) {
+	sets := make(map[string][]dns.RR, len(names))
+	for _, name := range names {
+		var rr []dns.RR
+		for _, s := range r.lookup(name) {
+			if x, err := dns.NewRR(s); err == nil && x != nil {
+				rr = append(rr, x)
+			}
+		}
+		sets[name] = rr
+	}
+	r.nameserver.replace(sets)
+}
+
+// lookup returns the replicated records of name.
+func (r *replica) lookup(name string) []string {
+	rec := r.repl.Lookup(r.key(name))
+	if rec == nil {
+		return nil
+	}
+	if set, ok := rec.Value.(*rrset); ok {
+		return set.RR
+	}
+	return nil
+}
+
+// write replicates the records of the given names, in bulk and as one batch. The revision of each name is its
+// time of change, but always greater than that of the current record.
+func (r *replica) write(sets map[string][]string) {
+	if len(sets) == 0 {
+		return
+	}
+	now := tube.Rev(time.Now().UnixNano())
+	var batch string
+	if len(sets) > 1 {
+		batch = strconv.FormatInt(int64(now), 36) + "." + strconv.FormatInt(rand.Int63(), 36)
+	}
+	bulk := make([]*tube.Record, 0, len(sets))
+	for name, rr := range sets {
+		key, rev := r.key(name), now
+		if cur := r.repl.Lookup(key); cur != nil && cur.Rev >= rev {
+			rev = cur.Rev + 1
+		}
+		bulk = append(bulk, &tube.Record{Key: key, Rev: rev, Value: &rrset{RR: rr, Batch: batch, Size: len(sets)}})
+	}
+	r.repl.BulkWrite(bulk)
+}
+
+func (r *replica) Set(s string) error {
+	x, err := dns.NewRR(s)
+	if err != nil {
+		return err
+	}
+	name := strings.ToLower(x.Header().Name)
+	r.wlk.Lock()
+	defer r.wlk.Unlock()
+	cur := r.lookup(name)
+	for _, c := range cur {
+		if c == x.String() {
+			return nil // already set
+		}
+	}
+	r.write(map[string][]string{name: append(cur[:len(cur):len(cur)], x.String())})
+	return nil
+}
+
+func (r *replica) Unset(name string) {
+	name = strings.ToLower(dns.Fqdn(name))
+	r.wlk.Lock()
+	defer r.wlk.Unlock()
+	if len(r.lookup(name)) == 0 {
+		return
+	}
+	r.write(map[string][]string{name: nil})
+}
+
+func (r *replica) Load(origin string, rr []string) error {
+	origin = strings.ToLower(dns.Fqdn(origin))
+	load, _, err := parseRecords(origin, rr)
+	if err != nil {
+		return err
+	}
+	r.wlk.Lock()
+	defer r.wlk.Unlock()
+	sets := make(map[string][]string)
+	for _, rec := range r.repl.BulkRead() {
+		name := strings.TrimPrefix(rec.Key, r.key(""))
+		if name == rec.Key || !dns.IsSubDomain(origin, name) || len(r.lookup(name)) == 0 {
+			continue
+		}
+		if _, ok := load[name]; !ok {
+			sets[name] = nil
+		}
+	}
+	for name, rr := range load {
+		var ss []string
+		for _, x := range rr {
+			ss = append(ss, x.String())
+		}
+		if !sameRecords(ss, r.lookup(name)) {
+			sets[name] = ss
+		}
+	}
+	r.write(sets)
+	return nil
+}
+
+func sameRecords(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	m := make(map[string]bool)
+	for _, x := range a {
+		m[x] = true
+	}
+	for _, x := range b {
+		if !m[x] {
+			return false
+		}
+	}
+	return true
+}
+
+// Scrub stops this replica. The records of the group remain with the other replicas.
+func (r *replica) Scrub() {
+	r.stop.Do(func() {
+		close(r.abort)
+		r.sub.Scrub()
+	})
+	r.nameserver.Scrub()
+}
+
+func (r *replica) Peek() Stat {
+	stat := r.nameserver.Peek()
+	stat.Replicate = r.group
+	return stat
+}
+
+func (r *replica) X() circuit.X {
+	return circuit.Ref(XNameserver{r})
+}
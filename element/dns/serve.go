@@ -0,0 +1,277 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package dns
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gocircuit/circuit/github.com/miekg/dns"
+)
+
+const (
+	// DefaultTTL is the TTL of the SOA, NS and glue records synthesized by the nameserver.
+	DefaultTTL = 60
+	// maxChase bounds the length of CNAME chains followed within the nameserver's records.
+	maxChase = 8
+)
+
+func (ns *nameserver) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	msg := ns.answer(req)
+	if ns.fwd != nil && msg.Rcode == dns.RcodeRefused { // names outside of the zone
+		msg = ns.fwd.forward(req)
+	}
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		truncate(msg, udpSize(req))
+	}
+	w.WriteMsg(msg)
+}
+
+// answer computes the response to req from the records of the nameserver.
+func (ns *nameserver) answer(req *dns.Msg) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetReply(req)
+	if req.Opcode != dns.OpcodeQuery {
+		msg.Rcode = dns.RcodeNotImplemented
+		return msg
+	}
+	if len(req.Question) != 1 {
+		msg.Rcode = dns.RcodeFormatError
+		return msg
+	}
+	q := req.Question[0]
+	if q.Qclass != dns.ClassINET && q.Qclass != dns.ClassANY {
+		msg.Rcode = dns.RcodeNotImplemented
+		return msg
+	}
+	switch q.Qtype {
+	case dns.TypeAXFR, dns.TypeIXFR, dns.TypeMAILA, dns.TypeMAILB:
+		msg.Rcode = dns.RcodeNotImplemented
+		return msg
+	}
+
+	ns.Lock()
+	defer ns.Unlock()
+	apex := ns.apex()
+	name := strings.ToLower(q.Name)
+	if !dns.IsSubDomain(apex, name) {
+		msg.Rcode = dns.RcodeRefused
+		return msg
+	}
+	msg.Authoritative = true
+	var answered bool
+	seen := make(map[string]bool)
+	for hop := 0; ; hop++ {
+		seen[name] = true
+		rr := ns.records(name, apex)
+		if len(rr) == 0 {
+			if !ns.nonTerminal(name) {
+				msg.Rcode = dns.RcodeNameError
+			}
+			break
+		}
+		if match := filter(rr, q.Qtype); len(match) > 0 {
+			msg.Answer = append(msg.Answer, ns.rotate(match)...)
+			answered = true
+			break
+		}
+		cname := findCNAME(rr)
+		if cname == nil || hop == maxChase {
+			break
+		}
+		msg.Answer = append(msg.Answer, cname)
+		name = strings.ToLower(cname.Target)
+		if seen[name] || !dns.IsSubDomain(apex, name) {
+			answered = true // resolvers continue the chase outside of our records
+			break
+		}
+	}
+	if !answered {
+		msg.Ns = []dns.RR{ns.soa(apex)}
+	}
+	msg.Extra = ns.glue(msg.Answer, apex)
+	return msg
+}
+
+// apex returns the apex of the zone served by the nameserver. It is the owner name of an explicitly
+// set SOA record, if any, and otherwise the longest domain common to all names with records.
+// The lock must be held.
+func (ns *nameserver) apex() string {
+	var apex string
+	for name, rr := range ns.rr {
+		for _, r := range rr {
+			if r.Header().Rrtype == dns.TypeSOA {
+				return name
+			}
+		}
+		if apex == "" {
+			apex = name
+		} else {
+			apex = commonDomain(apex, name)
+		}
+	}
+	if apex == "" {
+		return "."
+	}
+	return apex
+}
+
+// commonDomain returns the longest domain that both a and b belong to.
+func commonDomain(a, b string) string {
+	n := dns.CompareDomainName(a, b)
+	if n == 0 {
+		return "."
+	}
+	labels := dns.SplitDomainName(a)
+	return dns.Fqdn(strings.Join(labels[len(labels)-n:], "."))
+}
+
+// records returns the records owned by name, including the SOA and NS records of the apex and the
+// address of its nameserver, which are synthesized unless set explicitly. The lock must be held.
+func (ns *nameserver) records(name, apex string) []dns.RR {
+	rr := ns.rr[name]
+	if name == apex {
+		if !hasType(rr, dns.TypeSOA) {
+			rr = append(rr[:len(rr):len(rr)], ns.soa(apex))
+		}
+		if !hasType(rr, dns.TypeNS) {
+			rr = append(rr[:len(rr):len(rr)], &dns.NS{
+				Hdr: dns.RR_Header{Name: apex, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: DefaultTTL},
+				Ns:  nsName(apex),
+			})
+		}
+	}
+	if name == nsName(apex) && len(rr) == 0 {
+		if a := ns.self(); a != nil {
+			rr = []dns.RR{a}
+		}
+	}
+	return rr
+}
+
+// nonTerminal returns true if some record is owned by a name strictly below name. The lock must be held.
+func (ns *nameserver) nonTerminal(name string) bool {
+	for n := range ns.rr {
+		if n != name && dns.IsSubDomain(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func nsName(apex string) string {
+	return dns.Fqdn("ns." + strings.TrimPrefix(apex, "."))
+}
+
+// soa returns the SOA record of the zone. The lock must be held.
+func (ns *nameserver) soa(apex string) dns.RR {
+	for _, r := range ns.rr[apex] {
+		if r.Header().Rrtype == dns.TypeSOA {
+			return r
+		}
+	}
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: apex, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: DefaultTTL},
+		Ns:      nsName(apex),
+		Mbox:    dns.Fqdn("hostmaster." + strings.TrimPrefix(apex, ".")),
+		Serial:  ns.serial,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  DefaultTTL,
+	}
+}
+
+// self returns an address record for the nameserver, if it listens on a specific IP address.
+func (ns *nameserver) self() dns.RR {
+	var ip net.IP
+	switch a := ns.addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	}
+	if ip == nil || ip.IsUnspecified() {
+		return nil
+	}
+	name := nsName(ns.apex())
+	if ip4 := ip.To4(); ip4 != nil {
+		return &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: DefaultTTL}, A: ip4}
+	}
+	return &dns.AAAA{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: DefaultTTL}, AAAA: ip}
+}
+
+// glue returns the addresses, within our records, of the hosts named by NS, SRV and MX answers.
+// The lock must be held.
+func (ns *nameserver) glue(answer []dns.RR, apex string) (extra []dns.RR) {
+	for _, r := range answer {
+		var target string
+		switch t := r.(type) {
+		case *dns.NS:
+			target = t.Ns
+		case *dns.SRV:
+			target = t.Target
+		case *dns.MX:
+			target = t.Mx
+		default:
+			continue
+		}
+		target = strings.ToLower(target)
+		if !dns.IsSubDomain(apex, target) {
+			continue
+		}
+		for _, a := range ns.records(target, apex) {
+			if t := a.Header().Rrtype; t == dns.TypeA || t == dns.TypeAAAA {
+				extra = append(extra, a)
+			}
+		}
+	}
+	return extra
+}
+
+// rotate returns a copy of rr, rotated by one position on every call, if it holds address records.
+// The lock must be held.
+func (ns *nameserver) rotate(rr []dns.RR) []dns.RR {
+	r := make([]dns.RR, len(rr))
+	if t := rr[0].Header().Rrtype; len(rr) < 2 || (t != dns.TypeA && t != dns.TypeAAAA) {
+		copy(r, rr)
+		return r
+	}
+	k := int(ns.rot % uint32(len(rr)))
+	ns.rot++
+	copy(r, rr[k:])
+	copy(r[len(rr)-k:], rr[:k])
+	return r
+}
+
+func filter(rr []dns.RR, qtype uint16) (r []dns.RR) {
+	for _, x := range rr {
+		if qtype == dns.TypeANY || x.Header().Rrtype == qtype {
+			r = append(r, x)
+		}
+	}
+	return r
+}
+
+func findCNAME(rr []dns.RR) *dns.CNAME {
+	for _, x := range rr {
+		if c, ok := x.(*dns.CNAME); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+func hasType(rr []dns.RR, t uint16) bool {
+	for _, x := range rr {
+		if x.Header().Rrtype == t {
+			return true
+		}
+	}
+	return false
+}
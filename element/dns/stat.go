@@ -13,6 +13,12 @@ import (
 
 type Stat struct {
 	Address string `json:"addr"`
+	Zone string `json:"zone"`
+	Serial uint32 `json:"serial"`
+	Upstream []string `json:"upstream,omitempty"`
+	Forwarded int64 `json:"forwarded,omitempty"` // queries forwarded upstream
+	CacheHits int64 `json:"cachehits,omitempty"` // forwarded queries answered from the cache
+	Replicate string `json:"replicate,omitempty"` // group of replicas sharing the records
 	Records map[string][]string `json:"records"`
 }
 
@@ -8,12 +8,15 @@
 package dns
 
 import (
+	"encoding/gob"
+
 	"github.com/gocircuit/circuit/use/circuit"
 	"github.com/gocircuit/circuit/use/errors"
 )
 
 func init() {
 	circuit.RegisterValue(XNameserver{})
+	gob.Register(Spec{})
 }
 
 // X
@@ -26,6 +29,11 @@ func (x XNameserver) Set(rr string) error {
 	return errors.Pack(err)
 }
 
+func (x XNameserver) Load(origin string, rr []string) error {
+	err := x.Nameserver.Load(origin, rr)
+	return errors.Pack(err)
+}
+
 // Y
 type YNameserver struct {
 	X circuit.X
@@ -36,6 +44,11 @@ func (y YNameserver) Set(rr string) error {
 	return errors.Unpack(r[0])
 }
 
+func (y YNameserver) Load(origin string, rr []string) error {
+	r := y.X.Call("Load", origin, rr)
+	return errors.Unpack(r[0])
+}
+
 func (y YNameserver) Unset(name string) {
 	y.X.Call("Unset", name)
 }
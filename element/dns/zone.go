@@ -0,0 +1,106 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package dns
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gocircuit/circuit/github.com/miekg/dns"
+)
+
+// ParseZone parses an RFC 1035 master file, read from r, whose relative names are relative to origin.
+// The name file is used in error messages, and as the base for $INCLUDE directives.
+// ParseZone returns the apex of the zone, which is the owner of its SOA record if there is one,
+// then origin if not empty, and otherwise the longest domain common to all records.
+// Since loading a zone replaces all records below its apex, an apex at the root is an error,
+// unless origin is the root.
+// The parsed records are returned in textual form, as accepted by Load and Set.
+func ParseZone(r io.Reader, origin, file string) (apex string, rr []string, err error) {
+	var soa string
+	for t := range dns.ParseZone(r, origin, file) {
+		if t.Error != nil {
+			err = t.Error
+			continue // drain the parser
+		}
+		if err != nil {
+			continue
+		}
+		name := strings.ToLower(t.RR.Header().Name)
+		if t.RR.Header().Rrtype == dns.TypeSOA && soa == "" {
+			soa = name
+		}
+		if apex == "" {
+			apex = name
+		} else {
+			apex = commonDomain(apex, name)
+		}
+		rr = append(rr, t.RR.String())
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case soa != "":
+		apex = soa
+	case origin != "":
+		apex = strings.ToLower(dns.Fqdn(origin))
+	case apex == "":
+		apex = "."
+	}
+	if apex == "." && origin != "." {
+		return "", nil, errors.New("zone apex is the root; set the origin to . to load the root zone")
+	}
+	return apex, rr, nil
+}
+
+// DumpZone formats the records of a nameserver, as reported by Peek, as an RFC 1035 master file.
+// Records are ordered by name, starting from the apex of the zone.
+func DumpZone(stat Stat) string {
+	var names []string
+	for name := range stat.Records {
+		names = append(names, name)
+	}
+	sort.Sort(byDomain(names))
+	var w bytes.Buffer
+	fmt.Fprintf(&w, "; zone %s, serial %d\n", stat.Zone, stat.Serial)
+	for _, name := range names {
+		rr := append([]string(nil), stat.Records[name]...)
+		sort.Strings(rr)
+		for _, r := range rr {
+			fmt.Fprintln(&w, r)
+		}
+	}
+	return w.String()
+}
+
+// byDomain orders domain names by their labels, from the top-level domain down,
+// so that names precede the names below them.
+type byDomain []string
+
+func (s byDomain) Len() int {
+	return len(s)
+}
+
+func (s byDomain) Less(i, j int) bool {
+	a, b := dns.SplitDomainName(s[i]), dns.SplitDomainName(s[j])
+	for k := 1; k <= len(a) && k <= len(b); k++ {
+		if x, y := a[len(a)-k], b[len(b)-k]; x != y {
+			return x < y
+		}
+	}
+	return len(a) < len(b)
+}
+
+func (s byDomain) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
@@ -0,0 +1,456 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	ds "github.com/gocircuit/circuit/client/docker"
+	"github.com/gocircuit/circuit/element/proc"
+	"github.com/gocircuit/circuit/kit/interruptible"
+	"github.com/gocircuit/circuit/kit/lang"
+	"github.com/gocircuit/circuit/use/circuit"
+)
+
+// chroot is a container runtime built into the circuit. It runs the command of a container in new mount,
+// PID, UTS and IPC namespaces, chrooted into a root filesystem. Images are the directories of root
+// filesystems found in dir, which are staged by other means, e.g. by exporting docker images.
+//
+// The chroot runtime provides no security isolation: unless a user is given, commands run as root with
+// all capabilities, and a plain chroot does not confine such processes. Neither /proc nor /dev is mounted.
+type chroot struct {
+	dir string
+}
+
+func newChroot(dir string) (backend, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &chroot{dir: dir}, nil
+}
+
+func (r *chroot) version() (string, error) {
+	if fi, err := os.Stat(r.dir); err != nil || !fi.IsDir() {
+		return "", fmt.Errorf("root filesystem directory %s not found", r.dir)
+	}
+	if os.Geteuid() != 0 {
+		return "", errors.New("the chroot runtime requires superuser privileges")
+	}
+	return fmt.Sprintf("chroot runtime (root filesystems in %s)", r.dir), nil
+}
+
+// rootfs returns the root filesystem of the image.
+func (r *chroot) rootfs(image string) (string, error) {
+	name := path.Clean("/" + image)[1:]
+	if name == "" || name != image {
+		return "", fmt.Errorf("image name %q not valid", image)
+	}
+	root := filepath.Join(r.dir, filepath.FromSlash(name))
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		return "", fmt.Errorf("no such image: %s", image)
+	}
+	return root, nil
+}
+
+func (r *chroot) PullImage(image string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("the chroot runtime cannot pull images; stage root filesystems in %s", r.dir)
+}
+
+func (r *chroot) ListImages() ([]ds.Image, error) {
+	infos, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	var images []ds.Image
+	for _, fi := range infos {
+		if fi, err = os.Stat(filepath.Join(r.dir, fi.Name())); err != nil || !fi.IsDir() {
+			continue // root filesystems may be symbolic links to directories
+		}
+		images = append(images, ds.Image{
+			ID:       fi.Name(),
+			RepoTags: []string{fi.Name()},
+			Created:  fi.ModTime().Unix(),
+		})
+	}
+	return images, nil
+}
+
+func (r *chroot) RemoveImage(image string, force bool) error {
+	return fmt.Errorf("the chroot runtime does not remove images; remove root filesystems from %s", r.dir)
+}
+
+func (r *chroot) InspectImage(image string) (*ds.ImageStat, error) {
+	root, err := r.rootfs(image)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	return &ds.ImageStat{
+		ID:           image,
+		RepoTags:     []string{image},
+		Created:      fi.ModTime(),
+		Architecture: runtime.GOARCH,
+		Os:           "linux",
+	}, nil
+}
+
+// checkRun rejects the options of run which the chroot runtime does not implement.
+func checkRun(run ds.Run) error {
+	var unsupported []string
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{
+		{"Memory", run.Memory != 0},
+		{"CpuShares", run.CpuShares != 0},
+		{"Lxc", len(run.Lxc) > 0},
+		{"Volume", len(run.Volume) > 0},
+		{"Ports", len(run.Ports) > 0},
+		{"Hostname", run.Hostname != ""},
+		{"ReadOnly", run.ReadOnly},
+		{"CapDrop", len(run.CapDrop) > 0},
+		{"Health", run.Health != nil},
+		{"Restart", run.Restart != "" && run.Restart != "no"},
+	} {
+		if o.set {
+			unsupported = append(unsupported, o.name)
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("options not supported by the chroot runtime: %s", strings.Join(unsupported, ", "))
+	}
+	switch run.Network {
+	case "", "host", "none":
+	default:
+		return fmt.Errorf("network %q not supported by the chroot runtime; use host or none", run.Network)
+	}
+	return nil
+}
+
+// lookPath resolves the command file within the root filesystem root, searching the directories
+// of the PATH variable in env unless file contains a slash.
+func lookPath(root, file string, env []string) (string, error) {
+	if strings.Contains(file, "/") {
+		return file, nil
+	}
+	search := "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "PATH=") {
+			search = kv[len("PATH="):]
+		}
+	}
+	for _, dir := range filepath.SplitList(search) {
+		p := path.Join("/", dir, file)
+		if fi, err := os.Stat(filepath.Join(root, p)); err == nil && fi.Mode().IsRegular() && fi.Mode()&0111 != 0 {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("executable %s not found in the image", file)
+}
+
+// lookUser resolves a user of the form user[:group], given by name or number, against the
+// /etc/passwd and /etc/group files of the root filesystem root.
+func lookUser(root, spec string) (*syscall.Credential, error) {
+	user, group := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		user, group = spec[:i], spec[i+1:]
+	}
+	cred := &syscall.Credential{}
+	uid, gid, err := lookID(filepath.Join(root, "etc", "passwd"), user)
+	if err != nil {
+		return nil, err
+	}
+	cred.Uid, cred.Gid = uid, gid
+	if group != "" {
+		if cred.Gid, _, err = lookID(filepath.Join(root, "etc", "group"), group); err != nil {
+			return nil, err
+		}
+	}
+	return cred, nil
+}
+
+// lookID returns the numeric ID, and the third field, of the entry called name in a file formatted as
+// /etc/passwd or /etc/group. Numeric names are returned as is.
+func lookID(file, name string) (id, aux uint32, err error) {
+	if n, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(n), uint32(n), nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot resolve %s: %v", name, err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Split(s.Text(), ":")
+		if len(fields) < 4 || fields[0] != name {
+			continue
+		}
+		i, err1 := strconv.ParseUint(fields[2], 10, 32)
+		j, err2 := strconv.ParseUint(fields[3], 10, 32)
+		if err1 != nil || err2 != nil {
+			break
+		}
+		return uint32(i), uint32(j), nil
+	}
+	return 0, 0, fmt.Errorf("%s not found in %s", name, filepath.Base(file))
+}
+
+// chrootContainer is a container run by the chroot runtime.
+type chrootContainer struct {
+	id      string
+	name    string
+	image   string
+	created time.Time
+	cfg     ds.Config
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  io.ReadCloser
+	stderr  io.ReadCloser
+	exit    chan struct{}
+	sync.Mutex
+	state    *os.ProcessState // set once the command has exited
+	finished time.Time
+	waitErr  error
+}
+
+// Make starts the command of run in new namespaces, chrooted into the root filesystem of its image.
+// The container shares the network of the host, unless its network is none.
+func (r *chroot) Make(run ds.Run) (_ Container, err error) {
+	if err = checkRun(run); err != nil {
+		return nil, err
+	}
+	root, err := r.rootfs(run.Image)
+	if err != nil {
+		return nil, err
+	}
+	argv := run.Args
+	if run.Path != "" {
+		argv = append([]string{run.Path}, argv...)
+	}
+	if run.Entry != "" {
+		argv = append([]string{run.Entry}, argv...)
+	}
+	if len(argv) == 0 {
+		return nil, errors.New("container needs a command")
+	}
+	env := run.Env
+	if env == nil {
+		env = []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"}
+	}
+	bin, err := lookPath(root, argv[0], env)
+	if err != nil {
+		return nil, err
+	}
+	dir := run.Dir
+	if dir == "" {
+		dir = "/"
+	}
+	attr := &syscall.SysProcAttr{
+		Chroot:     root,
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWUTS | syscall.CLONE_NEWIPC,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+	if run.Network == "none" {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	if run.User != "" {
+		if attr.Credential, err = lookUser(root, run.User); err != nil {
+			return nil, err
+		}
+	}
+	id := lang.ChooseReceiverID().String()[1:]
+	con := &chrootContainer{
+		id:      id,
+		name:    "via-circuit-" + id,
+		image:   run.Image,
+		created: time.Now(),
+		cfg: ds.Config{
+			User:       run.User,
+			Env:        env,
+			Cmd:        argv,
+			Image:      run.Image,
+			WorkingDir: dir,
+			Labels:     run.Labels,
+		},
+		cmd: &exec.Cmd{
+			Path:        bin,
+			Args:        argv,
+			Env:         env,
+			Dir:         dir,
+			SysProcAttr: attr,
+		},
+		exit: make(chan struct{}),
+	}
+	var stdout, stderr io.WriteCloser
+	con.cmd.Stdin, con.stdin = interruptible.BufferPipe(StdBufferLen)
+	con.stdout, stdout = interruptible.BufferPipe(StdBufferLen)
+	con.stderr, stderr = interruptible.BufferPipe(StdBufferLen)
+	con.cmd.Stdout, con.cmd.Stderr = stdout, stderr
+	if err = con.cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		err := con.cmd.Wait()
+		stdout.Close()
+		stderr.Close()
+		con.Lock()
+		con.state = con.cmd.ProcessState
+		con.finished = time.Now()
+		if _, ok := err.(*exec.ExitError); !ok {
+			con.waitErr = err
+		}
+		con.Unlock()
+		close(con.exit)
+	}()
+	return con, nil
+}
+
+func (con *chrootContainer) Peek() (*ds.Stat, error) {
+	con.Lock()
+	defer con.Unlock()
+	stat := &ds.Stat{
+		ID:      con.id,
+		Created: con.created,
+		Path:    con.cfg.Cmd[0],
+		Args:    con.cfg.Cmd[1:],
+		Config:  con.cfg,
+		Image:   con.image,
+		Name:    "/" + con.name,
+		Driver:  "chroot",
+		State: ds.State{
+			Running:    con.state == nil,
+			Pid:        con.cmd.Process.Pid,
+			StartedAt:  con.created,
+			FinishedAt: con.finished,
+		},
+	}
+	if ps := con.state; ps != nil {
+		ws := ps.Sys().(syscall.WaitStatus)
+		stat.State.ExitCode = ws.ExitStatus()
+		if ws.Signaled() {
+			stat.State.ExitCode = 128 + int(ws.Signal()) // as reported by docker
+		}
+	}
+	stat.Config.Cmd = append([]string(nil), con.cfg.Cmd...)
+	return stat, nil
+}
+
+func (con *chrootContainer) Wait() (*ds.Stat, error) {
+	<-con.exit
+	con.Lock()
+	err := con.waitErr
+	con.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return con.Peek()
+}
+
+func (con *chrootContainer) Signal(sig string) error {
+	signo, ok := proc.ParseSignal(sig)
+	if !ok {
+		return errors.New("signal name not recognized")
+	}
+	if con.IsDone() {
+		return errors.New("container not running")
+	}
+	return con.cmd.Process.Signal(signo)
+}
+
+// Scrub kills the container, if running. The other processes of the container
+// are killed by the kernel along with its first process.
+func (con *chrootContainer) Scrub() {
+	if !con.IsDone() {
+		con.cmd.Process.Kill()
+	}
+}
+
+func (con *chrootContainer) IsDone() bool {
+	select {
+	case <-con.exit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (con *chrootContainer) Stdin() io.WriteCloser {
+	return con.stdin
+}
+
+func (con *chrootContainer) Stdout() io.ReadCloser {
+	return con.stdout
+}
+
+func (con *chrootContainer) Stderr() io.ReadCloser {
+	return con.stderr
+}
+
+func (con *chrootContainer) Exec(cmd ds.Exec) (Process, error) {
+	return nil, errors.New("exec not supported by the chroot runtime")
+}
+
+// Stats samples the resource usage of the first process of the container.
+func (con *chrootContainer) Stats() (*ds.Stats, error) {
+	if con.IsDone() {
+		return nil, errors.New("container not running")
+	}
+	pid := strconv.Itoa(con.cmd.Process.Pid)
+	buf, err := ioutil.ReadFile(filepath.Join("/proc", pid, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	// The fields following the command name, which is in parentheses, begin with the state.
+	s := string(buf)
+	fields := strings.Fields(s[strings.LastIndex(s, ")")+1:])
+	if len(fields) < 22 {
+		return nil, errors.New("process statistics not recognized")
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	rss, _ := strconv.ParseUint(fields[21], 10, 64)
+	const tick = uint64(time.Second / 100) // USER_HZ
+	return &ds.Stats{
+		Read:        time.Now(),
+		CPUTotal:    (utime + stime) * tick,
+		MemoryUsage: rss * uint64(os.Getpagesize()),
+		Pids:        1,
+	}, nil
+}
+
+// watch reports the exit of the container as a die event.
+func (con *chrootContainer) watch(f func(action string)) {
+	exit := con.exit // the watch must not keep the container from being collected
+	go func() {
+		<-exit
+		f("die")
+	}()
+}
+
+func (con *chrootContainer) X() circuit.X {
+	return circuit.Ref(XContainer{con})
+}
@@ -0,0 +1,75 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ds "github.com/gocircuit/circuit/client/docker"
+)
+
+func TestChroot(t *testing.T) {
+	dir := t.TempDir()
+	// The root filesystem of the host serves as an image.
+	if err := os.Symlink("/", filepath.Join(dir, "host")); err != nil {
+		t.Fatalf("symlink (%s)", err)
+	}
+	os.Mkdir(filepath.Join(dir, "empty"), 0755)
+	r, err := newChroot(dir)
+	if err != nil {
+		t.Fatalf("chroot (%s)", err)
+	}
+	images, err := r.ListImages()
+	if err != nil || len(images) != 2 || images[0].ID != "empty" || images[1].ID != "host" {
+		t.Fatalf("list %v (%v)", images, err)
+	}
+	if stat, err := r.InspectImage("host"); err != nil || stat.Os != "linux" {
+		t.Fatalf("inspect %v (%v)", stat, err)
+	}
+	for _, bad := range []ds.Run{
+		{Image: "../etc", Path: "/bin/sh"},
+		{Image: "missing", Path: "/bin/sh"},
+		{Image: "host"},
+		{Image: "host", Path: "/bin/sh", Ports: []string{"80"}, Memory: 1 << 20},
+		{Image: "host", Path: "/bin/sh", Network: "bridge"},
+		{Image: "empty", Path: "sh"},
+	} {
+		if _, err := r.Make(bad); err == nil {
+			t.Errorf("expecting error for %v", bad)
+		}
+	}
+	if p, err := lookPath(filepath.Join(dir, "host"), "sh", []string{"PATH=/nowhere:/bin"}); err != nil || p != "/bin/sh" {
+		t.Errorf("look path %q (%v)", p, err)
+	}
+
+	if os.Geteuid() != 0 {
+		t.Skip("running containers requires superuser privileges")
+	}
+	con, err := r.Make(ds.Run{Image: "host", Path: "sh", Args: []string{"-c", "echo $$; cat; exit 5"}, Network: "none"})
+	if err != nil {
+		t.Skipf("namespaces not available (%s)", err)
+	}
+	io.WriteString(con.Stdin(), "hello\n")
+	con.Stdin().Close()
+	out, _ := ioutil.ReadAll(con.Stdout())
+	if string(out) != "1\nhello\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	stat, err := con.Wait()
+	if err != nil || stat.State.ExitCode != 5 || stat.State.Running || !con.IsDone() {
+		t.Fatalf("wait %v (%v)", stat, err)
+	}
+	if _, err = con.Stats(); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Fatalf("expecting stats error, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"errors"
+)
+
+func newChroot(dir string) (backend, error) {
+	return nil, errors.New("the chroot runtime is only available on linux")
+}
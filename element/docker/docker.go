@@ -9,15 +9,20 @@ package docker
 
 import (
 	"errors"
+	"fmt"
 	"io"
-	"os/exec"
+	"net/url"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	ds "github.com/gocircuit/circuit/client/docker"
 	"github.com/gocircuit/circuit/element/proc"
 	"github.com/gocircuit/circuit/kit/interruptible"
 	"github.com/gocircuit/circuit/kit/lang"
 	"github.com/gocircuit/circuit/use/circuit"
-	ds "github.com/gocircuit/circuit/client/docker"
 )
 
 type Container interface {
@@ -29,51 +34,216 @@ type Container interface {
 	Stdin() io.WriteCloser
 	Stdout() io.ReadCloser
 	Stderr() io.ReadCloser
+	Exec(cmd ds.Exec) (Process, error)
+	Stats() (*ds.Stats, error)
 	X() circuit.X
 }
 
 type container struct {
-	name string
-	cmd *exec.Cmd
+	e      *engine
+	name   string
+	id     string
 	stdin  io.WriteCloser
 	stdout io.ReadCloser
 	stderr io.ReadCloser
-	exit <-chan error
+	exit   <-chan struct{}
+	sync.Mutex
+	exitCode int
+	waitErr  error
+}
+
+// config is the configuration of a container, as accepted by the Docker Engine API.
+type config struct {
+	Image        string
+	Cmd          []string            `json:",omitempty"`
+	Entrypoint   []string            `json:",omitempty"`
+	Env          []string            `json:",omitempty"`
+	WorkingDir   string              `json:",omitempty"`
+	Volumes      map[string]struct{} `json:",omitempty"`
+	Hostname     string              `json:",omitempty"`
+	User         string              `json:",omitempty"`
+	Labels       map[string]string   `json:",omitempty"`
+	ExposedPorts map[string]struct{} `json:",omitempty"`
+	Healthcheck  *ds.HealthConfig    `json:",omitempty"`
+	AttachStdin  bool
+	AttachStdout bool
+	AttachStderr bool
+	OpenStdin    bool
+	StdinOnce    bool
+	HostConfig   hostConfig
+}
+
+type hostConfig struct {
+	Memory         int64                       `json:",omitempty"`
+	CpuShares      int64                       `json:",omitempty"`
+	Binds          []string                    `json:",omitempty"`
+	PortBindings   map[string][]ds.PortBinding `json:",omitempty"`
+	NetworkMode    string                      `json:",omitempty"`
+	ReadonlyRootfs bool                        `json:",omitempty"`
+	CapDrop        []string                    `json:",omitempty"`
+	RestartPolicy  ds.RestartPolicy            `json:",omitempty"`
+}
+
+// makeConfig translates a run description into an engine configuration. Volumes of the
+// form host:container[:options] are bind-mounted, while others are created by the engine.
+func makeConfig(run ds.Run) (*config, error) {
+	if len(run.Lxc) > 0 {
+		return nil, errors.New("lxc options are not supported by the docker engine")
+	}
+	cfg := &config{
+		Image:        run.Image,
+		Env:          run.Env,
+		WorkingDir:   run.Dir,
+		Hostname:     run.Hostname,
+		User:         run.User,
+		Labels:       run.Labels,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		OpenStdin:    true,
+		StdinOnce:    true,
+		HostConfig: hostConfig{
+			Memory:         run.Memory,
+			CpuShares:      run.CpuShares,
+			NetworkMode:    run.Network,
+			ReadonlyRootfs: run.ReadOnly,
+			CapDrop:        run.CapDrop,
+		},
+	}
+	for _, p := range run.Ports {
+		port, binding, err := parsePort(p)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.ExposedPorts == nil {
+			cfg.ExposedPorts = make(map[string]struct{})
+			cfg.HostConfig.PortBindings = make(map[string][]ds.PortBinding)
+		}
+		cfg.ExposedPorts[port] = struct{}{}
+		if binding != nil {
+			cfg.HostConfig.PortBindings[port] = append(cfg.HostConfig.PortBindings[port], *binding)
+		}
+	}
+	var err error
+	if cfg.Healthcheck, err = healthConfig(run.Health); err != nil {
+		return nil, err
+	}
+	if cfg.HostConfig.RestartPolicy, err = restartPolicy(run.Restart); err != nil {
+		return nil, err
+	}
+	if run.Path != "" {
+		cfg.Cmd = append([]string{run.Path}, run.Args...)
+	} else {
+		cfg.Cmd = run.Args
+	}
+	if run.Entry != "" {
+		cfg.Entrypoint = []string{run.Entry}
+	}
+	for _, v := range run.Volume {
+		if strings.Contains(v, ":") {
+			cfg.HostConfig.Binds = append(cfg.HostConfig.Binds, v)
+			continue
+		}
+		if cfg.Volumes == nil {
+			cfg.Volumes = make(map[string]struct{})
+		}
+		cfg.Volumes[v] = struct{}{}
+	}
+	return cfg, nil
 }
 
-func MakeContainer(run ds.Run) (_ Container, err error) {
-	if dkr == "" {
-		return nil, errors.New("docker not enabled on this server")
+// Make creates and starts a container, as described by run, through the engine.
+// The standard streams of the container are attached before it starts, so no output is lost.
+func (e *engine) Make(run ds.Run) (_ Container, err error) {
+	cfg, err := makeConfig(run)
+	if err != nil {
+		return nil, err
+	}
+	if h := cfg.Healthcheck; h != nil && h.StartPeriod != 0 && apiOlder(e.api, healthStartAPIVersion) {
+		return nil, fmt.Errorf("health check start period needs API version %s, but %s speaks %s", healthStartAPIVersion, e.name, e.api)
 	}
-	ch := make(chan error, 1)
 	con := &container{
-		name: "via-circuit-"+lang.ChooseReceiverID().String()[1:],
-		exit: ch,
-	}
-	con.cmd = exec.Command(dkr, run.Arg(con.name)...)
-	con.cmd.Stdin, con.stdin = interruptible.BufferPipe(StdBufferLen)
-	con.stdout, con.cmd.Stdout = interruptible.BufferPipe(StdBufferLen)
-	con.stderr, con.cmd.Stderr = interruptible.BufferPipe(StdBufferLen)
-	if err = con.cmd.Start(); err != nil {
+		e:    e,
+		name: "via-circuit-" + lang.ChooseReceiverID().String()[1:],
+	}
+	var created struct {
+		Id string
+	}
+	if err = e.call("POST", "/containers/create", url.Values{"name": {con.name}}, cfg, &created); err != nil {
 		return nil, err
 	}
+	con.id = created.Id
+	conn, err := e.attach(con.id)
+	if err != nil {
+		con.Scrub()
+		return nil, err
+	}
+	if err = e.call("POST", "/containers/"+con.id+"/start", nil, nil, nil); err != nil {
+		conn.Close()
+		con.Scrub()
+		return nil, err
+	}
+	var stdin io.Reader
+	var stdout, stderr io.WriteCloser
+	stdin, con.stdin = interruptible.BufferPipe(StdBufferLen)
+	con.stdout, stdout = interruptible.BufferPipe(StdBufferLen)
+	con.stderr, stderr = interruptible.BufferPipe(StdBufferLen)
+	go func() {
+		io.Copy(conn, stdin)
+		conn.CloseWrite()
+	}()
+	ch := make(chan struct{})
+	con.exit = ch
+	go func() {
+		demux(conn, stdout, stderr)
+		stdout.Close()
+		stderr.Close()
+	}()
 	go func() {
-		ch <- con.cmd.Wait()
-		close(ch)
-		con.cmd.Stdout.(io.Closer).Close()
-		con.cmd.Stderr.(io.Closer).Close()
+		defer close(ch)
+		defer conn.Close()
+		var result struct {
+			StatusCode int
+		}
+		var err error
+		for {
+			err = e.call("POST", "/containers/"+con.id+"/wait", nil, nil, &result)
+			if err != nil || !con.restarting() {
+				break
+			}
+		}
+		con.Lock()
+		defer con.Unlock()
+		con.exitCode, con.waitErr = result.StatusCode, err
 	}()
 	runtime.SetFinalizer(con,
 		func(c *container) {
-			exec.Command(dkr, "rm", c.name).Run()
+			c.Scrub()
 		},
 	)
 	return con, nil
 }
 
+// restarting returns true if the engine is restarting the container, or has restarted it, after an exit.
+// Restarted containers keep running detached from the standard streams of the element.
+func (con *container) restarting() bool {
+	stat, err := con.Peek()
+	return err == nil && (stat.State.Restarting || stat.State.Running)
+}
+
 func (con *container) Wait() (_ *ds.Stat, err error) {
 	<-con.exit
-	return con.Peek()
+	stat, err := con.Peek()
+	if err != nil {
+		return nil, err
+	}
+	con.Lock()
+	defer con.Unlock()
+	if con.waitErr != nil {
+		return nil, con.waitErr
+	}
+	stat.State.ExitCode = con.exitCode // as reported by the engine upon exit
+	return stat, nil
 }
 
 func (con *container) Stdin() io.WriteCloser {
@@ -88,19 +258,17 @@ func (con *container) Stderr() io.ReadCloser {
 	return con.stderr
 }
 
-func (con *container) Peek() (stat *ds.Stat, err error) {
-	buf, err := exec.Command(dkr, "inspect", con.name).Output()
-	if err != nil {
+func (con *container) Peek() (*ds.Stat, error) {
+	stat := &ds.Stat{}
+	if err := con.e.call("GET", "/containers/"+con.id+"/json", nil, nil, stat); err != nil {
 		return nil, err
 	}
-	if stat, err = ds.ParseStatInArray(buf); err != nil {
-		return nil, err
-	}
-	return
+	return stat, nil
 }
 
+// Scrub removes the container, killing it if running.
 func (con *container) Scrub() {
-	exec.Command(dkr, "rm", con.name).Run()
+	con.e.call("DELETE", "/containers/"+con.id, url.Values{"force": {"1"}, "v": {"1"}}, nil, nil)
 }
 
 func (con *container) Signal(sig string) error {
@@ -108,10 +276,7 @@ func (con *container) Signal(sig string) error {
 	if !ok {
 		return errors.New("signal name not recognized")
 	}
-	if con.cmd.Process == nil {
-		return errors.New("no process")
-	}
-	return con.cmd.Process.Signal(signo)
+	return con.e.call("POST", "/containers/"+con.id+"/kill", url.Values{"signal": {strconv.Itoa(int(signo))}}, nil, nil)
 }
 
 func (con *container) IsDone() bool {
@@ -126,3 +291,99 @@ func (con *container) IsDone() bool {
 func (con *container) X() circuit.X {
 	return circuit.Ref(XContainer{con})
 }
+
+// watch calls f with the action of every event that the engine reports for the container,
+// like die, oom or kill, until the container is removed.
+func (con *container) watch(f func(action string)) {
+	e, id := con.e, con.id // the watch must not keep the container from being collected
+	go e.events(id, func(action string) bool {
+		f(action)
+		return action != "destroy"
+	})
+}
+
+// parsePort parses a published port of the form [[host-ip:]host-port:]container-port[/protocol].
+// It returns the container port with its protocol, e.g. 80/tcp, and its binding on the host, if any.
+// An empty host port is chosen by the engine.
+func parsePort(spec string) (port string, binding *ds.PortBinding, err error) {
+	proto := "tcp"
+	if i := strings.LastIndex(spec, "/"); i >= 0 {
+		spec, proto = spec[:i], strings.ToLower(spec[i+1:])
+	}
+	if proto != "tcp" && proto != "udp" && proto != "sctp" {
+		return "", nil, fmt.Errorf("port protocol %q not recognized", proto)
+	}
+	container := spec
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		container, spec = spec[i+1:], spec[:i]
+		binding = &ds.PortBinding{HostPort: spec}
+		if j := strings.LastIndex(spec, ":"); j >= 0 {
+			binding.HostIp = strings.Trim(spec[:j], "[]")
+			binding.HostPort = spec[j+1:]
+		}
+		if binding.HostPort != "" && !validPort(binding.HostPort) {
+			return "", nil, fmt.Errorf("host port %q not valid", binding.HostPort)
+		}
+	}
+	if !validPort(container) {
+		return "", nil, fmt.Errorf("container port %q not valid", container)
+	}
+	return container + "/" + proto, binding, nil
+}
+
+func validPort(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n > 0 && n < 65536
+}
+
+// healthConfig translates a health check description into the engine's form, with durations in nanoseconds.
+func healthConfig(h *ds.Health) (*ds.HealthConfig, error) {
+	if h == nil {
+		return nil, nil
+	}
+	if h.Cmd == "" {
+		return nil, errors.New("health check needs a command")
+	}
+	cfg := &ds.HealthConfig{
+		Test:    []string{"CMD-SHELL", h.Cmd},
+		Retries: h.Retries,
+	}
+	for _, d := range []struct {
+		s string
+		n *int64
+	}{{h.Interval, &cfg.Interval}, {h.Timeout, &cfg.Timeout}, {h.StartPeriod, &cfg.StartPeriod}} {
+		if d.s == "" {
+			continue
+		}
+		t, err := time.ParseDuration(d.s)
+		if err != nil {
+			return nil, fmt.Errorf("health check duration %q not valid", d.s)
+		}
+		*d.n = int64(t)
+	}
+	return cfg, nil
+}
+
+// restartPolicy parses a restart policy of the form no, always, unless-stopped, on-failure or on-failure:max-retries.
+func restartPolicy(s string) (p ds.RestartPolicy, err error) {
+	name, max := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		name, max = s[:i], s[i+1:]
+	}
+	switch name {
+	case "", "no", "always", "unless-stopped":
+		if max != "" {
+			return p, errors.New("only the on-failure restart policy takes a retry count")
+		}
+	case "on-failure":
+		if max != "" {
+			if p.MaximumRetryCount, err = strconv.Atoi(max); err != nil || p.MaximumRetryCount < 0 {
+				return p, fmt.Errorf("restart retry count %q not valid", max)
+			}
+		}
+	default:
+		return p, fmt.Errorf("restart policy %q not recognized", s)
+	}
+	p.Name = name
+	return p, nil
+}
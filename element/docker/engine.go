@@ -0,0 +1,333 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultHost is the address of the Docker Engine API, unless DOCKER_HOST is set.
+	DefaultHost = "unix:///var/run/docker.sock"
+	// DefaultPodmanHost is the address of the Docker-compatible API of a system-wide Podman service.
+	DefaultPodmanHost = "unix:///run/podman/podman.sock"
+	// MaxAPIVersion is the newest version of the Docker Engine API used.
+	// Engines that support a newer version are spoken to in this one.
+	MaxAPIVersion = "1.41"
+	// MinAPIVersion is the oldest version of the Docker Engine API supported.
+	MinAPIVersion = "1.24"
+	// healthStartAPIVersion is the oldest API version supporting the start period of health checks.
+	healthStartAPIVersion = "1.29"
+)
+
+// engine is a client of the Docker Engine HTTP API, or of a compatible API like Podman's.
+// It is the container runtime of the docker and podman runtimes.
+type engine struct {
+	name    string // name of the engine in descriptions
+	api     string // version of the API spoken, negotiated with the engine
+	network string
+	addr    string
+	client  *http.Client
+}
+
+// newEngine returns a client for the Docker Engine API at host, given as unix:///path or tcp://host:port.
+func newEngine(name, host string) (*engine, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+	e := &engine{name: name, network: u.Scheme}
+	switch u.Scheme {
+	case "unix":
+		e.addr = u.Path
+	case "tcp":
+		e.addr = u.Host
+	default:
+		return nil, fmt.Errorf("docker host %s not supported", host)
+	}
+	e.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return e.dial(ctx)
+			},
+		},
+	}
+	return e, nil
+}
+
+func (e *engine) dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, e.network, e.addr)
+}
+
+// engineError is an error reported by the Docker engine.
+type engineError struct {
+	Status  int
+	Message string
+}
+
+func (e *engineError) Error() string {
+	return fmt.Sprintf("docker engine: %s (status %d)", e.Message, e.Status)
+}
+
+// readError returns the error described by an unsuccessful engine response.
+func readError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	var msg struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &msg) != nil || msg.Message == "" {
+		msg.Message = strings.TrimSpace(string(body))
+	}
+	return &engineError{Status: resp.StatusCode, Message: msg.Message}
+}
+
+// do performs a request with an optional JSON body. A response with a 2xx status is returned with its body open.
+func (e *engine) do(method, path string, query url.Values, in interface{}) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest(method, e.url(path, query), body)
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, readError(resp)
+	}
+	return resp, nil
+}
+
+// call performs a request and decodes its JSON response into out, unless out is nil.
+func (e *engine) call(method, path string, query url.Values, in, out interface{}) error {
+	resp, err := e.do(method, path, query, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (e *engine) url(path string, query url.Values) string {
+	u := url.URL{Scheme: "http", Host: "docker", Path: path}
+	if e.api != "" {
+		u.Path = "/v" + e.api + path
+	}
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// version returns the version of the engine, and negotiates the version of the API
+// spoken with it, like the docker command does: the older of the engine's version and MaxAPIVersion is used.
+func (e *engine) version() (string, error) {
+	var v struct {
+		Version       string
+		ApiVersion    string
+		MinAPIVersion string
+	}
+	if err := e.call("GET", "/version", nil, nil, &v); err != nil {
+		return "", err
+	}
+	api, err := negotiateAPI(v.ApiVersion, v.MinAPIVersion)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %s", e.name, v.Version, err)
+	}
+	e.api = api
+	return fmt.Sprintf("%s %s (API %s, using %s)", e.name, v.Version, v.ApiVersion, api), nil
+}
+
+// negotiateAPI returns the API version to speak with an engine whose newest and oldest
+// supported API versions are max and min. An engine that does not report min supports all older versions.
+func negotiateAPI(max, min string) (string, error) {
+	if _, _, err := parseAPIVersion(max); err != nil {
+		return "", err
+	}
+	api := max
+	if apiOlder(MaxAPIVersion, api) {
+		api = MaxAPIVersion
+	}
+	if apiOlder(api, MinAPIVersion) {
+		return "", fmt.Errorf("API version %s is too old; version %s or newer is required", max, MinAPIVersion)
+	}
+	if _, _, err := parseAPIVersion(min); err == nil && apiOlder(api, min) {
+		return "", fmt.Errorf("API version %s or newer is required, but %s is the newest supported", min, MaxAPIVersion)
+	}
+	return api, nil
+}
+
+// parseAPIVersion parses an API version of the form major.minor.
+func parseAPIVersion(v string) (major, minor int, err error) {
+	i := strings.Index(v, ".")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("API version %q not valid", v)
+	}
+	if major, err = strconv.Atoi(v[:i]); err != nil {
+		return 0, 0, fmt.Errorf("API version %q not valid", v)
+	}
+	if minor, err = strconv.Atoi(v[i+1:]); err != nil {
+		return 0, 0, fmt.Errorf("API version %q not valid", v)
+	}
+	return major, minor, nil
+}
+
+// apiOlder returns true if the API version a is older than b. Invalid versions are oldest.
+func apiOlder(a, b string) bool {
+	amaj, amin, _ := parseAPIVersion(a)
+	bmaj, bmin, _ := parseAPIVersion(b)
+	return amaj < bmaj || (amaj == bmaj && amin < bmin)
+}
+
+// attach attaches to the standard streams of a container, and returns the hijacked connection.
+// Writes go to the container's standard input, and reads return its multiplexed output.
+func (e *engine) attach(id string) (*hijacked, error) {
+	q := url.Values{"stream": {"1"}, "stdin": {"1"}, "stdout": {"1"}, "stderr": {"1"}}
+	return e.hijack("/containers/"+id+"/attach", q, nil)
+}
+
+// hijack performs a request with an optional JSON body, whose connection the engine takes over
+// for the standard streams of a container or an exec instance.
+func (e *engine) hijack(path string, query url.Values, in interface{}) (*hijacked, error) {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(buf)
+	}
+	conn, err := e.dial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", e.url(path, query), body)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	if err = req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		defer conn.Close()
+		return nil, readError(resp)
+	}
+	return &hijacked{Conn: conn, r: r}, nil
+}
+
+// hijacked is a connection taken over from HTTP, whose buffered input is read first.
+type hijacked struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (h *hijacked) Read(p []byte) (int, error) {
+	return h.r.Read(p)
+}
+
+// CloseWrite signals the end of the standard input to the container.
+func (h *hijacked) CloseWrite() error {
+	if cw, ok := h.Conn.(interface {
+		CloseWrite() error
+	}); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// demux copies the multiplexed output of a container, read from r, to stdout and stderr.
+// Each frame begins with an 8-byte header, holding the stream in its first byte and
+// the length of the payload in its last four, in big-endian order.
+func demux(r io.Reader, stdout, stderr io.Writer) error {
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		w := stdout
+		if hdr[0] == 2 {
+			w = stderr
+		}
+		if _, err := io.CopyN(w, r, int64(binary.BigEndian.Uint32(hdr[4:]))); err != nil {
+			return err
+		}
+	}
+}
+
+// event is a container event reported by the engine.
+type event struct {
+	Status string `json:"status"`
+	ID     string `json:"id"`
+}
+
+// events streams the events of the container id to f, until the stream ends or f returns false.
+func (e *engine) events(id string, f func(action string) bool) error {
+	filters, _ := json.Marshal(map[string][]string{"container": {id}})
+	resp, err := e.do("GET", "/events", url.Values{"filters": {string(filters)}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev event
+		if err := dec.Decode(&ev); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if !f(ev.Status) {
+			return nil
+		}
+	}
+}
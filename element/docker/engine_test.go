@@ -0,0 +1,388 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ds "github.com/gocircuit/circuit/client/docker"
+)
+
+// fakeEngine serves the subset of the Docker Engine API used by docker elements, for a single container
+// that echoes its standard input to its standard output and exits with code 3 when its input ends.
+type fakeEngine struct {
+	sync.Mutex
+	config  config
+	exec    execConfig
+	signals []string
+	removed bool
+	exited  chan struct{}
+	api     string // API version of the last versioned request
+}
+
+func frame(stream byte, p []byte) []byte {
+	hdr := make([]byte, 8, 8+len(p))
+	hdr[0] = stream
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(p)))
+	return append(hdr, p...)
+}
+
+func (f *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	p := r.URL.Path
+	if strings.HasPrefix(p, "/v1.") {
+		i := strings.Index(p[1:], "/") + 1
+		f.Lock()
+		f.api, p = p[2:i], p[i:]
+		f.Unlock()
+	}
+	switch {
+	case p == "/version":
+		reply(map[string]string{"Version": "fake", "ApiVersion": "1.43", "MinAPIVersion": "1.12"})
+	case p == "/containers/create":
+		f.Lock()
+		defer f.Unlock()
+		json.NewDecoder(r.Body).Decode(&f.config)
+		if f.config.Image == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			reply(map[string]string{"message": "No such image: missing"})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		reply(map[string]string{"Id": "c1"})
+	case p == "/containers/c1/attach":
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		rw.WriteString("HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		rw.Flush()
+		go func() {
+			defer conn.Close()
+			defer close(f.exited)
+			in, _ := ioutil.ReadAll(rw)
+			conn.Write(frame(1, in))
+			conn.Write(frame(2, []byte("bye\n")))
+		}()
+	case p == "/containers/c1/exec":
+		f.Lock()
+		defer f.Unlock()
+		json.NewDecoder(r.Body).Decode(&f.exec)
+		w.WriteHeader(http.StatusCreated)
+		reply(map[string]string{"Id": "e1"})
+	case p == "/exec/e1/start":
+		ioutil.ReadAll(r.Body)
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		rw.WriteString("HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		rw.Flush()
+		go func() {
+			defer conn.Close()
+			in, _ := ioutil.ReadAll(rw)
+			conn.Write(frame(1, []byte(strings.ToUpper(string(in)))))
+		}()
+	case p == "/exec/e1/json":
+		reply(ds.ExecStat{ID: "e1", ExitCode: 7, Pid: 42})
+	case p == "/containers/c1/stats":
+		reply(map[string]interface{}{
+			"cpu_stats": map[string]interface{}{
+				"cpu_usage":        map[string]interface{}{"total_usage": 300, "percpu_usage": []int{150, 150}},
+				"system_cpu_usage": 2000,
+			},
+			"precpu_stats": map[string]interface{}{
+				"cpu_usage":        map[string]interface{}{"total_usage": 100},
+				"system_cpu_usage": 1000,
+			},
+			"memory_stats": map[string]interface{}{"usage": 1 << 20, "limit": 1 << 30},
+			"networks": map[string]interface{}{
+				"eth0": map[string]interface{}{"rx_bytes": 10, "tx_bytes": 20},
+				"eth1": map[string]interface{}{"rx_bytes": 1, "tx_bytes": 2},
+			},
+			"blkio_stats": map[string]interface{}{
+				"io_service_bytes_recursive": []map[string]interface{}{
+					{"op": "Read", "value": 100}, {"op": "Write", "value": 200}, {"op": "Total", "value": 300},
+				},
+			},
+		})
+	case p == "/images/create":
+		switch q := r.URL.Query(); q.Get("fromImage") + ":" + q.Get("tag") {
+		case "ubuntu:14.04":
+			reply(progress{Status: "Pulling from library/ubuntu", ID: "14.04"})
+			reply(progress{Status: "Downloading", Progress: "[=====>     ] 5 MB/10 MB", ID: "a1b2"})
+			reply(progress{Status: "Status: Downloaded newer image for ubuntu:14.04"})
+		default:
+			reply(progress{Error: "manifest unknown"})
+		}
+	case p == "/images/json":
+		reply([]ds.Image{{ID: "sha256:a1b2", RepoTags: []string{"ubuntu:14.04"}, Size: 10}})
+	case p == "/images/ubuntu:14.04/json":
+		reply(ds.ImageStat{ID: "sha256:a1b2", RepoTags: []string{"ubuntu:14.04"}, Os: "linux"})
+	case p == "/images/ubuntu:14.04" && r.Method == "DELETE":
+		if r.URL.Query().Get("force") != "true" {
+			w.WriteHeader(http.StatusConflict)
+			reply(map[string]string{"message": "image is being used by running container c1"})
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		reply([]map[string]string{{"Untagged": "ubuntu:14.04"}})
+	case p == "/containers/c1/start":
+		w.WriteHeader(http.StatusNoContent)
+	case p == "/containers/c1/wait":
+		<-f.exited
+		reply(map[string]int{"StatusCode": 3})
+	case p == "/containers/c1/json":
+		reply(map[string]interface{}{"Id": "c1", "Name": "/x", "State": map[string]interface{}{"Running": false}})
+	case p == "/containers/c1/kill":
+		f.Lock()
+		f.signals = append(f.signals, r.URL.Query().Get("signal"))
+		f.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case p == "/containers/c1" && r.Method == "DELETE":
+		f.Lock()
+		f.removed = true
+		f.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case p == "/events":
+		reply(event{Status: "die", ID: "c1"})
+		reply(event{Status: "destroy", ID: "c1"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		reply(map[string]string{"message": "not found"})
+	}
+}
+
+func TestEngine(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listen (%s)", err)
+	}
+	f := &fakeEngine{exited: make(chan struct{})}
+	go http.Serve(l, f)
+	defer l.Close()
+	if v, err := Init("docker", "unix://"+sock); err != nil || !strings.Contains(v, "fake") {
+		t.Fatalf("init %q (%v)", v, err)
+	}
+
+	if _, err = MakeContainer(ds.Run{Image: "missing"}); err == nil || !strings.Contains(err.Error(), "No such image") {
+		t.Fatalf("expecting engine error, got %v", err)
+	}
+	con, err := MakeContainer(ds.Run{
+		Image:  "ubuntu",
+		Path:   "/bin/cat",
+		Volume: []string{"/data", "/src:/opt/src:ro"},
+	})
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	if f.api != MaxAPIVersion {
+		t.Fatalf("expecting API version %s to be negotiated, got %s", MaxAPIVersion, f.api)
+	}
+	if c := f.config; len(c.Cmd) != 1 || c.HostConfig.Binds[0] != "/src:/opt/src:ro" || len(c.Volumes) != 1 || !c.OpenStdin {
+		t.Fatalf("unexpected configuration %#v", c)
+	}
+	var actions []string
+	done := make(chan struct{})
+	Watch(con, func(action string) {
+		actions = append(actions, action)
+		if action == "destroy" {
+			close(done)
+		}
+	})
+	<-done
+	if len(actions) != 2 || actions[0] != "die" {
+		t.Fatalf("unexpected events %v", actions)
+	}
+
+	if err = con.Signal("TERM"); err != nil {
+		t.Fatalf("signal (%s)", err)
+	}
+	io.WriteString(con.Stdin(), "hello\n")
+	con.Stdin().Close()
+	out, _ := ioutil.ReadAll(con.Stdout())
+	errout, _ := ioutil.ReadAll(con.Stderr())
+	if string(out) != "hello\n" || string(errout) != "bye\n" {
+		t.Fatalf("unexpected output %q and %q", out, errout)
+	}
+	stat, err := con.Wait()
+	if err != nil || stat.State.ExitCode != 3 || !con.IsDone() {
+		t.Fatalf("wait %v (%v)", stat, err)
+	}
+
+	pr, err := con.Exec(ds.Exec{Path: "/bin/sh", Args: []string{"-c", "tr a-z A-Z"}, User: "nobody"})
+	if err != nil {
+		t.Fatalf("exec (%s)", err)
+	}
+	if c := f.exec; len(c.Cmd) != 3 || c.User != "nobody" || !c.AttachStdin {
+		t.Fatalf("unexpected exec configuration %#v", c)
+	}
+	io.WriteString(pr.Stdin(), "hello\n")
+	pr.Stdin().Close()
+	if out, _ = ioutil.ReadAll(pr.Stdout()); string(out) != "HELLO\n" {
+		t.Fatalf("unexpected exec output %q", out)
+	}
+	if xstat, err := pr.Wait(); err != nil || xstat.ExitCode != 7 || !pr.IsDone() {
+		t.Fatalf("exec wait %v (%v)", xstat, err)
+	}
+
+	stats, err := con.Stats()
+	if err != nil {
+		t.Fatalf("stats (%s)", err)
+	}
+	if stats.CPUPercent != 40 || stats.MemoryLimit != 1<<30 || stats.NetworkRx != 11 || stats.NetworkTx != 22 || stats.BlockRead != 100 || stats.BlockWrite != 200 {
+		t.Fatalf("unexpected stats %#v", stats)
+	}
+
+	con.Scrub()
+	f.Lock()
+	defer f.Unlock()
+	if len(f.signals) != 1 || f.signals[0] != "15" || !f.removed {
+		t.Fatalf("signals %v, removed %v", f.signals, f.removed)
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	for _, c := range []struct {
+		max, min, api string
+	}{
+		{"1.43", "1.12", MaxAPIVersion},
+		{"1.30", "", "1.30"},
+		{"1.24", "1.12", "1.24"},
+		{"1.9", "", ""},      // too old
+		{"1.23", "", ""},     // too old
+		{"1.50", "1.44", ""}, // too new
+		{"", "", ""},
+	} {
+		api, err := negotiateAPI(c.max, c.min)
+		if api != c.api || (err == nil) != (c.api != "") {
+			t.Errorf("negotiating with %s-%s: expecting %q, got %q (%v)", c.min, c.max, c.api, api, err)
+		}
+	}
+	e := &engine{name: "docker engine", api: "1.28"}
+	if _, err := e.Make(ds.Run{Image: "ubuntu", Health: &ds.Health{Cmd: "true", StartPeriod: "5s"}}); err == nil {
+		t.Errorf("expecting health check start period to need a newer API version")
+	}
+}
+
+func TestImages(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listen (%s)", err)
+	}
+	go http.Serve(l, &fakeEngine{})
+	defer l.Close()
+	if _, err = Init("docker", "unix://"+sock); err != nil {
+		t.Fatalf("init (%s)", err)
+	}
+
+	r, err := PullImage("ubuntu:14.04")
+	if err != nil {
+		t.Fatalf("pull (%s)", err)
+	}
+	out, _ := ioutil.ReadAll(r)
+	if lines := strings.Split(strings.TrimSpace(string(out)), "\n"); len(lines) != 3 || lines[1] != "a1b2: Downloading [=====>     ] 5 MB/10 MB" {
+		t.Fatalf("unexpected progress %q", out)
+	}
+	if r, err = PullImage("ubuntu:99"); err != nil {
+		t.Fatalf("pull (%s)", err)
+	}
+	if out, _ = ioutil.ReadAll(r); string(out) != "error: manifest unknown\n" {
+		t.Fatalf("expecting pull error, got %q", out)
+	}
+
+	images, err := ListImages()
+	if err != nil || len(images) != 1 || images[0].RepoTags[0] != "ubuntu:14.04" {
+		t.Fatalf("list %v (%v)", images, err)
+	}
+	stat, err := InspectImage("ubuntu:14.04")
+	if err != nil || stat.ID != "sha256:a1b2" || stat.Os != "linux" {
+		t.Fatalf("inspect %v (%v)", stat, err)
+	}
+	if err = RemoveImage("ubuntu:14.04", false); err == nil || !strings.Contains(err.Error(), "being used") {
+		t.Fatalf("expecting conflict, got %v", err)
+	}
+	if err = RemoveImage("ubuntu:14.04", true); err != nil {
+		t.Fatalf("remove (%s)", err)
+	}
+
+	for image, want := range map[string][2]string{
+		"ubuntu":                  {"ubuntu", "latest"},
+		"localhost:5000/app:v1":   {"localhost:5000/app", "v1"},
+		"localhost:5000/app":      {"localhost:5000/app", "latest"},
+		"app@sha256:0123456789ab": {"app", "sha256:0123456789ab"},
+	} {
+		if name, tag := splitImage(image); name != want[0] || tag != want[1] {
+			t.Errorf("split %s: %s %s", image, name, tag)
+		}
+	}
+}
+
+func TestMakeConfig(t *testing.T) {
+	cfg, err := makeConfig(ds.Run{
+		Image:    "nginx",
+		Ports:    []string{"80", "8080:80", "127.0.0.1:8443:443/tcp", "[::1]::53/udp"},
+		Network:  "backend",
+		Hostname: "web",
+		Labels:   map[string]string{"tier": "front"},
+		User:     "nobody",
+		ReadOnly: true,
+		CapDrop:  []string{"ALL"},
+		Health:   &ds.Health{Cmd: "curl -f localhost", Interval: "30s", Retries: 3},
+		Restart:  "on-failure:5",
+	})
+	if err != nil {
+		t.Fatalf("config (%s)", err)
+	}
+	h := cfg.HostConfig
+	if len(cfg.ExposedPorts) != 3 || len(h.PortBindings["80/tcp"]) != 1 || h.PortBindings["80/tcp"][0].HostPort != "8080" {
+		t.Fatalf("unexpected ports %v %v", cfg.ExposedPorts, h.PortBindings)
+	}
+	if b := h.PortBindings["443/tcp"][0]; b.HostIp != "127.0.0.1" || b.HostPort != "8443" {
+		t.Fatalf("unexpected binding %v", b)
+	}
+	if b := h.PortBindings["53/udp"][0]; b.HostIp != "::1" || b.HostPort != "" {
+		t.Fatalf("unexpected binding %v", b)
+	}
+	if h.NetworkMode != "backend" || !h.ReadonlyRootfs || h.CapDrop[0] != "ALL" || cfg.User != "nobody" || cfg.Hostname != "web" || cfg.Labels["tier"] != "front" {
+		t.Fatalf("unexpected configuration %#v", cfg)
+	}
+	if hc := cfg.Healthcheck; hc.Test[0] != "CMD-SHELL" || hc.Interval != int64(30*time.Second) || hc.Retries != 3 {
+		t.Fatalf("unexpected health check %v", hc)
+	}
+	if p := h.RestartPolicy; p.Name != "on-failure" || p.MaximumRetryCount != 5 {
+		t.Fatalf("unexpected restart policy %v", p)
+	}
+	for _, bad := range []ds.Run{
+		{Ports: []string{"80/icmp"}},
+		{Ports: []string{"x:80"}},
+		{Restart: "always:3"},
+		{Restart: "sometimes"},
+		{Health: &ds.Health{Cmd: "true", Timeout: "soon"}},
+		{Lxc: []string{"lxc.cgroup.cpuset.cpus = 0"}},
+	} {
+		if _, err := makeConfig(bad); err == nil {
+			t.Errorf("expecting error for %v", bad)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	ds "github.com/gocircuit/circuit/client/docker"
+	"github.com/gocircuit/circuit/kit/interruptible"
+	"github.com/gocircuit/circuit/use/circuit"
+)
+
+// Process is a command executing within a running container.
+type Process interface {
+	IsDone() bool
+	Peek() (*ds.ExecStat, error)
+	Wait() (*ds.ExecStat, error)
+	Stdin() io.WriteCloser
+	Stdout() io.ReadCloser
+	Stderr() io.ReadCloser
+	X() circuit.X
+}
+
+type process struct {
+	e      *engine
+	id     string
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+	exit   <-chan struct{}
+	sync.Mutex
+	stat    *ds.ExecStat
+	waitErr error
+}
+
+// execConfig is the configuration of an exec instance, as accepted by the Docker Engine API.
+type execConfig struct {
+	Cmd          []string
+	User         string `json:",omitempty"`
+	AttachStdin  bool
+	AttachStdout bool
+	AttachStderr bool
+}
+
+// Exec starts a command within the container, attached to standard streams of its own.
+func (con *container) Exec(cmd ds.Exec) (_ Process, err error) {
+	if cmd.Path == "" {
+		return nil, errors.New("exec needs a command path")
+	}
+	cfg := execConfig{
+		Cmd:          append([]string{cmd.Path}, cmd.Args...),
+		User:         cmd.User,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	var created struct {
+		Id string
+	}
+	if err = con.e.call("POST", "/containers/"+con.id+"/exec", nil, cfg, &created); err != nil {
+		return nil, err
+	}
+	p := &process{e: con.e, id: created.Id}
+	conn, err := p.e.hijack("/exec/"+p.id+"/start", nil, map[string]bool{"Detach": false, "Tty": false})
+	if err != nil {
+		return nil, err
+	}
+	var stdin io.Reader
+	var stdout, stderr io.WriteCloser
+	stdin, p.stdin = interruptible.BufferPipe(StdBufferLen)
+	p.stdout, stdout = interruptible.BufferPipe(StdBufferLen)
+	p.stderr, stderr = interruptible.BufferPipe(StdBufferLen)
+	go func() {
+		io.Copy(conn, stdin)
+		conn.CloseWrite()
+	}()
+	ch := make(chan struct{})
+	p.exit = ch
+	go func() {
+		defer close(ch)
+		demux(conn, stdout, stderr)
+		conn.Close()
+		stdout.Close()
+		stderr.Close()
+		// The engine may report the command as running shortly after its output ends.
+		var stat *ds.ExecStat
+		var err error
+		for i := 0; i < 100; i++ {
+			if stat, err = p.Peek(); err != nil || !stat.Running {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		p.Lock()
+		defer p.Unlock()
+		p.stat, p.waitErr = stat, err
+	}()
+	return p, nil
+}
+
+func (p *process) Peek() (*ds.ExecStat, error) {
+	stat := &ds.ExecStat{}
+	if err := p.e.call("GET", "/exec/"+p.id+"/json", nil, nil, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+func (p *process) Wait() (*ds.ExecStat, error) {
+	<-p.exit
+	p.Lock()
+	defer p.Unlock()
+	return p.stat, p.waitErr
+}
+
+func (p *process) IsDone() bool {
+	select {
+	case <-p.exit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *process) Stdin() io.WriteCloser {
+	return p.stdin
+}
+
+func (p *process) Stdout() io.ReadCloser {
+	return p.stdout
+}
+
+func (p *process) Stderr() io.ReadCloser {
+	return p.stderr
+}
+
+func (p *process) X() circuit.X {
+	return circuit.Ref(XProcess{p})
+}
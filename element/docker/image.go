@@ -0,0 +1,114 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	ds "github.com/gocircuit/circuit/client/docker"
+	"github.com/gocircuit/circuit/kit/interruptible"
+)
+
+// progress is a message of the progress stream of an image pull.
+type progress struct {
+	ID       string `json:"id"`
+	Status   string `json:"status"`
+	Progress string `json:"progress"`
+	Error    string `json:"error"`
+}
+
+func (p *progress) String() string {
+	var w []string
+	if p.ID != "" {
+		w = append(w, p.ID+":")
+	}
+	if p.Status != "" {
+		w = append(w, p.Status)
+	}
+	if p.Progress != "" {
+		w = append(w, p.Progress)
+	}
+	return strings.Join(w, " ")
+}
+
+// splitImage splits an image reference of the form name[:tag] or name@digest into a name and a tag
+// or digest, which is latest if omitted.
+func splitImage(image string) (name, tag string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+// PullImage pulls an image from its registry. It returns the progress of the pull, one message per line,
+// which ends when the pull is over. A failed pull ends with a line beginning with "error:".
+func (e *engine) PullImage(image string) (io.ReadCloser, error) {
+	if image == "" {
+		return nil, errors.New("pull needs an image")
+	}
+	name, tag := splitImage(image)
+	resp, err := e.do("POST", "/images/create", url.Values{"fromImage": {name}, "tag": {tag}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	r, w := interruptible.BufferPipe(StdBufferLen)
+	go func() {
+		defer resp.Body.Close()
+		defer w.Close()
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var p progress
+			if err := dec.Decode(&p); err != nil {
+				if err != io.EOF {
+					fmt.Fprintf(w, "error: %v\n", err)
+				}
+				return
+			}
+			if p.Error != "" {
+				fmt.Fprintf(w, "error: %s\n", p.Error)
+				return
+			}
+			if _, err := fmt.Fprintln(w, p.String()); err != nil {
+				return // reader closed
+			}
+		}
+	}()
+	return r, nil
+}
+
+// ListImages returns the images present on the host of the engine.
+func (e *engine) ListImages() ([]ds.Image, error) {
+	var images []ds.Image
+	if err := e.call("GET", "/images/json", nil, nil, &images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// RemoveImage removes an image from the host of the engine. Unless force is set, images used by containers are kept.
+func (e *engine) RemoveImage(image string, force bool) error {
+	return e.call("DELETE", "/images/"+image, url.Values{"force": {strconv.FormatBool(force)}}, nil, nil)
+}
+
+// InspectImage returns a description of an image present on the host of the engine.
+func (e *engine) InspectImage(image string) (*ds.ImageStat, error) {
+	stat := &ds.ImageStat{}
+	if err := e.call("GET", "/images/"+image+"/json", nil, nil, stat); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
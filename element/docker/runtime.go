@@ -0,0 +1,91 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"errors"
+	"io"
+
+	ds "github.com/gocircuit/circuit/client/docker"
+)
+
+// Runtime is a container runtime, which runs the containers of docker elements and manages their images.
+type Runtime interface {
+	// Make creates and starts a container, as described by run.
+	Make(run ds.Run) (Container, error)
+	// PullImage fetches an image, and returns the progress of the fetch, one message per line.
+	// A failed fetch ends with a line beginning with "error:".
+	PullImage(image string) (io.ReadCloser, error)
+	ListImages() ([]ds.Image, error)
+	RemoveImage(image string, force bool) error
+	InspectImage(image string) (*ds.ImageStat, error)
+}
+
+// backend is a Runtime that can describe itself, upon checking that it is usable.
+type backend interface {
+	Runtime
+	version() (string, error)
+}
+
+// DefaultRootfsDir is the directory holding the root filesystems of the chroot runtime, by default.
+const DefaultRootfsDir = "/var/lib/circuit/rootfs"
+
+// rt is the runtime of this server, set by Init.
+var rt Runtime
+
+var errDisabled = errors.New("docker not enabled on this server")
+
+// MakeContainer creates and starts a container, as described by run, through the runtime of this server.
+func MakeContainer(run ds.Run) (Container, error) {
+	if rt == nil {
+		return nil, errDisabled
+	}
+	return rt.Make(run)
+}
+
+// Watch calls f with the action of every event that the runtime reports for the container c,
+// like die, oom or kill, until the container is removed.
+func Watch(c Container, f func(action string)) {
+	if w, ok := c.(interface {
+		watch(func(string))
+	}); ok {
+		w.watch(f)
+	}
+}
+
+// PullImage pulls an image onto this server.
+func PullImage(image string) (io.ReadCloser, error) {
+	if rt == nil {
+		return nil, errDisabled
+	}
+	return rt.PullImage(image)
+}
+
+// ListImages returns the images present on this server.
+func ListImages() ([]ds.Image, error) {
+	if rt == nil {
+		return nil, errDisabled
+	}
+	return rt.ListImages()
+}
+
+// RemoveImage removes an image from this server. Unless force is set, images used by containers are kept.
+func RemoveImage(image string, force bool) error {
+	if rt == nil {
+		return errDisabled
+	}
+	return rt.RemoveImage(image, force)
+}
+
+// InspectImage returns a description of an image present on this server.
+func InspectImage(image string) (*ds.ImageStat, error) {
+	if rt == nil {
+		return nil, errDisabled
+	}
+	return rt.InspectImage(image)
+}
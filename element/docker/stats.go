@@ -0,0 +1,92 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package docker
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	ds "github.com/gocircuit/circuit/client/docker"
+)
+
+// cpuStats is the CPU usage of a container, as reported by the Docker Engine API.
+type cpuStats struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  uint32 `json:"online_cpus"`
+}
+
+// stats is a sample of the resource usage of a container, as reported by the Docker Engine API.
+type stats struct {
+	Read        time.Time `json:"read"`
+	CPUStats    cpuStats  `json:"cpu_stats"`
+	PreCPUStats cpuStats  `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64 `json:"usage"`
+		Limit uint64 `json:"limit"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+	BlkioStats struct {
+		IoServiceBytesRecursive []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
+	} `json:"blkio_stats"`
+	PidsStats struct {
+		Current uint64 `json:"current"`
+	} `json:"pids_stats"`
+}
+
+// summary sums the counters of the sample over all network interfaces and block devices.
+func (s *stats) summary() *ds.Stats {
+	r := &ds.Stats{
+		Read:        s.Read,
+		CPUTotal:    s.CPUStats.CPUUsage.TotalUsage,
+		MemoryUsage: s.MemoryStats.Usage,
+		MemoryLimit: s.MemoryStats.Limit,
+		Pids:        s.PidsStats.Current,
+	}
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	cpu := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	system := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpu > 0 && system > 0 {
+		r.CPUPercent = cpu / system * cpus * 100
+	}
+	for _, n := range s.Networks {
+		r.NetworkRx += n.RxBytes
+		r.NetworkTx += n.TxBytes
+	}
+	for _, b := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(b.Op) {
+		case "read":
+			r.BlockRead += b.Value
+		case "write":
+			r.BlockWrite += b.Value
+		}
+	}
+	return r
+}
+
+// Stats returns a sample of the resource usage of the container.
+func (con *container) Stats() (*ds.Stats, error) {
+	var s stats
+	if err := con.e.call("GET", "/containers/"+con.id+"/stats", url.Values{"stream": {"0"}}, nil, &s); err != nil {
+		return nil, err
+	}
+	return s.summary(), nil
+}
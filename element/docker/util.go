@@ -8,18 +8,57 @@
 package docker
 
 import (
-	"os/exec"
+	"fmt"
+	"os"
+	"path/filepath"
 )
 
-func Init() (_ string, err error) {
-	dkr, err = exec.LookPath("docker")
+// Init enables docker elements, which are run by the container runtime called name, at host.
+//
+// The docker runtime uses the Docker Engine API at host; if host is empty, the value of DOCKER_HOST
+// or else DefaultHost is used. The podman runtime uses the Docker-compatible API of Podman at host,
+// which defaults to the user's Podman socket, or DefaultPodmanHost for the superuser.
+// The chroot runtime, built into the circuit, runs containers in Linux namespaces, chrooted into
+// root filesystems found in the directory host, which defaults to DefaultRootfsDir.
+// It does not isolate containers from the host for security.
+//
+// Init returns a description of the runtime.
+func Init(name, host string) (_ string, err error) {
+	var r backend
+	switch name {
+	case "", "docker":
+		if host == "" {
+			host = os.Getenv("DOCKER_HOST")
+		}
+		if host == "" {
+			host = DefaultHost
+		}
+		r, err = newEngine("docker engine", host)
+	case "podman":
+		if host == "" {
+			host = DefaultPodmanHost
+			if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Geteuid() != 0 {
+				host = "unix://" + filepath.Join(dir, "podman", "podman.sock")
+			}
+		}
+		r, err = newEngine("podman", host)
+	case "chroot":
+		if host == "" {
+			host = DefaultRootfsDir
+		}
+		r, err = newChroot(host)
+	default:
+		return "", fmt.Errorf("container runtime %q not recognized", name)
+	}
 	if err != nil {
 		return "", err
 	}
-	err = exec.Command(dkr, "version").Run()
-	return dkr, err
+	v, err := r.version()
+	if err != nil {
+		return "", err
+	}
+	rt = r
+	return v, nil
 }
 
-var dkr string
-
 const StdBufferLen = 32e3
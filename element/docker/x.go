@@ -18,6 +18,7 @@ import (
 
 func init() {
 	circuit.RegisterValue(XContainer{})
+	circuit.RegisterValue(XProcess{})
 }
 
 type XContainer struct {
@@ -50,6 +51,19 @@ func (x XContainer) Peek() (*ds.Stat, error) {
 	return stat, errors.Pack(err)
 }
 
+func (x XContainer) Exec(cmd ds.Exec) (circuit.X, error) {
+	p, err := x.Container.Exec(cmd)
+	if err != nil {
+		return nil, errors.Pack(err)
+	}
+	return p.X(), nil
+}
+
+func (x XContainer) Stats() (*ds.Stats, error) {
+	stats, err := x.Container.Stats()
+	return stats, errors.Pack(err)
+}
+
 type YContainer struct {
 	X circuit.X
 }
@@ -90,3 +104,75 @@ func (y YContainer) Stdout() io.ReadCloser {
 func (y YContainer) Stderr() io.ReadCloser {
 	return xio.NewYReadCloser(y.X.Call("Stderr")[0])
 }
+
+func (y YContainer) Exec(cmd ds.Exec) (ds.Process, error) {
+	r := y.X.Call("Exec", cmd)
+	if err := errors.Unpack(r[1]); err != nil {
+		return nil, err
+	}
+	return YProcess{r[0].(circuit.X)}, nil
+}
+
+func (y YContainer) Stats() (stats *ds.Stats, err error) {
+	r := y.X.Call("Stats")
+	stats, _ = r[0].(*ds.Stats)
+	return stats, errors.Unpack(r[1])
+}
+
+type XProcess struct {
+	Process
+}
+
+func (x XProcess) Wait() (*ds.ExecStat, error) {
+	stat, err := x.Process.Wait()
+	return stat, errors.Pack(err)
+}
+
+func (x XProcess) Peek() (*ds.ExecStat, error) {
+	stat, err := x.Process.Peek()
+	return stat, errors.Pack(err)
+}
+
+func (x XProcess) Stdin() circuit.X {
+	return xio.NewXWriteCloser(x.Process.Stdin())
+}
+
+func (x XProcess) Stdout() circuit.X {
+	return xio.NewXReadCloser(x.Process.Stdout())
+}
+
+func (x XProcess) Stderr() circuit.X {
+	return xio.NewXReadCloser(x.Process.Stderr())
+}
+
+type YProcess struct {
+	X circuit.X
+}
+
+func (y YProcess) Wait() (stat *ds.ExecStat, err error) {
+	r := y.X.Call("Wait")
+	stat, _ = r[0].(*ds.ExecStat)
+	return stat, errors.Unpack(r[1])
+}
+
+func (y YProcess) Peek() (stat *ds.ExecStat, err error) {
+	r := y.X.Call("Peek")
+	stat, _ = r[0].(*ds.ExecStat)
+	return stat, errors.Unpack(r[1])
+}
+
+func (y YProcess) IsDone() bool {
+	return y.X.Call("IsDone")[0].(bool)
+}
+
+func (y YProcess) Stdin() io.WriteCloser {
+	return xio.NewYWriteCloser(y.X.Call("Stdin")[0])
+}
+
+func (y YProcess) Stdout() io.ReadCloser {
+	return xio.NewYReadCloser(y.X.Call("Stdout")[0])
+}
+
+func (y YProcess) Stderr() io.ReadCloser {
+	return xio.NewYReadCloser(y.X.Call("Stderr")[0])
+}
@@ -12,10 +12,14 @@ import (
 	"io"
 	"os"
 	"runtime/pprof"
+	"sync"
 	"time"
 
+	ds "github.com/gocircuit/circuit/client/docker"
+	"github.com/gocircuit/circuit/element/docker"
 	"github.com/gocircuit/circuit/kit/interruptible"
 	"github.com/gocircuit/circuit/tissue"
+	"github.com/gocircuit/circuit/tissue/tube"
 	"github.com/gocircuit/circuit/use/circuit"
 	"github.com/gocircuit/circuit/use/n"
 )
@@ -25,6 +29,11 @@ type Server interface {
 	Peek() Stat
 	Rejoin(string) error // circuit address to join to
 	Suicide()
+	Drain(DrainSpec) error
+	PullImage(image string) (io.ReadCloser, error)
+	Images() ([]ds.Image, error)
+	RemoveImage(image string, force bool) error
+	InspectImage(image string) (*ds.ImageStat, error)
 	IsDone() bool
 	Scrub()
 	X() circuit.X
@@ -32,22 +41,59 @@ type Server interface {
 
 // server
 type server struct {
-	addr   string
-	kin    *tissue.Kin
-	joined time.Time
+	addr     string
+	kin      *tissue.Kin
+	joined   time.Time
+	announce time.Duration
+	expire   time.Duration
+	drain    func(DrainSpec)
+	tubes    []*tube.Tube
+	lk       sync.Mutex
+	draining bool
 }
 
-func New(kin *tissue.Kin) Server {
+// DrainSpec describes how a server treats its running processes before it leaves the circuit.
+type DrainSpec struct {
+	Signal string        // signal sent to running processes, if not empty
+	Wait   time.Duration // duration for which the server waits for its processes to exit
+}
+
+// drainExit is the pause between the return of Drain and the exit of the server.
+const drainExit = 100 * time.Millisecond
+
+// New returns the server element of a circuit server, whose membership records are announced
+// every announce interval and expire after the expire duration. The server reports on the given tubes.
+// Draining the server invokes drain, which must stop its elements and withdraw it from the circuit.
+func New(kin *tissue.Kin, announce, expire time.Duration, drain func(DrainSpec), tubes ...*tube.Tube) Server {
 	return &server{
-		addr:   kin.Avatar().X.Addr().String(),
-		kin:    kin,
-		joined: time.Now(),
+		addr:     kin.Avatar().X.Addr().String(),
+		kin:      kin,
+		joined:   time.Now(),
+		announce: announce,
+		expire:   expire,
+		drain:    drain,
+		tubes:    tubes,
 	}
 }
 
 type Stat struct {
 	Addr   string
 	Joined time.Time
+	// Membership timing
+	Announce time.Duration
+	Expire   time.Duration
+	// Tissue topology
+	ExpansionLow  int
+	ExpansionHigh int
+	Spread        int
+	Depth         int
+	// Tissue overlay
+	Neighbors []string            // IDs of the servers in the neighborhood of this one
+	Folk      map[string][]string // IDs of the peers of this server's tubes, by topic
+	// Revisions of tube records, by topic and key
+	Revisions map[string]map[string]uint64
+	// Anti-entropy traffic of the tubes, by topic
+	Repair map[string]tube.RepairStat
 }
 
 func (s *server) Rejoin(addr string) error {
@@ -62,6 +108,24 @@ func (s *server) Suicide() {
 	os.Exit(0)
 }
 
+// Drain stops the server from accepting new elements, waits for or signals its processes as described by spec,
+// and withdraws the server from the circuit. The server exits shortly after Drain returns.
+func (s *server) Drain(spec DrainSpec) error {
+	s.lk.Lock()
+	if s.draining {
+		s.lk.Unlock()
+		return errors.New("server is already draining")
+	}
+	s.draining = true
+	s.lk.Unlock()
+	s.drain(spec)
+	go func() {
+		time.Sleep(drainExit) // let the reply reach the caller
+		os.Exit(0)
+	}()
+	return nil
+}
+
 func (s *server) Profile(name string) (io.ReadCloser, error) {
 	p := pprof.Lookup(name)
 	if p == nil {
@@ -76,6 +140,26 @@ func (s *server) Profile(name string) (io.ReadCloser, error) {
 	return r, nil
 }
 
+// PullImage pulls a docker image onto this server, and returns the progress of the pull.
+func (s *server) PullImage(image string) (io.ReadCloser, error) {
+	return docker.PullImage(image)
+}
+
+// Images lists the docker images present on this server.
+func (s *server) Images() ([]ds.Image, error) {
+	return docker.ListImages()
+}
+
+// RemoveImage removes a docker image from this server.
+func (s *server) RemoveImage(image string, force bool) error {
+	return docker.RemoveImage(image, force)
+}
+
+// InspectImage describes a docker image present on this server.
+func (s *server) InspectImage(image string) (*ds.ImageStat, error) {
+	return docker.InspectImage(image)
+}
+
 type nopCloser struct {
 	io.Reader
 }
@@ -85,9 +169,40 @@ func (nopCloser) Close() error {
 }
 
 func (s *server) Peek() Stat {
+	cfg := s.kin.Config()
+	var neighbors []string
+	for _, av := range s.kin.Neighbors() {
+		neighbors = append(neighbors, av.ID.String())
+	}
+	folk := make(map[string][]string)
+	for _, f := range s.kin.Folk() {
+		ids := []string{}
+		for _, av := range f.Opened() {
+			ids = append(ids, av.ID.String())
+		}
+		folk[f.Topic()] = ids
+	}
+	rev, repair := make(map[string]map[string]uint64), make(map[string]tube.RepairStat)
+	for _, t := range s.tubes {
+		rev[t.Topic()] = make(map[string]uint64)
+		for _, r := range t.BulkRead() {
+			rev[t.Topic()][r.Key] = uint64(r.Rev)
+		}
+		repair[t.Topic()] = t.RepairStat()
+	}
 	return Stat{
-		Addr:   s.addr,
-		Joined: s.joined,
+		Addr:          s.addr,
+		Joined:        s.joined,
+		Announce:      s.announce,
+		Expire:        s.expire,
+		ExpansionLow:  cfg.ExpansionLow,
+		ExpansionHigh: cfg.ExpansionHigh,
+		Spread:        cfg.Spread,
+		Depth:         cfg.Depth,
+		Neighbors:     neighbors,
+		Folk:          folk,
+		Revisions:     rev,
+		Repair:        repair,
 	}
 }
 
@@ -0,0 +1,69 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gocircuit/circuit/sys/lang"
+	_ "github.com/gocircuit/circuit/sys/tele"
+	"github.com/gocircuit/circuit/tissue"
+	"github.com/gocircuit/circuit/tissue/tube"
+	"github.com/gocircuit/circuit/use/circuit"
+	"github.com/gocircuit/circuit/use/n"
+)
+
+func newRuntime() *lang.Runtime {
+	return lang.New(n.NewTransport(n.ChooseWorkerID(), &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}, nil))
+}
+
+// TestPeekFolk joins two kins, hosted by two runtimes in this process,
+// and checks that their stats report each other by kin ID.
+func TestPeekFolk(t *testing.T) {
+	r1, r2 := newRuntime(), newRuntime()
+	circuit.Bind(r2) // the runtime of the joining kin
+	k1, x1, _ := tissue.NewKin(tissue.DefaultConfig)
+	k2, x2, _ := tissue.NewKin(tissue.DefaultConfig)
+	r1.Listen(tissue.ServiceName, x1)
+	r2.Listen(tissue.ServiceName, x2)
+	t1 := tube.NewTube(k1, "locus")
+	tube.NewTube(k2, "locus")
+	if err := k2.ReJoin(r1.ServerAddr()); err != nil {
+		t.Fatalf("join (%s)", err)
+	}
+	// Upon attaching, the dns tube of k2 learns of the one of k1; the latter learns of nobody.
+	tube.NewTube(k1, "dns")
+	tube.NewTube(k2, "dns")
+
+	s1, s2 := New(k1, time.Second, time.Minute, nil, t1), New(k2, time.Second, time.Minute, nil)
+	id1, id2 := k1.Avatar().ID.String(), k2.Avatar().ID.String()
+	for _, c := range []struct {
+		s    Server
+		peer string
+		dns  int
+	}{{s1, id2, 0}, {s2, id1, 1}} {
+		var stat Stat
+		for i := 0; i < 100; i++ { // folk peers are opened asynchronously
+			if stat = c.s.Peek(); len(stat.Folk["locus"]) > 0 && len(stat.Folk["dns"]) == c.dns {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if len(stat.Neighbors) != 1 || stat.Neighbors[0] != c.peer {
+			t.Errorf("expecting neighbors [%s], got %v", c.peer, stat.Neighbors)
+		}
+		if f := stat.Folk["locus"]; len(f) != 1 || f[0] != c.peer {
+			t.Errorf("expecting locus folk [%s], got %v", c.peer, f)
+		}
+		if f := stat.Folk["dns"]; len(f) != c.dns || (c.dns == 1 && f[0] != c.peer) {
+			t.Errorf("expecting %d dns folk, got %v", c.dns, f)
+		}
+	}
+}
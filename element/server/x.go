@@ -11,6 +11,7 @@ import (
 	// "fmt"
 	"io"
 
+	ds "github.com/gocircuit/circuit/client/docker"
 	xio "github.com/gocircuit/circuit/kit/x/io"
 	"github.com/gocircuit/circuit/use/circuit"
 	"github.com/gocircuit/circuit/use/errors"
@@ -37,6 +38,32 @@ func (x XServer) Rejoin(addr string) error {
 	return errors.Pack(x.server.Rejoin(addr))
 }
 
+func (x XServer) Drain(spec DrainSpec) error {
+	return errors.Pack(x.server.Drain(spec))
+}
+
+func (x XServer) PullImage(image string) (circuit.X, error) {
+	r, err := x.server.PullImage(image)
+	if err != nil {
+		return nil, errors.Pack(err)
+	}
+	return xio.NewXReadCloser(r), nil
+}
+
+func (x XServer) Images() ([]ds.Image, error) {
+	images, err := x.server.Images()
+	return images, errors.Pack(err)
+}
+
+func (x XServer) RemoveImage(image string, force bool) error {
+	return errors.Pack(x.server.RemoveImage(image, force))
+}
+
+func (x XServer) InspectImage(image string) (*ds.ImageStat, error) {
+	stat, err := x.server.InspectImage(image)
+	return stat, errors.Pack(err)
+}
+
 // YServer…
 type YServer struct {
 	X circuit.X
@@ -69,3 +96,31 @@ func (y YServer) Scrub() {
 func (y YServer) Suicide() {
 	y.X.Call("Suicide")
 }
+
+func (y YServer) Drain(spec DrainSpec) error {
+	return errors.Unpack(y.X.Call("Drain", spec)[0])
+}
+
+func (y YServer) PullImage(image string) (io.ReadCloser, error) {
+	r := y.X.Call("PullImage", image)
+	if err := errors.Unpack(r[1]); err != nil {
+		return nil, err
+	}
+	return xio.NewYReadCloser(r[0]), nil
+}
+
+func (y YServer) Images() (images []ds.Image, err error) {
+	r := y.X.Call("Images")
+	images, _ = r[0].([]ds.Image)
+	return images, errors.Unpack(r[1])
+}
+
+func (y YServer) RemoveImage(image string, force bool) error {
+	return errors.Unpack(y.X.Call("RemoveImage", image, force)[0])
+}
+
+func (y YServer) InspectImage(image string) (stat *ds.ImageStat, err error) {
+	r := y.X.Call("InspectImage", image)
+	stat, _ = r[0].(*ds.ImageStat)
+	return stat, errors.Unpack(r[1])
+}
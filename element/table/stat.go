@@ -0,0 +1,26 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package table
+
+import (
+	"encoding/json"
+)
+
+// Stat describes a table.
+type Stat struct {
+	Topic string `json:"topic"`
+	Len   int    `json:"len"` // number of live entries
+}
+
+func (s Stat) String() string {
+	b, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		panic(0)
+	}
+	return string(b)
+}
@@ -0,0 +1,287 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+// Package table implements the table element, a key-value map replicated across the servers of a circuit.
+package table
+
+import (
+	"encoding/gob"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocircuit/circuit/kit/pubsub"
+	"github.com/gocircuit/circuit/tissue/tube"
+	"github.com/gocircuit/circuit/use/circuit"
+)
+
+// ReplicaTopic is the topic of the tube that carries the entries of all tables.
+const ReplicaTopic = "table"
+
+// MaxValueSize is the largest value a table accepts. Tables are meant for small data, like configuration.
+const MaxValueSize = 64 << 10
+
+// TombstoneTTL is the duration for which the deletion of a key is remembered.
+// A write of the key that is older than its deletion, and reaches a server later than TombstoneTTL
+// after the deletion, revives the key. So does a server that held the key, and was cut off from
+// its peers for longer than TombstoneTTL and tube.ForgetMemory, when it rejoins.
+const TombstoneTTL = time.Hour
+
+// Replicator is a key-value table shared among the servers of a circuit, like tube.Tube.
+type Replicator interface {
+	Lookup(key string) *tube.Record
+	BulkRead() []*tube.Record
+	Write(key string, rev tube.Rev, value interface{}) bool
+	Forget(key string, notAfterRev tube.Rev, notAfterUpdated time.Time) bool
+	NewUpdates(from int64, match pubsub.Match) (*pubsub.Subscription, error)
+	UpdateSeq() int64
+}
+
+// value is the replicated value of a table key. A deleted value records the removal of the key.
+type value struct {
+	Value   string
+	Expires time.Time
+	Deleted bool
+}
+
+func init() {
+	gob.Register(&value{})
+	gob.Register(Entry{})
+}
+
+// Entry is a key-value pair of a table.
+type Entry struct {
+	Key     string
+	Value   string
+	Rev     uint64    // revision of the entry; later changes have higher revisions
+	Expires time.Time // time after which the entry is removed, or zero if it does not expire
+	Deleted bool      // set in watched entries that report the removal of a key
+}
+
+// Table is a key-value map, whose entries are shared by all tables of the same topic across the circuit.
+type Table interface {
+	Put(key, value string, ttl time.Duration) error
+	Get(key string) (Entry, bool)
+	Delete(key string)
+	List(prefix string) []Entry
+	Watch(prefix string) (pubsub.Consumer, error)
+	Peek() Stat
+	Scrub()
+	X() circuit.X
+}
+
+// table keeps its entries in a replicator, under keys of the form topic/key.
+// Concurrent changes to the same key are resolved in favor of the later one.
+type table struct {
+	topic string
+	repl  Replicator
+}
+
+// Make returns a table of the given topic, whose entries are kept in repl.
+func Make(topic string, repl Replicator) (Table, error) {
+	if topic == "" || strings.Contains(topic, "/") {
+		return nil, errors.New("invalid table topic")
+	}
+	return &table{topic: topic, repl: repl}, nil
+}
+
+func (t *table) key(key string) string {
+	return t.topic + "/" + key
+}
+
+// entry returns the entry replicated in rec.
+func (t *table) entry(rec *tube.Record) Entry {
+	e := Entry{
+		Key: strings.TrimPrefix(rec.Key, t.key("")),
+		Rev: uint64(rec.Rev),
+	}
+	if v, ok := rec.Value.(*value); ok {
+		e.Value, e.Expires, e.Deleted = v.Value, v.Expires, v.Deleted
+	}
+	return e
+}
+
+// live returns true if the entry is neither deleted nor expired at time now.
+func (e Entry) live(now time.Time) bool {
+	return !e.Deleted && (e.Expires.IsZero() || now.Before(e.Expires))
+}
+
+// write replicates the value of key. The revision of the value is its time of change,
+// but always greater than that of the current value.
+func (t *table) write(key string, v *value) {
+	key, rev := t.key(key), tube.Rev(time.Now().UnixNano())
+	if cur := t.repl.Lookup(key); cur != nil && cur.Rev >= rev {
+		rev = cur.Rev + 1
+	}
+	t.repl.Write(key, rev, v)
+}
+
+func (t *table) Put(key, val string, ttl time.Duration) error {
+	switch {
+	case key == "":
+		return errors.New("empty key")
+	case len(val) > MaxValueSize:
+		return errors.New("value too large")
+	case ttl < 0:
+		return errors.New("negative ttl")
+	}
+	v := &value{Value: val}
+	if ttl > 0 {
+		v.Expires = time.Now().Add(ttl)
+	}
+	t.write(key, v)
+	return nil
+}
+
+func (t *table) Get(key string) (Entry, bool) {
+	rec := t.repl.Lookup(t.key(key))
+	if rec == nil {
+		return Entry{}, false
+	}
+	e := t.entry(rec)
+	if !e.live(time.Now()) {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+func (t *table) Delete(key string) {
+	if _, ok := t.Get(key); !ok {
+		return
+	}
+	t.write(key, &value{Deleted: true})
+}
+
+// List returns the live entries whose keys begin with prefix, in order of key.
+func (t *table) List(prefix string) []Entry {
+	var r []Entry
+	now := time.Now()
+	for _, rec := range t.repl.BulkRead() {
+		if !strings.HasPrefix(rec.Key, t.key(prefix)) {
+			continue
+		}
+		if e := t.entry(rec); e.live(now) {
+			r = append(r, e)
+		}
+	}
+	sort.Sort(byKey(r))
+	return r
+}
+
+type byKey []Entry
+
+func (x byKey) Len() int           { return len(x) }
+func (x byKey) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byKey) Less(i, j int) bool { return x[i].Key < x[j].Key }
+
+// Watch returns a subscription to the changes of the entries whose keys begin with prefix.
+// The subscription begins with the live entries, as listed by List, followed by their later changes.
+// Entries that expire are reported as deleted once they are reaped. Keys that reappear after their
+// removal has been forgotten (see TombstoneTTL) are reported as new entries.
+func (t *table) Watch(prefix string) (pubsub.Consumer, error) {
+	key := t.key(prefix)
+	seq := t.repl.UpdateSeq()
+	snap := t.List(prefix)
+	// Updates distributed after seq may already be reflected in the snapshot; they are skipped by revision.
+	sub, err := t.repl.NewUpdates(seq, func(v interface{}) bool {
+		return strings.HasPrefix(v.(*tube.Record).Key, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	w := &watch{Subscription: sub, t: t, seq: seq, snap: snap, rev: make(map[string]uint64)}
+	for _, e := range snap {
+		w.rev[e.Key] = e.Rev
+	}
+	return w, nil
+}
+
+func (t *table) Peek() Stat {
+	return Stat{Topic: t.topic, Len: len(t.List(""))}
+}
+
+// Scrub removes the table element. Its entries remain with the other tables of the topic.
+func (t *table) Scrub() {}
+
+func (t *table) X() circuit.X {
+	return circuit.Ref(XTable{t})
+}
+
+// watch is a subscription to the changes of table entries, delivering Entry values.
+type watch struct {
+	*pubsub.Subscription
+	t  *table
+	lk sync.Mutex
+	// seq is the sequence number of the update the snapshot follows
+	seq  int64
+	snap []Entry
+	// rev holds the revision of every key reported so far
+	rev map[string]uint64
+}
+
+func init() {
+	circuit.RegisterValue(&watch{})
+}
+
+func (w *watch) X() circuit.X {
+	return circuit.Ref(w)
+}
+
+func (w *watch) Consume() (interface{}, bool) {
+	_, v, ok := w.ConsumeSeq()
+	return v, ok
+}
+
+func (w *watch) ConsumeSeq() (int64, interface{}, bool) {
+	w.lk.Lock()
+	if len(w.snap) > 0 {
+		e := w.snap[0]
+		w.snap = w.snap[1:]
+		w.lk.Unlock()
+		return w.seq, e, true
+	}
+	w.lk.Unlock()
+	for {
+		seq, v, ok := w.Subscription.ConsumeSeq()
+		if !ok {
+			return 0, nil, false
+		}
+		e := w.t.entry(v.(*tube.Record))
+		w.lk.Lock()
+		rev, known := w.rev[e.Key]
+		if e.Rev <= rev || e.Deleted && !known {
+			w.lk.Unlock()
+			continue // already reported, or the removal of a key never reported
+		}
+		w.rev[e.Key] = e.Rev
+		w.lk.Unlock()
+		return seq, e, true
+	}
+}
+
+// Reap periodically replaces the expired entries of all tables with deletions, which are delivered to watchers,
+// and forgets deletions older than TombstoneTTL.
+// Entries are reaped by each server independently, so the clocks of servers should agree.
+// The deletion of an expired entry has the next revision on all servers, so that it is written only once.
+func Reap(repl Replicator, interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		now := time.Now()
+		for _, rec := range repl.BulkRead() {
+			v, ok := rec.Value.(*value)
+			switch {
+			case !ok:
+			case v.Deleted && now.Sub(rec.Updated) > TombstoneTTL:
+				repl.Forget(rec.Key, rec.Rev, time.Time{})
+			case !v.Deleted && !v.Expires.IsZero() && now.After(v.Expires):
+				repl.Write(rec.Key, rec.Rev+1, &value{Deleted: true})
+			}
+		}
+	}
+}
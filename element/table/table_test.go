@@ -0,0 +1,121 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package table
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocircuit/circuit/kit/pubsub"
+	"github.com/gocircuit/circuit/tissue/tube"
+)
+
+type testReplicator struct {
+	*tube.View
+}
+
+func (r testReplicator) BulkRead() []*tube.Record {
+	return r.Peek()
+}
+
+func (r testReplicator) Write(key string, rev tube.Rev, value interface{}) bool {
+	return r.Update(&tube.Record{Key: key, Rev: rev, Value: value})
+}
+
+func TestTable(t *testing.T) {
+	repl := testReplicator{tube.NewView()}
+	a, _ := Make("config", repl)
+	b, _ := Make("config", repl)
+	other, _ := Make("other", repl)
+	if _, err := Make("a/b", repl); err == nil {
+		t.Fatalf("topic with slash accepted")
+	}
+	if err := a.Put("db/addr", "10.0.0.7:5432", 0); err != nil {
+		t.Fatalf("put (%s)", err)
+	}
+	a.Put("db/user", "admin", 0)
+	a.Put("web/addr", "10.0.0.8:80", 0)
+	if e, ok := b.Get("db/addr"); !ok || e.Value != "10.0.0.7:5432" {
+		t.Fatalf("get from another table of the topic: %v %v", e, ok)
+	}
+	if _, ok := other.Get("db/addr"); ok {
+		t.Fatalf("entry visible in table of another topic")
+	}
+	if l := b.List("db/"); len(l) != 2 || l[0].Key != "db/addr" || l[1].Key != "db/user" {
+		t.Fatalf("list: %v", l)
+	}
+	e0, _ := a.Get("db/user")
+	b.Put("db/user", "root", 0)
+	if e, _ := a.Get("db/user"); e.Value != "root" || e.Rev <= e0.Rev {
+		t.Fatalf("overwrite: %v after %v", e, e0)
+	}
+	b.Delete("db/user")
+	if _, ok := a.Get("db/user"); ok {
+		t.Fatalf("deleted key present")
+	}
+	if n := a.Peek().Len; n != 2 {
+		t.Fatalf("expecting 2 entries, got %d", n)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	repl := testReplicator{tube.NewView()}
+	a, _ := Make("config", repl)
+	a.Put("lock", "X1", 50*time.Millisecond)
+	if _, ok := a.Get("lock"); !ok {
+		t.Fatalf("entry expired early")
+	}
+	sub, err := a.Watch("")
+	if err != nil {
+		t.Fatalf("watch (%s)", err)
+	}
+	go Reap(repl, 10*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	if _, ok := a.Get("lock"); ok {
+		t.Fatalf("entry did not expire")
+	}
+	if rec := repl.Lookup("config/lock"); rec == nil || !rec.Value.(*value).Deleted {
+		t.Fatalf("expired entry not replaced by a deletion")
+	}
+	for _, want := range []Entry{{Key: "lock"}, {Key: "lock", Deleted: true}} {
+		v, ok := sub.Consume()
+		if !ok {
+			t.Fatalf("watch ended")
+		}
+		if e := v.(Entry); e.Key != want.Key || e.Deleted != want.Deleted {
+			t.Fatalf("expecting %v, got %v", want, e)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	repl := testReplicator{tube.NewView()}
+	a, _ := Make("config", repl)
+	a.Put("db/addr", "10.0.0.7:5432", 0)
+	a.Put("web/addr", "10.0.0.8:80", 0)
+	a.Put("db/old", "x", 0)
+	a.Delete("db/old") // deleted before the watch, not reported
+	for i := 0; i < 2*pubsub.DefaultRetain; i++ {
+		a.Put("db/addr", "10.0.0.7:5432", 0) // updates beyond the retained history
+	}
+	sub, err := a.Watch("db/")
+	if err != nil {
+		t.Fatalf("watch (%s)", err)
+	}
+	a.Put("db/user", "admin", 0)
+	a.Delete("db/addr")
+	for _, want := range []Entry{{Key: "db/addr"}, {Key: "db/user"}, {Key: "db/addr", Deleted: true}} {
+		v, ok := sub.Consume()
+		if !ok {
+			t.Fatalf("watch ended")
+		}
+		if e := v.(Entry); e.Key != want.Key || e.Deleted != want.Deleted {
+			t.Fatalf("expecting %v, got %v", want, e)
+		}
+	}
+}
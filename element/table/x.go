@@ -0,0 +1,77 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package table
+
+import (
+	"time"
+
+	"github.com/gocircuit/circuit/kit/pubsub"
+	"github.com/gocircuit/circuit/use/circuit"
+	"github.com/gocircuit/circuit/use/errors"
+)
+
+func init() {
+	circuit.RegisterValue(XTable{})
+}
+
+// X
+type XTable struct {
+	Table
+}
+
+func (x XTable) Put(key, value string, ttl time.Duration) error {
+	err := x.Table.Put(key, value, ttl)
+	return errors.Pack(err)
+}
+
+func (x XTable) Watch(prefix string) (circuit.X, error) {
+	sub, err := x.Table.Watch(prefix)
+	if err != nil {
+		return nil, errors.Pack(err)
+	}
+	return sub.X(), nil
+}
+
+// Y
+type YTable struct {
+	X circuit.X
+}
+
+func (y YTable) Put(key, value string, ttl time.Duration) error {
+	r := y.X.Call("Put", key, value, ttl)
+	return errors.Unpack(r[0])
+}
+
+func (y YTable) Get(key string) (Entry, bool) {
+	r := y.X.Call("Get", key)
+	return r[0].(Entry), r[1].(bool)
+}
+
+func (y YTable) Delete(key string) {
+	y.X.Call("Delete", key)
+}
+
+func (y YTable) List(prefix string) []Entry {
+	return y.X.Call("List", prefix)[0].([]Entry)
+}
+
+func (y YTable) Watch(prefix string) (pubsub.YSubscription, error) {
+	r := y.X.Call("Watch", prefix)
+	if err := errors.Unpack(r[1]); err != nil {
+		return pubsub.YSubscription{}, err
+	}
+	return pubsub.YSubscription{X: r[0].(circuit.X)}, nil
+}
+
+func (y YTable) Peek() Stat {
+	return y.X.Call("Peek")[0].(Stat)
+}
+
+func (y YTable) Scrub() {
+	y.X.Call("Scrub")
+}
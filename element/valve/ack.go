@@ -0,0 +1,294 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package valve
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// DefaultVisibility is the lease duration of acknowledgement-mode channels that do not specify one.
+const DefaultVisibility = 30 * time.Second
+
+// Lease is a message received from a channel in acknowledgement mode.
+// Unless acknowledged before the channel's visibility timeout expires, the message is redelivered.
+type Lease interface {
+	io.ReadCloser
+	// Ack confirms the message has been processed, so that it is never redelivered.
+	Ack() error
+	// Release gives up the lease, making the message available for redelivery right away.
+	Release()
+}
+
+// AckMaxBytes bounds the size of the messages of in-memory channels in acknowledgement mode
+// that do not specify a MaxBytes limit. Larger messages are discarded.
+const AckMaxBytes = 16 << 20 // 16M
+
+// held is a message held by a channel in acknowledgement mode until it is acknowledged.
+type held interface {
+	// open returns a reader for the payload of the message, once for every delivery.
+	open() (io.ReadCloser, error)
+	// ack consumes the message.
+	ack() error
+}
+
+// ackValve wraps a channel in acknowledgement mode.
+// Messages are pumped out of the underlying channel and leased to receivers.
+// Expired and released leases are queued for redelivery ahead of fresh messages.
+//
+// Messages of in-memory channels are read in full, up to the channel's size limit.
+// Durable channels keep leased messages in their log until they are acknowledged,
+// so that messages unacknowledged at the time of a crash are redelivered once the channel is reopened.
+type ackValve struct {
+	Valve
+	visibility time.Duration
+	maxBytes   int64
+	ready      <-chan held // fresh messages from the underlying channel
+	ctrl       struct {
+		sync.Mutex
+		abr          chan struct{}
+		wake         chan struct{} // closed and replaced whenever a lease ends
+		redo         []held        // messages awaiting redelivery
+		err          error         // reason the pump stopped
+		aborted      bool
+		numLeased    int
+		numAck       int
+		numRedeliver int
+	}
+}
+
+func withAck(v Valve, spec Spec) Valve {
+	a := &ackValve{Valve: v, visibility: spec.Visibility, maxBytes: spec.MaxBytes}
+	if a.visibility <= 0 {
+		a.visibility = DefaultVisibility
+	}
+	if a.maxBytes <= 0 {
+		a.maxBytes = AckMaxBytes
+	}
+	source := a.recv
+	if d, ok := v.(*durableValve); ok {
+		source = d.next
+	}
+	ready := make(chan held)
+	a.ready = ready
+	a.ctrl.abr, a.ctrl.wake = make(chan struct{}), make(chan struct{})
+	go a.pump(source, ready, a.ctrl.abr)
+	return a
+}
+
+// pump receives messages from source, until the underlying channel is closed or aborted.
+func (a *ackValve) pump(source func() (held, error), ready chan<- held, abr <-chan struct{}) {
+	defer close(ready)
+	for {
+		msg, err := source()
+		if err != nil {
+			a.ctrl.Lock()
+			a.ctrl.err = err
+			a.ctrl.Unlock()
+			return
+		}
+		select {
+		case ready <- msg:
+		case <-abr:
+			return
+		}
+	}
+}
+
+// recv receives the next message of an in-memory channel in full.
+func (a *ackValve) recv() (held, error) {
+	for {
+		r, err := a.Valve.Recv()
+		if err != nil {
+			return nil, err
+		}
+		msg, err := ioutil.ReadAll(io.LimitReader(r, a.maxBytes+1))
+		r.Close()
+		if err != nil || int64(len(msg)) > a.maxBytes {
+			continue // the sender failed mid-transmission, or the message is too large
+		}
+		return memHeld(msg), nil
+	}
+}
+
+// memHeld is a message of an in-memory channel.
+type memHeld []byte
+
+func (m memHeld) open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(m)), nil
+}
+
+func (m memHeld) ack() error {
+	return nil
+}
+
+// Lease blocks until a message is available, and leases it to the caller.
+func (a *ackValve) Lease() (Lease, error) {
+	var drained bool
+	for {
+		a.ctrl.Lock()
+		if len(a.ctrl.redo) > 0 {
+			msg := a.ctrl.redo[0]
+			a.ctrl.redo = a.ctrl.redo[1:]
+			a.ctrl.numRedeliver++
+			l, err := a.lease(msg)
+			a.ctrl.Unlock()
+			return l, err
+		}
+		if drained && a.ctrl.numLeased == 0 {
+			err := a.ctrl.err
+			a.ctrl.Unlock()
+			return nil, err
+		}
+		wake, abr := a.ctrl.wake, a.ctrl.abr
+		a.ctrl.Unlock()
+		var ready <-chan held
+		if !drained {
+			ready = a.ready
+		}
+		select {
+		case msg, ok := <-ready:
+			if !ok {
+				drained = true
+				continue
+			}
+			a.ctrl.Lock()
+			l, err := a.lease(msg)
+			a.ctrl.Unlock()
+			return l, err
+		case <-wake:
+		case <-abr:
+			return nil, errors.New("channel aborted")
+		}
+	}
+}
+
+// lease issues a new lease for msg. The control lock must be held.
+// If the message cannot be read, it is queued for redelivery.
+func (a *ackValve) lease(msg held) (*lease, error) {
+	r, err := msg.open()
+	if err != nil {
+		a.ctrl.redo = append(a.ctrl.redo, msg)
+		return nil, err
+	}
+	a.ctrl.numLeased++
+	l := &lease{ReadCloser: r, v: a, msg: msg}
+	l.Lock()
+	defer l.Unlock()
+	l.timer = time.AfterFunc(a.visibility, l.Release)
+	return l, nil
+}
+
+// settle ends a lease, queueing its message for redelivery unless it was acknowledged.
+func (a *ackValve) settle(redo held) {
+	a.ctrl.Lock()
+	defer a.ctrl.Unlock()
+	a.ctrl.numLeased--
+	if redo != nil {
+		a.ctrl.redo = append(a.ctrl.redo, redo)
+	} else {
+		a.ctrl.numAck++
+	}
+	close(a.ctrl.wake)
+	a.ctrl.wake = make(chan struct{})
+}
+
+// Recv receives a message and acknowledges it right away.
+func (a *ackValve) Recv() (io.ReadCloser, error) {
+	l, err := a.Lease()
+	if err != nil {
+		return nil, err
+	}
+	l.Ack()
+	return l, nil
+}
+
+func (a *ackValve) Scrub() {
+	a.ctrl.Lock()
+	if !a.ctrl.aborted {
+		a.ctrl.aborted = true
+		close(a.ctrl.abr)
+	}
+	a.ctrl.Unlock()
+	a.Valve.Scrub()
+}
+
+func (a *ackValve) Stat() Stat {
+	s := a.Valve.Stat()
+	a.ctrl.Lock()
+	defer a.ctrl.Unlock()
+	s.Ack = true
+	s.NumLeased = a.ctrl.numLeased
+	s.NumAck = a.ctrl.numAck
+	s.NumRedeliver = a.ctrl.numRedeliver
+	return s
+}
+
+// lease implements Lease.
+// A lease that expires, or is released, closes its reader, which fails any later reads.
+type lease struct {
+	io.ReadCloser
+	v     *ackValve
+	msg   held
+	timer *time.Timer
+	sync.Mutex
+	done   bool // set once the lease is acknowledged or released
+	closed bool // set once the reader is closed
+}
+
+func (l *lease) Read(p []byte) (int, error) {
+	l.Lock()
+	defer l.Unlock()
+	if l.closed {
+		return 0, errors.New("lease no longer held")
+	}
+	return l.ReadCloser.Read(p)
+}
+
+func (l *lease) Close() error {
+	l.Lock()
+	defer l.Unlock()
+	return l.close()
+}
+
+// close closes the reader of the lease, unless already closed. The lease lock must be held.
+func (l *lease) close() error {
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	return l.ReadCloser.Close()
+}
+
+func (l *lease) Ack() error {
+	l.Lock()
+	defer l.Unlock()
+	if l.done {
+		return errors.New("lease no longer held")
+	}
+	l.done = true
+	l.timer.Stop()
+	l.v.settle(nil)
+	return l.msg.ack()
+}
+
+func (l *lease) Release() {
+	l.Lock()
+	defer l.Unlock()
+	if l.done {
+		return
+	}
+	l.done = true
+	l.timer.Stop()
+	l.close()
+	l.v.settle(l.msg)
+}
@@ -0,0 +1,139 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package valve
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAckValve(t *testing.T) {
+	v, err := Make("", Spec{Cap: 2, Ack: true, Visibility: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	send(t, v, "job")
+	v.Close()
+	l, err := v.Lease()
+	if err != nil {
+		t.Fatalf("lease (%s)", err)
+	}
+	time.Sleep(100 * time.Millisecond) // let the lease expire
+	if err = l.Ack(); err == nil {
+		t.Fatalf("ack of expired lease should fail")
+	}
+	if _, err = ioutil.ReadAll(l); err == nil {
+		t.Fatalf("read of expired lease should fail")
+	}
+	if l, err = v.Lease(); err != nil {
+		t.Fatalf("redelivery (%s)", err)
+	}
+	if b, _ := ioutil.ReadAll(l); string(b) != "job" {
+		t.Fatalf("redelivered %q", b)
+	}
+	if err = l.Ack(); err != nil {
+		t.Fatalf("ack (%s)", err)
+	}
+	if _, err = v.Lease(); err == nil {
+		t.Fatalf("expecting closed channel")
+	}
+	if s := v.Stat(); s.NumAck != 1 || s.NumRedeliver != 1 || s.NumLeased != 0 {
+		t.Fatalf("unexpected stat %v", s)
+	}
+}
+
+func TestDurableAckValve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "valve")
+	if err != nil {
+		t.Fatalf("tempdir (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+	if err = Init(dir); err != nil {
+		t.Fatalf("init (%s)", err)
+	}
+	v, err := Make("a", Spec{Cap: 3, Durable: true, Ack: true, Visibility: time.Minute})
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	for i := 0; i < 3; i++ {
+		send(t, v, fmt.Sprintf("msg%d", i))
+	}
+	var ll []Lease
+	for i := 0; i < 3; i++ {
+		l, err := v.Lease()
+		if err != nil {
+			t.Fatalf("lease (%s)", err)
+		}
+		ll = append(ll, l)
+	}
+	// acknowledge msg0 and msg2; msg1 is still leased when the server crashes
+	if err = ll[0].Ack(); err != nil {
+		t.Fatalf("ack (%s)", err)
+	}
+	if err = ll[2].Ack(); err != nil {
+		t.Fatalf("ack (%s)", err)
+	}
+	v.(*ackValve).Valve.(*durableValve).ctrl.log.Close() // simulate a crash
+
+	if v, err = OpenDurableValve("a"); err != nil {
+		t.Fatalf("reopen (%s)", err)
+	}
+	v.Close()
+	// msg2 was acknowledged out of order, so it is delivered again along with msg1
+	for _, want := range []string{"msg1", "msg2"} {
+		l, err := v.Lease()
+		if err != nil {
+			t.Fatalf("lease after restart (%s)", err)
+		}
+		if b, _ := ioutil.ReadAll(l); string(b) != want {
+			t.Fatalf("expecting %s, got %q", want, b)
+		}
+		l.Close()
+		if err = l.Ack(); err != nil {
+			t.Fatalf("ack (%s)", err)
+		}
+	}
+	if _, err = v.Lease(); err == nil {
+		t.Fatalf("expecting closed channel")
+	}
+	if n := v.(*ackValve).Valve.(*durableValve).ctrl.log.Len(); n != 0 {
+		t.Fatalf("expecting an empty log, got %d records", n)
+	}
+	v.Scrub()
+}
+
+func TestAckMaxBytes(t *testing.T) {
+	// the underlying channel does not enforce the limit, so that the bound of the pump is exercised
+	v := withAck(makeValve(Spec{Cap: 2}), Spec{MaxBytes: 4})
+	sent := make(chan error, 1)
+	go func() {
+		defer v.Close()
+		for _, msg := range []string{"too long", "ok"} {
+			w, err := v.Send()
+			if err != nil {
+				sent <- err
+				return
+			}
+			w.Write([]byte(msg))
+			if err = w.Close(); err != nil {
+				sent <- err
+				return
+			}
+		}
+		sent <- nil
+	}()
+	if m := recv(t, v); m != "ok" {
+		t.Fatalf("expecting the oversized message to be discarded, got %q", m)
+	}
+	if err := <-sent; err != nil {
+		t.Fatalf("send (%s)", err)
+	}
+}
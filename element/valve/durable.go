@@ -0,0 +1,439 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package valve
+
+import (
+	"encoding/json"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gocircuit/circuit/use/circuit"
+)
+
+// Durable channels keep their buffered messages in segment logs under this directory.
+var durableDir string
+
+const (
+	metaName = "meta"
+	metaTmp  = "meta.tmp"
+	spoolDir = "spool"
+)
+
+// Init enables durable channels on this server, storing their buffers under dir.
+// Durable channels found in dir survive the restart of a server that reuses dir.
+func Init(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	durableDir = dir
+	return nil
+}
+
+// meta is the on-disk description of a durable channel.
+type meta struct {
+	Anchor      string        `json:"anchor"` // anchor path, relative to the hosting server
+	Cap         int           `json:"cap"`
+	Ack         bool          `json:"ack"`
+	Visibility  time.Duration `json:"visibility"`
+	MaxBytes    int64         `json:"maxbytes"`
+	MaxDuration time.Duration `json:"maxduration"`
+	Closed      bool          `json:"closed"`
+}
+
+// ListDurable returns the relative anchor paths of the durable channels stored on this server.
+func ListDurable() (r []string) {
+	if durableDir == "" {
+		return nil
+	}
+	fis, err := ioutil.ReadDir(durableDir)
+	if err != nil {
+		return nil
+	}
+	for _, fi := range fis {
+		if name, err := url.PathUnescape(fi.Name()); err == nil && fi.IsDir() {
+			r = append(r, name)
+		}
+	}
+	return r
+}
+
+func durablePath(name string) string {
+	return path.Join(durableDir, url.PathEscape(name))
+}
+
+// durableValve is a channel whose buffer is a segment log on disk.
+// Messages are spooled to a file while being sent, and are appended to the log when
+// the sender closes its WriteCloser.
+type durableValve struct {
+	dir   string
+	meter *meter
+	ctrl  struct {
+		sync.Mutex
+		log      *segLog
+		meta     meta
+		wake     chan struct{} // closed and replaced whenever the state of the valve changes
+		abr      chan struct{} // closed on abort
+		reserved int           // sends in progress, counted against capacity
+		stat     Stat
+	}
+}
+
+// MakeDurableValve creates a durable channel, identified by the anchor path name relative to
+// its hosting server, with a buffer capacity of spec.Cap messages.
+func MakeDurableValve(name string, spec Spec) (Valve, error) {
+	if durableDir == "" {
+		return nil, errors.New("durable channels not enabled on this server")
+	}
+	if spec.Cap <= 0 {
+		return nil, errors.New("durable channels must have positive capacity")
+	}
+	dir := durablePath(name)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, errors.New("durable channel already exists on disk")
+	}
+	v, err := openDurableValve(dir, meta{
+		Anchor:      name,
+		Cap:         spec.Cap,
+		Ack:         spec.Ack,
+		Visibility:  spec.Visibility,
+		MaxBytes:    spec.MaxBytes,
+		MaxDuration: spec.MaxDuration,
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return v, nil
+}
+
+// OpenDurableValve reopens the durable channel stored for the anchor path name.
+func OpenDurableValve(name string) (Valve, error) {
+	dir := durablePath(name)
+	b, err := ioutil.ReadFile(path.Join(dir, metaName))
+	if err != nil {
+		return nil, err
+	}
+	var m meta
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	v, err := openDurableValve(dir, m)
+	if err != nil {
+		return nil, err
+	}
+	if m.Ack {
+		return withAck(v, Spec{Visibility: m.Visibility, MaxBytes: m.MaxBytes}), nil
+	}
+	return v, nil
+}
+
+func openDurableValve(dir string, m meta) (v *durableValve, err error) {
+	v = &durableValve{dir: dir, meter: newMeter(Spec{MaxBytes: m.MaxBytes, MaxDuration: m.MaxDuration})}
+	if v.ctrl.log, err = openSegLog(dir); err != nil {
+		return nil, err
+	}
+	os.RemoveAll(path.Join(dir, spoolDir)) // sends that were in progress at crash time are lost
+	if err = os.MkdirAll(path.Join(dir, spoolDir), 0700); err != nil {
+		v.ctrl.log.Close()
+		return nil, err
+	}
+	v.ctrl.meta = m
+	if err = v.writeMeta(); err != nil {
+		v.ctrl.log.Close()
+		return nil, err
+	}
+	v.ctrl.wake, v.ctrl.abr = make(chan struct{}), make(chan struct{})
+	v.ctrl.stat = Stat{
+		Cap:     m.Cap,
+		Opened:  true,
+		Closed:  m.Closed,
+		Durable: true,
+	}
+	return v, nil
+}
+
+func (v *durableValve) writeMeta() error {
+	b, err := json.Marshal(v.ctrl.meta)
+	if err != nil {
+		panic(err)
+	}
+	return writeFileSync(path.Join(v.dir, metaTmp), path.Join(v.dir, metaName), b)
+}
+
+// kick wakes up all goroutines waiting on a state change. The control lock must be held.
+func (v *durableValve) kick() {
+	close(v.ctrl.wake)
+	v.ctrl.wake = make(chan struct{})
+}
+
+// wait releases the control lock until the state of the valve changes.
+// It returns false if the valve has been aborted.
+func (v *durableValve) wait() bool {
+	wake, abr := v.ctrl.wake, v.ctrl.abr
+	v.ctrl.Unlock()
+	defer v.ctrl.Lock()
+	select {
+	case <-wake:
+		return true
+	case <-abr:
+		return false
+	}
+}
+
+// Send blocks until there is room in the buffer for one more message.
+// The message is committed to disk when the returned WriteCloser is closed.
+func (v *durableValve) Send() (io.WriteCloser, error) {
+	v.ctrl.Lock()
+	defer v.ctrl.Unlock()
+	for {
+		switch {
+		case v.ctrl.stat.Aborted:
+			return nil, errors.New("channel aborted")
+		case v.ctrl.stat.Closed:
+			return nil, errors.New("channel closed")
+		case v.ctrl.log.Len()+v.ctrl.reserved < v.ctrl.meta.Cap:
+			f, err := ioutil.TempFile(path.Join(v.dir, spoolDir), "")
+			if err != nil {
+				return nil, err
+			}
+			v.ctrl.reserved++
+			w := &spoolWriter{v: v, f: f, sum: crc32.NewIEEE(), sent: time.Now()}
+			runtime.SetFinalizer(w, func(w2 *spoolWriter) { w2.abandon() })
+			return v.meter.sender(w, &message{sent: w.sent}, w.abandon), nil
+		}
+		if !v.wait() {
+			return nil, errors.New("channel aborted")
+		}
+	}
+}
+
+// commit appends the spooled message to the log.
+func (v *durableValve) commit(f *os.File, size int64, sum uint32, sent time.Time) error {
+	if _, err := f.Seek(0, 0); err != nil {
+		v.release()
+		return err
+	}
+	v.ctrl.Lock()
+	defer v.ctrl.Unlock()
+	v.ctrl.reserved--
+	defer v.kick()
+	if v.ctrl.stat.Aborted {
+		return errors.New("channel aborted")
+	}
+	if err := v.ctrl.log.Append(f, size, sum, sent); err != nil {
+		return err
+	}
+	v.ctrl.stat.NumSend++
+	return nil
+}
+
+// release returns the capacity reserved by an abandoned send.
+func (v *durableValve) release() {
+	v.ctrl.Lock()
+	defer v.ctrl.Unlock()
+	v.ctrl.reserved--
+	v.kick()
+}
+
+func (v *durableValve) Recv() (io.ReadCloser, error) {
+	v.ctrl.Lock()
+	defer v.ctrl.Unlock()
+	for {
+		switch {
+		case v.ctrl.stat.Aborted:
+			return nil, errors.New("channel aborted")
+		case v.ctrl.log.Len() > 0:
+			r, sent, err := v.ctrl.log.Take()
+			if err != nil {
+				return nil, err
+			}
+			v.ctrl.stat.NumRecv++
+			v.kick()
+			return v.meter.receiver(&message{ReadCloser: r, sent: sent}), nil
+		case v.ctrl.stat.Closed && v.ctrl.reserved == 0:
+			return nil, errors.New("channel closed")
+		}
+		if !v.wait() {
+			return nil, errors.New("channel aborted")
+		}
+	}
+}
+
+// next hands out the next message of the log to an acknowledgement-mode wrapper.
+// The message remains in the log until it is acknowledged.
+func (v *durableValve) next() (held, error) {
+	v.ctrl.Lock()
+	defer v.ctrl.Unlock()
+	for {
+		switch {
+		case v.ctrl.stat.Aborted:
+			return nil, errors.New("channel aborted")
+		case v.ctrl.log.Unread() > 0:
+			at, sent, err := v.ctrl.log.Next()
+			if err != nil {
+				return nil, err
+			}
+			v.ctrl.stat.NumRecv++
+			return &logHeld{v: v, at: at, sent: sent}, nil
+		case v.ctrl.stat.Closed && v.ctrl.reserved == 0:
+			return nil, errors.New("channel closed")
+		}
+		if !v.wait() {
+			return nil, errors.New("channel aborted")
+		}
+	}
+}
+
+// logHeld is a message of a durable channel in acknowledgement mode, identified by its position in the log.
+type logHeld struct {
+	v    *durableValve
+	at   pos
+	sent time.Time
+}
+
+func (h *logHeld) open() (io.ReadCloser, error) {
+	h.v.ctrl.Lock()
+	defer h.v.ctrl.Unlock()
+	if h.v.ctrl.stat.Aborted {
+		return nil, errors.New("channel aborted")
+	}
+	r, err := h.v.ctrl.log.Open(h.at)
+	if err != nil {
+		return nil, err
+	}
+	return h.v.meter.receiver(&message{ReadCloser: r, sent: h.sent}), nil
+}
+
+// ack removes the message from the log, making room for another one.
+func (h *logHeld) ack() error {
+	h.v.ctrl.Lock()
+	defer h.v.ctrl.Unlock()
+	if h.v.ctrl.stat.Aborted {
+		return errors.New("channel aborted")
+	}
+	defer h.v.kick()
+	return h.v.ctrl.log.Ack(h.at)
+}
+
+func (v *durableValve) Lease() (Lease, error) {
+	return nil, errors.New("channel not in acknowledgement mode")
+}
+
+// Close closes the channel. The closure is recorded on disk.
+func (v *durableValve) Close() error {
+	v.ctrl.Lock()
+	defer v.ctrl.Unlock()
+	if v.ctrl.stat.Closed {
+		return errors.New("channel already closed")
+	}
+	v.ctrl.meta.Closed = true
+	if err := v.writeMeta(); err != nil {
+		v.ctrl.meta.Closed = false
+		return err
+	}
+	v.ctrl.stat.Closed = true
+	v.kick()
+	return nil
+}
+
+func (v *durableValve) IsDone() bool {
+	v.ctrl.Lock()
+	defer v.ctrl.Unlock()
+	return v.ctrl.stat.Aborted
+}
+
+// Scrub aborts the channel and removes its log from disk.
+func (v *durableValve) Scrub() {
+	v.ctrl.Lock()
+	defer v.ctrl.Unlock()
+	if v.ctrl.stat.Aborted {
+		return
+	}
+	close(v.ctrl.abr)
+	v.ctrl.stat.Aborted = true
+	v.ctrl.log.Close()
+	os.RemoveAll(v.dir)
+}
+
+func (v *durableValve) Cap() int {
+	v.ctrl.Lock()
+	defer v.ctrl.Unlock()
+	return v.ctrl.meta.Cap
+}
+
+func (v *durableValve) Stat() Stat {
+	v.ctrl.Lock()
+	s := v.ctrl.stat
+	v.ctrl.Unlock()
+	v.meter.stat(&s)
+	return s
+}
+
+func (v *durableValve) X() circuit.X {
+	return circuit.Ref(XValve{v})
+}
+
+// spoolWriter accumulates a message in a spool file until it is closed.
+type spoolWriter struct {
+	v *durableValve
+	sync.Mutex
+	f    *os.File
+	sum  hash.Hash32
+	size int64
+	sent time.Time
+}
+
+func (w *spoolWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.f == nil {
+		return 0, errors.New("message closed")
+	}
+	n, err := w.f.Write(p)
+	w.sum.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+// Close commits the message to the channel's log and returns after it has been synced to disk.
+func (w *spoolWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.f == nil {
+		return errors.New("message already closed")
+	}
+	f := w.f
+	w.f = nil
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	return w.v.commit(f, w.size, w.sum.Sum32(), w.sent)
+}
+
+// abandon discards a message whose writer was never closed.
+func (w *spoolWriter) abandon() {
+	w.Lock()
+	defer w.Unlock()
+	if w.f == nil {
+		return
+	}
+	w.f.Close()
+	os.Remove(w.f.Name())
+	w.f = nil
+	w.v.release()
+}
@@ -0,0 +1,93 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package valve
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func send(t *testing.T, v Valve, msg string) {
+	w, err := v.Send()
+	if err != nil {
+		t.Fatalf("send (%s)", err)
+	}
+	if _, err = w.Write([]byte(msg)); err != nil {
+		t.Fatalf("write (%s)", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("commit (%s)", err)
+	}
+}
+
+func recv(t *testing.T, v Valve) string {
+	r, err := v.Recv()
+	if err != nil {
+		t.Fatalf("recv (%s)", err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read (%s)", err)
+	}
+	return string(b)
+}
+
+func TestDurableValve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "valve")
+	if err != nil {
+		t.Fatalf("tempdir (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+	if err = Init(dir); err != nil {
+		t.Fatalf("init (%s)", err)
+	}
+	v, err := MakeDurableValve("a/b", Spec{Cap: 10, Durable: true})
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	for i := 0; i < 5; i++ {
+		send(t, v, fmt.Sprintf("msg%d", i))
+	}
+	if m := recv(t, v); m != "msg0" {
+		t.Fatalf("expecting msg0, got %q", m)
+	}
+	v.(*durableValve).ctrl.log.Close() // simulate a crash
+
+	// a torn record at the end of the log must be discarded
+	f, err := os.OpenFile(path.Join(durablePath("a/b"), segName(0)), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("open segment (%s)", err)
+	}
+	f.Write([]byte{0, 0, 0, 0, 0, 0, 0, 100, 1, 2})
+	f.Close()
+
+	if l := ListDurable(); len(l) != 1 || l[0] != "a/b" {
+		t.Fatalf("unexpected durable list %v", l)
+	}
+	if v, err = OpenDurableValve("a/b"); err != nil {
+		t.Fatalf("reopen (%s)", err)
+	}
+	send(t, v, "msg5")
+	v.Close()
+	for i := 1; i < 6; i++ {
+		if m := recv(t, v); m != fmt.Sprintf("msg%d", i) {
+			t.Fatalf("expecting msg%d, got %q", i, m)
+		}
+	}
+	if _, err = v.Recv(); err == nil {
+		t.Fatalf("expecting closed channel")
+	}
+	v.Scrub()
+	if l := ListDurable(); len(l) != 0 {
+		t.Fatalf("scrubbed channel still on disk")
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package valve
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gocircuit/circuit/kit/stat"
+)
+
+var (
+	ErrTooLarge = errors.New("message exceeds channel size limit")
+	ErrTooLong  = errors.New("message exceeds channel duration limit")
+)
+
+// message is a channel message on its way from a sender to a receiver.
+type message struct {
+	io.ReadCloser           // receiving end of the message
+	sent          time.Time // time of the Send invocation
+	sync.Mutex
+	err error // set if the sender violated a limit
+}
+
+func (m *message) fail(err error) bool {
+	m.Lock()
+	defer m.Unlock()
+	if m.err != nil {
+		return false
+	}
+	m.err = err
+	return true
+}
+
+func (m *message) failure() error {
+	m.Lock()
+	defer m.Unlock()
+	return m.err
+}
+
+// Latencies are accounted for in a histogram of their base-10 logarithm, in seconds,
+// spanning from 100µs to 1000s.
+const (
+	latencyLogMin  = -4
+	latencyLogMax  = 3
+	latencyLogBins = 140
+)
+
+// meter enforces the message limits of a channel and accounts for its traffic.
+type meter struct {
+	maxBytes    int64
+	maxDuration time.Duration
+	sync.Mutex
+	bytesSent   int64
+	bytesRecv   int64
+	inFlight    int
+	numRejected int
+	latency     *stat.Histogram
+}
+
+func newMeter(spec Spec) *meter {
+	return &meter{
+		maxBytes:    spec.MaxBytes,
+		maxDuration: spec.MaxDuration,
+		latency:     stat.NewHistogram(latencyLogMin, latencyLogMax, latencyLogBins),
+	}
+}
+
+// sender wraps the sending end w of msg. Upon violation of a limit, abort is invoked to
+// discard the message, and the violation is reported to both sender and receiver.
+func (m *meter) sender(w io.WriteCloser, msg *message, abort func()) io.WriteCloser {
+	m.Lock()
+	m.inFlight++
+	m.Unlock()
+	mw := &meterWriter{m: m, msg: msg, w: w, abort: abort}
+	if m.maxDuration > 0 {
+		mw.timer = time.AfterFunc(m.maxDuration-time.Since(msg.sent), func() {
+			mw.reject(ErrTooLong)
+		})
+	}
+	return mw
+}
+
+// receiver wraps a received message.
+func (m *meter) receiver(msg *message) io.ReadCloser {
+	return &meterReader{m: m, msg: msg}
+}
+
+func (m *meter) stat(s *Stat) {
+	m.Lock()
+	defer m.Unlock()
+	s.MaxBytes, s.MaxDuration = m.maxBytes, m.maxDuration
+	s.BytesSent, s.BytesRecv = m.bytesSent, m.bytesRecv
+	s.InFlight, s.NumRejected = m.inFlight, m.numRejected
+	s.LatencyP50 = m.quantile(0.5)
+	s.LatencyP90 = m.quantile(0.9)
+	s.LatencyP99 = m.quantile(0.99)
+}
+
+func (m *meter) quantile(q float64) time.Duration {
+	x := m.latency.Quantile(q)
+	if math.IsNaN(x) {
+		return 0
+	}
+	return time.Duration(math.Pow(10, x) * float64(time.Second))
+}
+
+// meterWriter is the sending end of a metered message.
+type meterWriter struct {
+	m     *meter
+	msg   *message
+	w     io.WriteCloser
+	abort func()
+	timer *time.Timer
+	sync.Mutex
+	n    int64
+	done bool
+}
+
+func (mw *meterWriter) Write(p []byte) (int, error) {
+	if err := mw.msg.failure(); err != nil {
+		return 0, err
+	}
+	mw.Lock()
+	defer mw.Unlock()
+	if mw.m.maxBytes > 0 && mw.n+int64(len(p)) > mw.m.maxBytes {
+		mw.reject(ErrTooLarge)
+		return 0, ErrTooLarge
+	}
+	n, err := mw.w.Write(p)
+	mw.n += int64(n)
+	mw.m.Lock()
+	mw.m.bytesSent += int64(n)
+	mw.m.Unlock()
+	if e := mw.msg.failure(); e != nil {
+		err = e
+	}
+	return n, err
+}
+
+// reject discards the message because of a limit violation.
+// It does not acquire the writer lock, so that it can interrupt a pending write.
+func (mw *meterWriter) reject(err error) {
+	if !mw.msg.fail(err) {
+		return
+	}
+	mw.abort()
+	mw.finish(true)
+}
+
+// finish accounts for the end of the transmission, once.
+func (mw *meterWriter) finish(rejected bool) {
+	mw.m.Lock()
+	defer mw.m.Unlock()
+	if mw.done {
+		return
+	}
+	mw.done = true
+	mw.m.inFlight--
+	if rejected {
+		mw.m.numRejected++
+	}
+}
+
+func (mw *meterWriter) Close() error {
+	if mw.timer != nil {
+		mw.timer.Stop()
+	}
+	if err := mw.msg.failure(); err != nil {
+		return err
+	}
+	err := mw.w.Close()
+	mw.finish(false)
+	return err
+}
+
+// meterReader is the receiving end of a metered message.
+type meterReader struct {
+	m   *meter
+	msg *message
+	eof bool
+}
+
+func (mr *meterReader) Read(p []byte) (n int, err error) {
+	n, err = mr.msg.Read(p)
+	mr.m.Lock()
+	defer mr.m.Unlock()
+	mr.m.bytesRecv += int64(n)
+	if err != io.EOF || mr.eof {
+		return n, err
+	}
+	if e := mr.msg.failure(); e != nil {
+		return n, e
+	}
+	mr.eof = true
+	if d := time.Since(mr.msg.sent); d > 0 {
+		mr.m.latency.Put(math.Log10(d.Seconds()), 1)
+	}
+	return n, err
+}
+
+func (mr *meterReader) Close() error {
+	return mr.msg.Close()
+}
@@ -0,0 +1,49 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package valve
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestMeter(t *testing.T) {
+	v, err := Make("", Spec{Cap: 3, MaxBytes: 4, MaxDuration: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("make (%s)", err)
+	}
+	send(t, v, "ok")
+	// oversized message
+	w, _ := v.Send()
+	if _, err = w.Write([]byte("hello")); err != ErrTooLarge {
+		t.Fatalf("expecting size violation, got %v", err)
+	}
+	if err = w.Close(); err != ErrTooLarge {
+		t.Fatalf("expecting size violation on close, got %v", err)
+	}
+	// overlong message
+	w, _ = v.Send()
+	time.Sleep(100 * time.Millisecond)
+	if err = w.Close(); err != ErrTooLong {
+		t.Fatalf("expecting duration violation, got %v", err)
+	}
+	if m := recv(t, v); m != "ok" {
+		t.Fatalf("received %q", m)
+	}
+	for i := 0; i < 2; i++ {
+		r, _ := v.Recv()
+		if _, err = ioutil.ReadAll(r); err == nil {
+			t.Fatalf("rejected message delivered without error")
+		}
+	}
+	s := v.Stat()
+	if s.BytesSent != 2 || s.BytesRecv != 2 || s.InFlight != 0 || s.NumRejected != 2 || s.LatencyP50 <= 0 {
+		t.Fatalf("unexpected stat %v", s.String())
+	}
+}
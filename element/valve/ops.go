@@ -10,6 +10,7 @@ package valve
 import (
 	"errors"
 	"io"
+	"time"
 
 	"github.com/gocircuit/circuit/kit/interruptible"
 )
@@ -22,11 +23,12 @@ func (v *valve) Send() (io.WriteCloser, error) {
 	if v.send.tun == nil {
 		return nil, errors.New("channel closed")
 	}
-	r, w := interruptible.BufferPipe(32e3)
+	r, w := interruptible.BufferPipe(MessageCap)
+	msg := &message{ReadCloser: r, sent: time.Now()}
 	select {
-	case v.send.tun <- r:
+	case v.send.tun <- msg:
 		v.incSend()
-		return w, nil
+		return v.meter.sender(w, msg, func() { w.Close() }), nil
 	case <-v.send.abr:
 		return nil, errors.New("channel aborted")
 	}
@@ -76,8 +78,12 @@ func (v *valve) Recv() (io.ReadCloser, error) {
 			return nil, errors.New("channel closed")
 		}
 		v.incRecv()
-		return g.(interruptible.Reader), nil
+		return v.meter.receiver(g.(*message)), nil
 	case <-v.recv.abr:
 		return nil, errors.New("channel aborted")
 	}
 }
+
+func (v *valve) Lease() (Lease, error) {
+	return nil, errors.New("channel not in acknowledgement mode")
+}
@@ -0,0 +1,451 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package valve
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SegmentSize is the size beyond which the active segment of a log is sealed and a new one is started.
+const SegmentSize = 64 << 20 // 64M
+
+const (
+	headerLen = 20 // 8-byte payload length, 4-byte CRC32 of the payload, 8-byte send time in ns
+	segSuffix = ".seg"
+	headName  = "head"
+	headTmp   = "head.tmp"
+)
+
+// pos is a position within a segment log.
+type pos struct {
+	Seg int64 `json:"seg"`
+	Off int64 `json:"off"`
+}
+
+// segLog is an append-only log of messages, split across numbered segment files.
+// The head file records the position of the oldest unconsumed message.
+// segLog is not synchronized; its user holds a lock.
+//
+// Records are consumed either by Take, or, in acknowledgement mode, by Next followed by Ack.
+// Records handed out by Next remain in the log until they are acknowledged, and the head
+// advances only past acknowledged records; upon reopening, unacknowledged records are handed out again.
+type segLog struct {
+	dir  string
+	head pos         // position of the first unconsumed record
+	next pos         // position of the first record not handed out by Next
+	tail pos         // position past the last committed record
+	file *os.File    // active segment, the one containing tail
+	n    int         // number of records between head and tail
+	out  []outRecord // records handed out by Next and not yet consumed, in log order
+}
+
+// outRecord is a record handed out by Next.
+type outRecord struct {
+	at    pos
+	size  int64
+	acked bool
+}
+
+func segName(seg int64) string {
+	return fmt.Sprintf("%016d%s", seg, segSuffix)
+}
+
+// openSegLog opens the segment log in dir, creating it if necessary.
+// Segments preceding the head are removed, and a torn record at the end of the log is truncated.
+func openSegLog(dir string) (l *segLog, err error) {
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	l = &segLog{dir: dir}
+	if err = l.readHead(); err != nil {
+		return nil, err
+	}
+	segs, err := l.segments()
+	if err != nil {
+		return nil, err
+	}
+	var live []int64
+	for _, seg := range segs {
+		if seg < l.head.Seg {
+			os.Remove(l.segPath(seg))
+			continue
+		}
+		live = append(live, seg)
+	}
+	if len(live) == 0 {
+		l.head.Off = 0
+		live = []int64{l.head.Seg}
+		if err = ioutil.WriteFile(l.segPath(l.head.Seg), nil, 0600); err != nil {
+			return nil, err
+		}
+	}
+	for i, seg := range live {
+		if seg != live[0]+int64(i) {
+			return nil, fmt.Errorf("segment %d missing from log %s", live[0]+int64(i), dir)
+		}
+		var from int64
+		if seg == l.head.Seg {
+			from = l.head.Off
+		}
+		last := i == len(live)-1
+		n, end, err := scanSegment(l.segPath(seg), from, last)
+		if err != nil {
+			return nil, err
+		}
+		l.n += n
+		if last {
+			l.tail = pos{Seg: seg, Off: end}
+		}
+	}
+	if l.file, err = os.OpenFile(l.segPath(l.tail.Seg), os.O_RDWR, 0600); err != nil {
+		return nil, err
+	}
+	// Discard any torn record that follows the last intact one.
+	if err = l.file.Truncate(l.tail.Off); err != nil {
+		l.file.Close()
+		return nil, err
+	}
+	if _, err = l.file.Seek(l.tail.Off, 0); err != nil {
+		l.file.Close()
+		return nil, err
+	}
+	l.next = l.head
+	return l, nil
+}
+
+// scanSegment counts the records in the segment file name, starting at offset from.
+// It returns the offset past the last intact record. Payload checksums are verified
+// only in the last segment, since sealed segments have been synced in their entirety.
+func scanSegment(name string, from int64, last bool) (n int, end int64, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := fi.Size()
+	end = from
+	var hdr [headerLen]byte
+	for end < size {
+		if _, err = f.ReadAt(hdr[:], end); err != nil {
+			break
+		}
+		m := int64(binary.BigEndian.Uint64(hdr[:8]))
+		if m < 0 || end+headerLen+m > size {
+			break
+		}
+		if last {
+			h := crc32.NewIEEE()
+			if _, err = io.Copy(h, io.NewSectionReader(f, end+headerLen, m)); err != nil {
+				break
+			}
+			if h.Sum32() != binary.BigEndian.Uint32(hdr[8:12]) {
+				break
+			}
+		}
+		end += headerLen + m
+		n++
+	}
+	if end < size && !last {
+		return 0, 0, fmt.Errorf("corrupt sealed segment %s", name)
+	}
+	return n, end, nil
+}
+
+func (l *segLog) segPath(seg int64) string {
+	return path.Join(l.dir, segName(seg))
+}
+
+// segments returns the sorted sequence numbers of the segment files in the log directory.
+func (l *segLog) segments() ([]int64, error) {
+	fis, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []int64
+	for _, fi := range fis {
+		if !strings.HasSuffix(fi.Name(), segSuffix) {
+			continue
+		}
+		seg, err := strconv.ParseInt(strings.TrimSuffix(fi.Name(), segSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, seg)
+	}
+	sort.Sort(int64Slice(segs))
+	return segs, nil
+}
+
+type int64Slice []int64
+
+func (s int64Slice) Len() int           { return len(s) }
+func (s int64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s int64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (l *segLog) readHead() error {
+	b, err := ioutil.ReadFile(path.Join(l.dir, headName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &l.head)
+}
+
+func (l *segLog) writeHead() error {
+	b, err := json.Marshal(l.head)
+	if err != nil {
+		panic(err)
+	}
+	return writeFileSync(path.Join(l.dir, headTmp), path.Join(l.dir, headName), b)
+}
+
+// writeFileSync atomically replaces the file name with contents b, using tmp as a staging file.
+func writeFileSync(tmp, name string, b []byte) error {
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// Len returns the number of unconsumed records in the log.
+func (l *segLog) Len() int {
+	return l.n
+}
+
+// Unread returns the number of records not yet handed out by Next.
+func (l *segLog) Unread() int {
+	return l.n - len(l.out)
+}
+
+// Append writes a record of size bytes, read from r, whose payload checksum is sum.
+// Append returns after the record has been synced to disk.
+// If the active segment has been filled by earlier records, it is sealed first.
+func (l *segLog) Append(r io.Reader, size int64, sum uint32, sent time.Time) (err error) {
+	if l.file == nil {
+		return errors.New("log closed")
+	}
+	if l.tail.Off >= SegmentSize {
+		if err = l.roll(); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if err != nil { // roll back a partial record
+			l.file.Truncate(l.tail.Off)
+			l.file.Seek(l.tail.Off, 0)
+		}
+	}()
+	var hdr [headerLen]byte
+	binary.BigEndian.PutUint64(hdr[:8], uint64(size))
+	binary.BigEndian.PutUint32(hdr[8:12], sum)
+	binary.BigEndian.PutUint64(hdr[12:], uint64(sent.UnixNano()))
+	if _, err = l.file.Write(hdr[:]); err != nil {
+		return err
+	}
+	m, err := io.Copy(l.file, io.LimitReader(r, size))
+	if err != nil {
+		return err
+	}
+	if m != size {
+		return io.ErrUnexpectedEOF
+	}
+	if err = l.file.Sync(); err != nil {
+		return err
+	}
+	l.tail.Off += headerLen + size
+	l.n++
+	return nil
+}
+
+// roll seals the active segment and starts a new one.
+func (l *segLog) roll() error {
+	f, err := os.OpenFile(l.segPath(l.tail.Seg+1), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	l.file.Close()
+	l.file = f
+	l.tail = pos{Seg: l.tail.Seg + 1}
+	return nil
+}
+
+// Take removes the oldest unconsumed record from the log and returns a reader for its payload,
+// as well as its send time. The record is durably marked as consumed before Take returns.
+func (l *segLog) Take() (io.ReadCloser, time.Time, error) {
+	if l.n == 0 {
+		return nil, time.Time{}, errors.New("log empty")
+	}
+	if err := l.skipSealed(); err != nil {
+		return nil, time.Time{}, err
+	}
+	rc, m, sent, err := l.open(l.head)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	l.head.Off += headerLen + m
+	l.n--
+	if err = l.writeHead(); err != nil {
+		l.head.Off -= headerLen + m
+		l.n++
+		rc.Close()
+		return nil, time.Time{}, err
+	}
+	return rc, sent, nil
+}
+
+// Next hands out the oldest record not yet handed out, returning its position and send time.
+// The record remains in the log until it is acknowledged with Ack.
+func (l *segLog) Next() (pos, time.Time, error) {
+	if l.Unread() == 0 {
+		return pos{}, time.Time{}, errors.New("log empty")
+	}
+	if l.next.Seg < l.head.Seg {
+		l.next = l.head
+	}
+	for l.next.Seg < l.tail.Seg { // skip exhausted sealed segments
+		fi, err := os.Stat(l.segPath(l.next.Seg))
+		if err != nil {
+			return pos{}, time.Time{}, err
+		}
+		if l.next.Off < fi.Size() {
+			break
+		}
+		l.next = pos{Seg: l.next.Seg + 1}
+	}
+	rc, m, sent, err := l.open(l.next)
+	if err != nil {
+		return pos{}, time.Time{}, err
+	}
+	rc.Close()
+	at := l.next
+	l.out = append(l.out, outRecord{at: at, size: m})
+	l.next.Off += headerLen + m
+	return at, sent, nil
+}
+
+// Open returns a reader for the payload of the record at, which was handed out by Next.
+func (l *segLog) Open(at pos) (io.ReadCloser, error) {
+	rc, _, _, err := l.open(at)
+	return rc, err
+}
+
+// Ack consumes the record at, which was handed out by Next. The head advances past the
+// acknowledged records that precede all unacknowledged ones, and is durably recorded before Ack returns.
+func (l *segLog) Ack(at pos) error {
+	i := 0
+	for ; i < len(l.out); i++ {
+		if l.out[i].at == at {
+			break
+		}
+	}
+	if i == len(l.out) || l.out[i].acked {
+		return errors.New("record not handed out")
+	}
+	l.out[i].acked = true
+	k := 0
+	for k < len(l.out) && l.out[k].acked {
+		k++
+	}
+	if k == 0 {
+		return nil
+	}
+	last, head := l.out[k-1], l.head
+	l.head = pos{Seg: last.at.Seg, Off: last.at.Off + headerLen + last.size}
+	if err := l.writeHead(); err != nil {
+		l.head, l.out[i].acked = head, false
+		return err
+	}
+	l.out = l.out[k:]
+	l.n -= k
+	return l.skipSealed()
+}
+
+// open returns a reader for the payload of the record at, as well as its size and send time.
+func (l *segLog) open(at pos) (io.ReadCloser, int64, time.Time, error) {
+	f, err := os.Open(l.segPath(at.Seg))
+	if err != nil {
+		return nil, 0, time.Time{}, err
+	}
+	var hdr [headerLen]byte
+	if _, err = f.ReadAt(hdr[:], at.Off); err != nil {
+		f.Close()
+		return nil, 0, time.Time{}, err
+	}
+	m := int64(binary.BigEndian.Uint64(hdr[:8]))
+	sent := time.Unix(0, int64(binary.BigEndian.Uint64(hdr[12:])))
+	return &sectionReadCloser{io.NewSectionReader(f, at.Off+headerLen, m), f}, m, sent, nil
+}
+
+// skipSealed advances the head past exhausted sealed segments, removing them.
+func (l *segLog) skipSealed() error {
+	for l.head.Seg < l.tail.Seg {
+		fi, err := os.Stat(l.segPath(l.head.Seg))
+		if err != nil {
+			return err
+		}
+		if l.head.Off < fi.Size() {
+			return nil
+		}
+		old := l.head.Seg
+		l.head = pos{Seg: old + 1}
+		if err = l.writeHead(); err != nil {
+			return err
+		}
+		os.Remove(l.segPath(old))
+	}
+	return nil
+}
+
+// Close releases the active segment file.
+func (l *segLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+	f *os.File
+}
+
+func (s *sectionReadCloser) Close() error {
+	return s.f.Close()
+}
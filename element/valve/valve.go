@@ -8,9 +8,11 @@
 package valve
 
 import (
+	"encoding/gob"
 	"encoding/json"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/gocircuit/circuit/use/circuit"
 )
@@ -21,6 +23,7 @@ type Valve interface {
 	Scrub()
 	Close() error
 	Recv() (io.ReadCloser, error)
+	Lease() (Lease, error)
 	Cap() int
 	Stat() Stat
 	X() circuit.X
@@ -42,6 +45,7 @@ type valve struct {
 		abr  chan<- struct{}
 		stat Stat
 	}
+	meter *meter
 }
 
 type Stat struct {
@@ -51,6 +55,52 @@ type Stat struct {
 	Aborted bool `json:"aborted"`
 	NumSend int  `json:"numsend"`
 	NumRecv int  `json:"numrecv"`
+	Durable bool `json:"durable"`
+	// Acknowledgement mode
+	Ack          bool `json:"ack"`
+	NumLeased    int  `json:"numleased"`
+	NumAck       int  `json:"numack"`
+	NumRedeliver int  `json:"numredeliver"`
+	// Message limits and accounting
+	MaxBytes    int64         `json:"maxbytes"`
+	MaxDuration time.Duration `json:"maxduration"`
+	BytesSent   int64         `json:"bytessent"`
+	BytesRecv   int64         `json:"bytesrecv"`
+	InFlight    int           `json:"inflight"`
+	NumRejected int           `json:"numrejected"`
+	LatencyP50  time.Duration `json:"latencyp50"`
+	LatencyP90  time.Duration `json:"latencyp90"`
+	LatencyP99  time.Duration `json:"latencyp99"`
+}
+
+func init() {
+	gob.Register(Spec{})
+}
+
+// Spec parameterizes the creation of a channel element.
+type Spec struct {
+	Cap         int           // Buffer capacity, in messages
+	Durable     bool          // Keep buffered messages on disk
+	Ack         bool          // Lease received messages until acknowledged
+	Visibility  time.Duration // Lease duration in acknowledgement mode
+	MaxBytes    int64         // Maximum size of a message in bytes, if positive
+	MaxDuration time.Duration // Maximum duration of a message transmission, if positive
+}
+
+// Make creates a channel element, as described by spec.
+// Durable channels are identified by name, the path of their anchor relative to the hosting server.
+func Make(name string, spec Spec) (v Valve, err error) {
+	if spec.Durable {
+		if v, err = MakeDurableValve(name, spec); err != nil {
+			return nil, err
+		}
+	} else {
+		v = makeValve(spec)
+	}
+	if spec.Ack {
+		v = withAck(v, spec)
+	}
+	return v, nil
 }
 
 // Sender-receiver pipe capacity (once matched)
@@ -65,11 +115,15 @@ func (s *Stat) String() string {
 }
 
 func MakeValve(n int) Valve {
-	v := &valve{}
-	tun, abr := make(chan interface{}, n), make(chan struct{})
+	return makeValve(Spec{Cap: n})
+}
+
+func makeValve(spec Spec) *valve {
+	v := &valve{meter: newMeter(spec)}
+	tun, abr := make(chan interface{}, spec.Cap), make(chan struct{})
 	v.send.tun, v.recv.tun = tun, tun
 	v.ctrl.abr, v.send.abr, v.recv.abr = abr, abr, abr
-	v.ctrl.stat.Opened, v.ctrl.stat.Cap = true, n
+	v.ctrl.stat.Opened, v.ctrl.stat.Cap = true, spec.Cap
 	return v
 }
 
@@ -101,6 +155,8 @@ func (v *valve) Cap() int {
 
 func (v *valve) Stat() Stat {
 	v.ctrl.Lock()
-	defer v.ctrl.Unlock()
-	return v.ctrl.stat
+	s := v.ctrl.stat
+	v.ctrl.Unlock()
+	v.meter.stat(&s)
+	return s
 }
@@ -9,6 +9,7 @@ package valve
 
 import (
 	"io"
+	"runtime"
 
 	xio "github.com/gocircuit/circuit/kit/x/io"
 	"github.com/gocircuit/circuit/use/circuit"
@@ -17,6 +18,7 @@ import (
 
 func init() {
 	circuit.RegisterValue(XValve{})
+	circuit.RegisterValue(&XLease{})
 }
 
 type XValve struct {
@@ -43,6 +45,19 @@ func (x XValve) Recv() (circuit.X, error) {
 	return xio.NewXReadCloser(r), nil
 }
 
+func (x XValve) Lease() (circuit.X, error) {
+	l, err := x.Valve.Lease()
+	if err != nil {
+		return nil, errors.Pack(err)
+	}
+	xl := &XLease{XReader: xio.XReader{Reader: l}, l: l}
+	// If the receiver's worker dies, the lease is released for redelivery.
+	runtime.SetFinalizer(xl, func(xl *XLease) {
+		xl.l.Release()
+	})
+	return circuit.Ref(xl), nil
+}
+
 func (x XValve) Scrub() {
 	x.Valve.Scrub()
 }
@@ -59,6 +74,24 @@ func (x XValve) Stat() Stat {
 	return x.Valve.Stat()
 }
 
+// XLease exports a Lease.
+type XLease struct {
+	xio.XReader
+	l Lease
+}
+
+func (x *XLease) Close() error {
+	return errors.Pack(x.l.Close())
+}
+
+func (x *XLease) Ack() error {
+	return errors.Pack(x.l.Ack())
+}
+
+func (x *XLease) Release() {
+	x.l.Release()
+}
+
 type YValve struct {
 	X circuit.X
 }
@@ -85,6 +118,14 @@ func (y YValve) Recv() (_ io.ReadCloser, err error) {
 	return xio.NewYReadCloser(r[0]), nil
 }
 
+func (y YValve) Lease() (_ Lease, err error) {
+	r := y.X.Call("Lease")
+	if err = errors.Unpack(r[1]); err != nil {
+		return nil, err
+	}
+	return YLease{xio.NewYReadCloser(r[0]), r[0].(circuit.X)}, nil
+}
+
 func (y YValve) Cap() int {
 	return y.X.Call("Cap")[0].(int)
 }
@@ -100,3 +141,17 @@ func (y YValve) Scrub() {
 func (y YValve) IsDone() bool {
 	return y.X.Call("IsDone")[0].(bool)
 }
+
+// YLease…
+type YLease struct {
+	*xio.YReadCloser
+	X circuit.X
+}
+
+func (y YLease) Ack() error {
+	return errors.Unpack(y.X.Call("Ack")[0])
+}
+
+func (y YLease) Release() {
+	y.X.Call("Release")
+}
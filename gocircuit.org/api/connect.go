@@ -53,4 +53,20 @@ DialDiscover(multicast string, authkey []byte) *Client
 <p>The argument <code>multicast</code> must equal the multicast discovery address for the
 circuit cluster.
 
+<p>Servers of named clusters, started with the <code>-cluster</code> option, are discovered with
+<pre>
+DialDiscoverCluster(multicast, cluster string, authkey []byte) *Client
+</pre>
+<p>which ignores the servers of other clusters sharing the multicast address.
+If both a cluster name and a key are given, the discovery message is signed with the key.
+
+<p>Both functions connect to the server whose offer arrives first among those collected within a
+quarter of a second. <code>DialDiscoverSpec</code> allows the window and the choice of server to be set:
+<pre>
+DialDiscoverSpec(spec DiscoverSpec, authkey []byte) *Client
+</pre>
+<p>Servers are chosen by latency, or with <code>By: ByLoad</code>, by their load average per CPU.
+The other servers that responded are kept as candidates. Should the server of the client fail,
+<code>Failover</code> connects the client to the next candidate that responds.
+
         `
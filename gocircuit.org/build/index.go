@@ -54,6 +54,7 @@ the Circuit visit <a href="https://groups.google.com/forum/#!forum/gocircuit-use
 <li><a href="element-container.html">Using containers</a></li>
 <li><a href="element-subscription.html">Using subscriptions</a></li>
 <li><a href="element-dns.html">Using name servers</a></li>
+<li><a href="element-table.html">Using replicated tables</a></li>
 <li><a href="element-server.html">Using servers</a></li>
 <li><a href="element-channel.html">Using channel</a></li>
 </ul>
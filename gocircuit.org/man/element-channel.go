@@ -77,4 +77,57 @@ it for an eventual receiver) but only up to 32K bytes.
 <p>The received message will be produced on the standard output of 
 the command above.
 
+<h2>Durable channels</h2>
+
+<p>A channel created with the <code>--durable</code> option keeps its buffer
+on the disk of its hosting server, rather than in memory:
+
+<pre>
+	circuit mkchan --durable /X88550014d4c82e4d/this/is/delta 1000000
+</pre>
+
+<p>Durable channels must have a positive capacity, which can be in the millions.
+A <code>circuit send</code> to a durable channel returns only after the entire message has been
+written and synced to a segment log in the working directory of the server. If the server
+is restarted with the same <code>--var</code> directory, its durable channels reappear at the
+same anchor paths (under the new server ID) with their buffered messages intact.
+Programmatically, durable channels are created with <code>MakeChanSpec</code>, with the <code>Durable</code> field of
+the <code>ChanSpec</code> set.
+
+<h2>Acknowledgement mode</h2>
+
+<p>A channel created with the <code>--ack</code> option distributes work reliably.
+Each received message is leased to its receiver, who must acknowledge it before the
+channel's visibility timeout expires:
+
+<pre>
+	circuit mkchan --ack --visibility 1m /X88550014d4c82e4d/this/is/jobs 100
+	circuit recv --ack /X88550014d4c82e4d/this/is/jobs
+</pre>
+
+<p>If a lease is not acknowledged in time, or if the runtime of the receiver dies,
+the message is redelivered to another receiver. <code>circuit recv --ack</code> acknowledges
+the message only after it has been written out in full. Durable channels keep leased messages
+in their log until they are acknowledged, and count them against the capacity of the channel,
+so that messages unacknowledged when the server stops are redelivered after it restarts.
+(A message acknowledged while an older one is still leased may be redelivered as well.)
+Non-durable channels hold leased messages in the memory of the hosting server, and
+discard messages larger than their <code>--max-bytes</code> limit, or 16MB if none is given.
+
+<h2>Message limits</h2>
+
+<p>Every message is a byte stream of arbitrary length. To keep misbehaving senders from
+holding receivers indefinitely, a channel can be created with per-message limits, which
+are enforced by its hosting server:
+
+<pre>
+	circuit mkchan --max-bytes 1048576 --max-duration 1m /X88550014d4c82e4d/this/is/echo 10
+</pre>
+
+<p>A message exceeding either limit is aborted. Its sender receives an error, and its
+receiver, if any, sees the message end in an error. Rejected messages are never
+stored in the buffer of a durable channel. <code>circuit peek</code> reports
+the limits, the total bytes sent and received, the number of messages in flight,
+the number of rejected messages and percentiles of the message latency.
+
         `
@@ -26,6 +26,40 @@ with the <code>-docker</code> switch. For instance:
 	circuit start -if eth0 -discover 228.8.8.8:7711 -docker
 </pre>
 
+<p>Servers operate containers through the Docker Engine API, at the address given by
+the <code>-docker-host</code> option or the <code>DOCKER_HOST</code> environment variable,
+which defaults to <code>unix:///var/run/docker.sock</code>. The <code>docker</code> command-line tool
+is not needed. Like the <code>docker</code> tool, servers speak the newest API version supported by both
+the engine and the circuit (currently up to 1.41). Engines older than API version 1.24 (Docker 1.12) are not supported,
+and health check start periods need API version 1.29 (Docker 17.05).
+
+<p>Containers can also be run by other container runtimes, selected with the <code>-runtime</code> option,
+through the same docker elements:
+
+<pre>
+	circuit start -if eth0 -discover 228.8.8.8:7711 -runtime podman
+	circuit start -if eth0 -discover 228.8.8.8:7711 -runtime chroot -rootfs /var/lib/circuit/rootfs
+</pre>
+
+<p>The <code>podman</code> runtime uses the Docker-compatible API of a Podman service, at the address
+given by <code>-docker-host</code>, which defaults to the user's Podman socket, or to
+<code>unix:///run/podman/podman.sock</code> for the superuser.
+
+<p>The <code>chroot</code> runtime is built into the circuit and needs superuser privileges on Linux.
+It runs the command of a container in new mount, PID, UTS and IPC namespaces, chrooted into a root filesystem.
+Its images are the directories (or symbolic links to directories) found in the <code>-rootfs</code> directory,
+and the <code>Image</code> of a container names one of them. Such images are staged by other means,
+for instance by unpacking the output of <code>docker export</code>; they cannot be pulled or removed through the circuit.
+Containers share the network of the host, unless their <code>Network</code> is <code>none</code>.
+The <code>Memory</code>, <code>CpuShares</code>, <code>Volume</code>, <code>Ports</code>, <code>Hostname</code>,
+<code>ReadOnly</code>, <code>CapDrop</code>, <code>Health</code> and <code>Restart</code> options are rejected,
+as are <code>dkr-exec</code> commands, and <code>dkr-stats</code> samples only the first process of the container.
+
+<p>The <code>chroot</code> runtime provides no security isolation. Unless a <code>User</code> is given,
+commands run as root with all capabilities, and a plain chroot does not confine such processes:
+they can escape the root filesystem and act on the host. Neither <code>/proc</code> nor <code>/dev</code>
+is mounted inside the container. Use the chroot runtime only for trusted commands.
+
 <p>To create and execute a new docker container, using the tool:
 
 <pre>
@@ -34,25 +68,73 @@ with the <code>-docker</code> switch. For instance:
 		"Image": "ubuntu",
 		"Memory": 1000000000,
 		"CpuShares": 3,
-		"Lxc": ["lxc.cgroup.cpuset.cpus = 0,1"],
 		"Volume": ["/webapp", "/src/webapp:/opt/webapp:ro"],
 		"Dir": "/",
 		"Entry": "",
 		"Env": ["PATH=/usr/bin"],
 		"Path": "/bin/ls",
 		"Args": ["/"],
+		"Ports": ["8080:80", "127.0.0.1:8443:443/tcp"],
+		"Network": "bridge",
+		"Hostname": "docky",
+		"Labels": {"tier": "front"},
+		"User": "nobody",
+		"ReadOnly": true,
+		"CapDrop": ["NET_RAW"],
+		"Health": {"Cmd": "test -e /", "Interval": "30s", "Timeout": "5s", "Retries": 3},
+		"Restart": "on-failure:5"
 	}
 	EOF
 </pre>
 
 <p>Most of these fields can be omitted analogously to their command-line option counterparts 
-of the <code>docker</code> command-line tool.
+of the <code>docker</code> command-line tool. Volumes of the form <code>host:container</code>
+are bind-mounted from the host. The legacy <code>Lxc</code> options are rejected, as the engine
+does not support them.
+
+<p>Published <code>Ports</code> take the form <code>[[host-ip:]host-port:]container-port[/protocol]</code>;
+a port without a host part is only exposed. <code>Network</code> selects the network the container joins,
+<code>ReadOnly</code> mounts its root filesystem read-only, and <code>CapDrop</code> lists capabilities to drop.
+<code>Health</code> defines a shell command run periodically within the container, whose outcome <code>peek</code>
+reports in the health state of the container. The <code>Restart</code> policy is one of <code>no</code>,
+<code>always</code>, <code>unless-stopped</code>, <code>on-failure</code> or <code>on-failure:<i>max-retries</i></code>.
+A docker element whose container is restarted by the engine is done only when the container stops for good,
+though the standard streams of the element follow only the first run of the container.
+All of these settings are reported by <code>peek</code>, along with the networks and the restart count of the container.
 
 {{.FigMkDkr}}
 
 <p>The remaining docker element commands are identical to those for processes:
 <code>stdin</code>, <code>stdout</code>, <code>stderr</code>, <code>peek</code> and 
 <code>wait</code>. In one exception, <code>peek</code> will return
-a detailed description of the container, derived from <code>docker inspect</code>. 
+a detailed description of the container, derived from <code>docker inspect</code>,
+and <code>wait</code> reports the exit code of the container as observed by the engine.
+Events reported by the engine for a container, such as running out of memory, are delivered
+to event subscriptions as events of kind <code>docker</code>.
+
+<h2>Example: Debug a running container</h2>
+
+<p>Commands can be executed within a running container, without access to its host.
+The standard streams of the command are attached to those of the tool, and the tool exits
+with the exit code of the command:
+
+<pre>
+	circuit dkr-exec /X88550014d4c82e4d/docky ps aux
+	circuit dkr-exec -user root /X88550014d4c82e4d/docky sh -c 'cat /etc/hosts'
+</pre>
+
+<p>Programmatically, <code>Exec</code> returns a handle on the executing command, with standard
+streams of its own, whose <code>Wait</code> reports its exit code.
+
+<p>A sample of the resource usage of a container is printed by:
+
+<pre>
+	circuit dkr-stats /X88550014d4c82e4d/docky
+</pre>
+
+<p>The sample holds the share of CPU time used by the container since the engine's previous sample
+(which exceeds 100 percent when several CPUs are busy), the total CPU time in nanoseconds,
+the memory usage and limit, the bytes received and sent over all network interfaces,
+and the bytes read from and written to block devices.
 
         `
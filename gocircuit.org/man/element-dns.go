@@ -84,4 +84,107 @@ an output similar to this one:
 	}
 </pre>
 
+<h2>How queries are answered</h2>
+
+<p>The nameserver is authoritative for a single zone. Its apex is the owner name of an SOA record,
+if one has been set, and otherwise the longest domain common to all names with records.
+Unless set explicitly, an SOA record and an NS record are synthesized for the apex.
+The serial number of the synthesized SOA record is incremented on every change to the records.
+Both the apex and the serial number are reported by <code>peek</code>.
+
+<p>A query receives all records of the queried name and type. Address records
+are rotated round-robin from one response to the next. If the name only holds a CNAME record,
+the chain is followed within the nameserver's records, and the addresses of the targets of
+SRV, MX and NS answers are included in the additional section.
+Queries for missing names within the zone receive NXDOMAIN, queries for names outside the zone
+receive REFUSED, and unsupported operations, like zone transfers, receive NOTIMP.
+
+<p>The nameserver listens on both UDP and TCP, on the same address. UDP responses that
+exceed the size accepted by the client are truncated, and resolvers retry over TCP.
+
+<h2>Loading and dumping zones</h2>
+
+<p>Records can be imported in bulk from an RFC 1035 zone file:
+
+<pre>
+	circuit dns-load /X88550014d4c82e4d/mydns example.zone
+</pre>
+
+<p>The zone file is parsed by the client, so <code>$INCLUDE</code> directives refer to local files,
+and relative names are completed with the <code>$ORIGIN</code> of the file, or the value of the
+<code>-origin</code> flag. The apex of the loaded zone is the owner of its SOA record, if any, then the origin,
+and otherwise the longest domain common to all of its names. All existing records at or below the apex
+are replaced with those of the file in a single step, so that resolvers never see a partially-loaded zone.
+A zone whose apex would be the root, such as an empty file or one whose names share no domain,
+is refused, unless loaded with <code>-origin .</code>
+Records outside of the apex are left intact. A zone file of <code>-</code> reads the standard input.
+
+<p>Conversely, <code>dns-dump</code> prints all records of a nameserver in zone file format,
+suitable for loading back:
+
+<pre>
+	circuit dns-dump /X88550014d4c82e4d/mydns > example.zone
+</pre>
+
+<h2>Forwarding to upstream resolvers</h2>
+
+<p>A nameserver can also resolve names outside of its records, by forwarding queries
+to one or more upstream resolvers, so that it can be the sole resolver configured
+in containers:
+
+<pre>
+	circuit mkdns -upstream 8.8.8.8 -upstream 8.8.4.4:53 /X88550014d4c82e4d/mydns
+</pre>
+
+<p>Queries for names outside of the nameserver's zone, which would otherwise be refused, are forwarded to the upstream
+resolvers in order, until one responds. Names within the zone are answered by the nameserver alone, even if they do not exist. Responses are cached for the duration of
+their TTLs, up to an hour. The number of forwarded queries and of cache hits is reported by <code>peek</code>.
+
+<h2>Replicated nameservers</h2>
+
+<p>A nameserver lives on a single server, and so resolution through it fails with its server.
+Nameservers made on different servers with the same replica group share their records:
+
+<pre>
+	circuit mkdns -replicate main /X88550014d4c82e4d/mydns
+	circuit mkdns -replicate main /X938fe923bcdef2390/mydns
+</pre>
+
+<p>Records set, unset or loaded through any replica propagate to all replicas of the group,
+over the same gossip system that tracks the membership of the circuit, so any replica can answer queries.
+Each replica applies the changes of a load in a single step, once all of them have arrived. Changes that are
+overtaken in transit by later changes of the same names never arrive, in which case the rest are applied after a second.
+A replica made later starts out with the current records of its group. When the same name
+is changed concurrently through different replicas, the later change prevails, as judged by the clocks of
+the servers making the changes, so these clocks should be kept in sync. The removals of records are remembered
+for an hour, after which a delayed, older change can revive the records. Scrubbing a replica
+leaves the records of its group intact at the remaining replicas. <code>peek</code> reports the replica group.
+
+<h2>Service discovery</h2>
+
+<p>A nameserver can publish the state of the circuit itself, under a dedicated domain called its service zone:
+
+<pre>
+	circuit mkdns -service-zone circuit. /X88550014d4c82e4d/mydns
+</pre>
+
+<p>Every live circuit server is then resolvable as <code>x88550014d4c82e4d.circuit.</code>, and
+every anchor labeled as a service, as in
+
+<pre>
+	circuit label /X88550014d4c82e4d/db1 service=db
+</pre>
+
+<p>is resolvable as <code>db1.db.circuit.</code>. All such names resolve to the IP address of the
+hosting server. Anchors with the same name and service on different servers resolve to all of their
+servers, in round-robin order. A server whose root anchor is labeled <code>alias=<i>name</i></code> is
+additionally published as <code><i>name</i>.circuit.</code>. Only anchors holding an element are published.
+Servers started with an unspecified address, like <code>-a 0.0.0.0:11022</code>, have no address to publish,
+so they and their services are left out of the zone, and the nameserver logs that they are.
+
+<p>The records are updated as servers join and leave the circuit, and as elements are started or scrubbed,
+and labels are set, on any server. They are replaced at once, so that queries never observe a partial update,
+and they carry a short TTL of 10 seconds. Records set with <code>set</code> outside of the service zone are
+not affected.
+
         `
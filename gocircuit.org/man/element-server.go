@@ -54,4 +54,61 @@ to perform networking operations that will result in joining this circuit cluste
 circuit cluster that the target address <code>circuit://127.0.0.1:41222/5650/Q4e16779fe039ecf3</code> is
 a part of. If the target is already a member of this cluster, no change will occur.
 
+<h2>Draining a server</h2>
+
+<p>A server killed with <code>circuit suicide</code> exits at once, and its peers learn of its
+departure only once it fails to announce itself. To take a server out of service gracefully, drain it instead:
+
+<pre>
+	# circuit drain --signal TERM --wait 30s /X88550014d4c82e4d
+</pre>
+
+<p>A draining server stops accepting new elements, sends the given signal to its running processes,
+if any, and waits up to the given duration for them to exit. It then removes its membership record
+from the circuit, so that its peers (and leave subscriptions) learn of its departure immediately,
+and exits. Processes still running when the server exits are left running.
+Programmatically, the same is done by the <code>Drain</code> method of server elements.
+
+<h2>Inspecting the topology</h2>
+
+<p>Circuit servers are connected by an overlay network, in which each server keeps a small
+neighborhood of peers. Membership records, replicated nameservers and tables are gossiped along its links.
+Besides its address, peeking into a server reports its <code>Neighbors</code>, the peers of each of its
+replicated tubes in <code>Folk</code>, by topic (<code>locus</code> for membership), and the revision of every
+record of each tube in <code>Revisions</code>. Two servers whose revisions of the same record disagree for long
+indicate that updates are not getting through.
+
+<p>The topology command gathers the neighborhoods of all servers and prints the overlay as an adjacency list:
+
+<pre>
+	# circuit topology
+	X88550014d4c82e4d	X938fe923bcdef2390
+	X938fe923bcdef2390	X88550014d4c82e4d
+</pre>
+
+<p>With <code>--dot</code>, the overlay is printed in the Graphviz DOT language instead, and with
+<code>--topic locus</code> the peers of the membership tube are printed in place of the neighborhoods.
+Neighbors that are not live servers are noted (and drawn dashed). If the overlay falls apart
+into disconnected components, which cannot exchange updates, the components are listed (or drawn as
+separate clusters) and the command fails. Components can be merged with <code>circuit join</code>.
+
+<h2>Docker images</h2>
+
+<p>On servers started with docker support, server elements also manage the docker images
+present on their host: programmatically, <code>PullImage</code>, <code>Images</code>,
+<code>RemoveImage</code> and <code>InspectImage</code> pull, list, remove and describe images.
+<code>PullImage</code> streams the progress of the pull, one message per line;
+a failed pull ends with a line beginning with <code>error:</code>.
+
+<p>Since a docker element cannot be created on a host that lacks its image, images can be
+staged ahead of time. To pull an image onto one server, or onto every server of the circuit:
+
+<pre>
+	# circuit dkr-pull /X88550014d4c82e4d ubuntu:14.04
+	# circuit dkr-pull --all ubuntu:14.04
+</pre>
+
+<p>With <code>--all</code>, images are pulled on all servers concurrently, progress lines are
+prefixed with the server they come from, and the command fails if any server fails to pull the image.
+
         `
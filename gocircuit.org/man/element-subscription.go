@@ -36,4 +36,71 @@ a circuit server disappears from the system.
 	circuit peek /X88550014d4c82e4d/watch/join
 </pre>
 
+<h2>Example: Listen on element lifecycle events</h2>
+
+<p>The <code>@event</code> subscription delivers the lifecycle events of the element
+stored at another anchor, called the <em>source</em>, which must reside on the same server.
+For instance, the following subscription reports each time the process at <code>/X88550014d4c82e4d/job</code>
+exits or is started again after exiting:
+
+<pre>
+	circuit mk@event /X88550014d4c82e4d/watch/job /X88550014d4c82e4d/job exit restart
+	circuit recv /X88550014d4c82e4d/watch/job
+</pre>
+
+<p>Each message holds the time of the event, the source anchor, the kind of its element,
+the kind of the event and an optional detail, like the exit error of a process.
+The event kinds are
+<code>start</code> and <code>exit</code> for processes and containers,
+<code>restart</code> for a process made at an anchor whose previous process had exited,
+<code>close</code> for channels,
+<code>set</code> and <code>unset</code> for nameserver records,
+<code>load</code> for the replacement of a nameserver zone,
+<code>label</code> for changes to the labels of an anchor,
+<code>docker</code> for the events reported by the Docker engine for a container, like <code>oom</code>,
+<code>partition</code> and <code>merge</code> for the server element at a server's root anchor,
+when the server loses contact with much of the circuit and when it rejoins a lost peer, and
+<code>scrub</code> for the removal of any element.
+If no kinds are given, all events are delivered.
+
+<h2>Filtering and resuming subscriptions</h2>
+
+<p>Join and leave subscriptions can be restricted to servers whose ID begins with a given prefix,
+using the repeatable <code>-prefix</code> flag:
+
+<pre>
+	circuit mk@join -prefix X8855 /X88550014d4c82e4d/watch/join
+</pre>
+
+<p>Every message carries a sequence number, which increases monotonically within
+the event stream of the hosting server. It is reported by <code>peek</code> for the last consumed
+message, and is printed before each message by <code>circuit recv -seq</code>.
+A subscriber that loses its subscription can make a new one which resumes the stream right
+after the last message it saw, using the <code>-resume</code> flag of
+<code>mk@join</code>, <code>mk@leave</code> and <code>mk@event</code>:
+
+<pre>
+	circuit mk@event -resume 1234 /X88550014d4c82e4d/watch/job /X88550014d4c82e4d/job
+</pre>
+
+<h2>Suspected servers</h2>
+
+<p>Servers detect the failure of their peers by the timing of their membership announcements.
+A peer that is late is first <em>suspected</em>, and only declared dead, and reported to
+<code>@leave</code> subscriptions, if it stays silent for much longer. Brief pauses, such as those
+caused by garbage collection or a congested link, thus do not cause leave events.
+Suspected servers are listed by <code>circuit ls</code> with the kind <code>suspect</code>.
+A leave subscription made with the <code>-suspect</code> flag reports servers as soon as they
+become suspected instead:
+
+<pre>
+	circuit mk@leave -suspect /X88550014d4c82e4d/watch/suspect
+</pre>
+
+<p>A suspected server that recovers is not reported again, unless it becomes suspected anew.
+
+<p>Servers retain the most recent 1024 messages of each stream. Resuming from a sequence number
+whose successors are no longer retained fails, as does resuming after a server restart
+from a sequence number it has not reached.
+
         `
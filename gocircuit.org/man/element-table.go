@@ -0,0 +1,92 @@
+package man
+
+import (
+	. "github.com/gocircuit/circuit/gocircuit.org/render"
+)
+
+func RenderElementTablePage() string {
+	return RenderHtml("Circuit table element", Render(tableBody, nil))
+}
+
+const tableBody = `
+
+<h2>Example: Share configuration with a table element</h2>
+
+<p>A table element is a key-value map, whose entries are replicated across
+all servers of the circuit. Tables are meant for small data, like configuration
+or the addresses of services, which must be available everywhere in the cluster
+without an external store.
+
+<p>Every table has a topic. All tables of the same topic, on any circuit server,
+share their entries: a change made through one of them propagates to all others
+within a few announce intervals. Create a table of topic <code>config</code>
+on some circuit server, like so
+
+<pre>
+	circuit mktable /X88550014d4c82e4d/config config
+</pre>
+
+<p>and set a key in it
+
+<pre>
+	circuit table-put /X88550014d4c82e4d/config db/addr 10.0.0.7:5432
+</pre>
+
+<p>A table of the same topic on a different server soon reports the same entry:
+
+<pre>
+	circuit mktable /X4a1b2f00c3d2e1a0/config config
+	circuit table-get /X4a1b2f00c3d2e1a0/config db/addr
+</pre>
+
+<p>Entries can be given a time-to-live, after which they are removed from all tables:
+
+<pre>
+	circuit table-put --ttl 30s /X88550014d4c82e4d/config lock/primary X88550014d4c82e4d
+</pre>
+
+<p>Since each server removes expired entries on its own, the clocks of the
+servers should roughly agree.
+
+<p>Keys are removed with <code>table-del</code>. All entries, or only those whose keys
+begin with a given prefix, are printed with <code>table-ls</code>:
+
+<pre>
+	circuit table-del /X88550014d4c82e4d/config lock/primary
+	circuit table-ls /X88550014d4c82e4d/config db/
+</pre>
+
+<p>Finally, <code>table-watch</code> prints the current entries of a table, followed by each
+subsequent change, as it arrives:
+
+<pre>
+	circuit table-watch /X88550014d4c82e4d/config db/
+</pre>
+
+<p>Concurrent changes to the same key are resolved in favor of the later one.
+Scrubbing a table element removes the element, but not its entries, which remain
+with the other tables of its topic.
+
+<h3>Programmatic access</h3>
+
+<p>In Go, tables are created with the <code>MakeTable</code> method of an anchor:
+
+<pre>
+	t, err := a.MakeTable("config")
+	if err != nil {
+		…
+	}
+	t.Put("db/addr", "10.0.0.7:5432", 0)
+	e, ok := t.Get("db/addr")
+</pre>
+
+<p>The subscription returned by the table's <code>Watch</code> method delivers values
+of type <code>client.TableEntry</code>. Entries reporting the removal of a key, whether
+deleted or expired, have their <code>Deleted</code> field set.
+
+<p>The deletion of a key is remembered for an hour. A server that was cut off from the circuit
+for longer than that, and still holds the key, revives the key when it rejoins, and watchers
+see it as a new entry. Expired entries are removed by every server on its own, so the clocks
+of servers should agree.
+
+        `
@@ -30,6 +30,19 @@ server-server discover.
 The <code>-discover</code> option can be omitted by setting the environment variable
 <code>CIRCUIT_DISCOVER</code> to equal the desired multicast address.
 
+<p>Unrelated clusters discovering peers over the same multicast address would merge into one.
+To keep them apart, give each cluster a name with the <code>-cluster</code> option,
+or the <code>CIRCUIT_CLUSTER</code> environment variable. Servers ignore the discovery
+messages of other clusters, and servers started without a name form the default cluster.
+If the cluster is also given an HMAC key with <code>-hmac</code>, discovery messages are
+signed with the key, and unsigned messages, or messages signed with another key, are ignored.
+Command-line tools discovering a server must be given the same name and key:
+
+<pre>
+	circuit start -if eth0 -discover 228.8.8.8:7711 -cluster staging -hmac staging.key
+	circuit ls -discover 228.8.8.8:7711 -cluster staging -hmac staging.key /...
+</pre>
+
 <h2>Alternative advanced server startup</h2>
 
 <p>To run the circuit server on the first machine, pick a public IP address and port for it to
@@ -68,4 +81,94 @@ presence awareness and ordered communication, which is genuinely distributed;
 It uses communication and connectivity sparingly, hardly leaving a footprint
 when idle.
 
+<h3>Seeds</h3>
+
+<p>The join address can also be given without the process and worker identity,
+as in <code>-j 10.0.0.1:11022</code>, in which case the server joins whichever circuit server
+listens at that address. Where UDP multicast is not available, as in most cloud networks,
+servers can instead be given a list of <em>seeds</em>, addresses of likely circuit members,
+which they try in turn until a join succeeds. Seeds which are not up yet, or are the
+server itself, are skipped, and the list is retried every five seconds until a join succeeds.
+A server that is its own only seed starts a new circuit, which the other servers then join:
+
+<pre>
+	circuit start -a 10.0.0.3:11022 -seeds 10.0.0.1:11022,10.0.0.2:11022
+</pre>
+
+<p>Seeds can also be listed in a file, one per line, with blank lines and lines beginning with
+<code>#</code> ignored. The file is re-read whenever it changes, and the server joins
+through the listed seeds again, so that a circuit split by an outdated list can be mended
+by editing it:
+
+<pre>
+	circuit start -a 10.0.0.3:11022 -seeds-file /etc/circuit/seeds
+</pre>
+
+<p>Finally, seeds can be published in the DNS, either as SRV records, whose targets and ports are the seeds,
+or as the A records of a name, which are all tried at a given port:
+
+<pre>
+	circuit start -a 10.0.0.3:11022 -seeds-dns _circuit._tcp.example.com
+	circuit start -a 10.0.0.3:11022 -seeds-dns circuit.example.com:11022
+</pre>
+
+<p>All three sources can be combined. A <code>-j</code> address takes precedence over seeds,
+and seeds take precedence over multicast discovery.
+
+<h3>Tuning membership for large or distant clusters</h3>
+
+<p>Each server announces its membership every two seconds. Peers learn the usual timing of each
+other's announcements, and suspect a server whose announcement is late by more than the difference
+between the expire duration, four seconds by default, and the announce interval.
+A suspected server is declared dead, and forgotten, once it is much later still.
+Over slow links, such as those between datacenters, these defaults can cause live servers
+to be reported as departed. The timing is set when starting a server:
+
+<pre>
+	circuit start -a 10.0.0.1:11022 -announce 5s -expire 20s
+</pre>
+
+<p>The expire duration defaults to twice the announce interval, and must be at least that.
+Servers announce their interval along with their membership, and peers adapt to each other's
+intervals, so servers with different timings can share a circuit.
+
+<p>How late is too late is measured by a phi-accrual failure detector: a level of <i>p</i> means that
+an announcement as late as the current one would be expected once in 10<sup><i>p</i></sup> intervals,
+given the recent history of the peer. Servers are suspected at level 3 and declared dead at level 8.
+These thresholds are set with <code>-suspect-phi</code> and <code>-fail-phi</code>; a higher failure
+threshold tolerates longer pauses at the cost of slower detection of real failures.
+
+<h3>Network partitions</h3>
+
+<p>If the network splits, the servers on each side of the split declare the servers on the other
+side dead and carry on as separate circuits. Servers remember the addresses of departed peers
+for an hour, and every ten seconds try to rejoin one of them, in turn. Once connectivity returns, the first
+successful attempt merges the two circuits back into one, without the need for <code>circuit join</code>.
+Drained servers leave the circuit for good, and are not retried.
+The interval and the duration are set with <code>-heal</code> and <code>-remember</code>,
+and <code>-heal 0</code> disables healing.
+
+<p>A server that loses contact with at least half of its peers at about the same time, not counting drained ones, reports a
+<code>partition</code> event, and each peer it rejoins is reported by a <code>merge</code> event.
+Both concern the server element at the server's root anchor, and can be watched with an event subscription:
+
+<pre>
+	circuit mk@event /X88550014d4c82e4d/watch/split /X88550014d4c82e4d partition merge
+</pre>
+
+<h3>Repair of replicated state</h3>
+
+<p>Membership records, and the entries of replicated nameservers and tables, are pushed from server
+to server as they change. An update that is lost in transit is repaired by anti-entropy: every 30 seconds,
+each server compares a digest of its replicated state with that of a random neighbor, and the two
+exchange only the records that differ. The interval is set with <code>-entropy</code>, and
+<code>-entropy 0</code> disables repair. <code>circuit peek</code> on a server reports the number of
+reconciliations, and of records repaired, for each kind of replicated state.
+
+<p>The shape of the expander graph is set by <code>-expansion-low</code> and <code>-expansion-high</code>
+(a server with fewer than the low number of neighbors seeks new ones, up to the high number),
+<code>-spread</code> (the number of peers exchanged when two circuits join) and <code>-depth</code>
+(the length of the random walks used to sample peers). Inconsistent values are rejected at start,
+and <code>circuit peek</code> on a server reports all of these parameters.
+
         `
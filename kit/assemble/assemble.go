@@ -10,6 +10,7 @@ package assemble
 import (
 	"log"
 	"net"
+	"time"
 
 	"github.com/gocircuit/circuit/kit/xor"
 	"github.com/gocircuit/circuit/use/circuit"
@@ -20,20 +21,31 @@ type Assembler struct {
 	focus     xor.Key
 	addr      n.Addr       // our circuit address
 	multicast *net.UDPAddr // udp multicast address
+	cluster   string       // name of our cluster
+	key       []byte       // key signing our traces, or nil
 }
 
-func NewAssembler(addr n.Addr, multicast *net.UDPAddr) *Assembler {
+// NewAssembler creates an assembler for the named cluster, discovering peers over the multicast address.
+// Traces of other clusters are ignored, so that clusters can share a multicast address.
+// If key is not nil, traces are signed with it, and traces not signed with it are ignored.
+func NewAssembler(addr n.Addr, multicast *net.UDPAddr, cluster string, key []byte) *Assembler {
 	return &Assembler{
 		focus:     xor.ChooseKey(),
 		addr:      addr,
 		multicast: multicast,
+		cluster:   cluster,
+		key:       key,
 	}
 }
 
 func (a *Assembler) scatter(origin string) {
 	msg := &TraceMsg{
-		Origin: origin,
-		Addr:   a.addr.String(),
+		Origin:  origin,
+		Addr:    a.addr.String(),
+		Cluster: a.cluster,
+	}
+	if a.key != nil {
+		msg.Sign(a.key)
 	}
 	scatter := NewScatter(a.multicast, a.focus, msg.Encode())
 	scatter.Scatter() // send off a sequence of messages announcing our presnence over time
@@ -52,6 +64,9 @@ func (a *Assembler) AssembleServer(joinServer JoinFunc) {
 				log.Printf("Unrecognized trace message (%v)", err)
 				continue
 			}
+			if !a.accept(trace) {
+				continue // trace of another cluster
+			}
 			joinAddr, err := n.ParseAddr(trace.Addr)
 			if err != nil {
 				log.Printf("Trace origin address not parsing (%v)", err)
@@ -67,18 +82,33 @@ func (a *Assembler) AssembleServer(joinServer JoinFunc) {
 	}()
 }
 
+// accept returns true if the trace originates from our cluster.
+func (a *Assembler) accept(trace *TraceMsg) bool {
+	if trace.Cluster != a.cluster {
+		return false
+	}
+	return a.key == nil || trace.Verify(a.key)
+}
+
 func joinClient(serverAddr, clientAddr n.Addr) {
 	x, err := circuit.TryDial(clientAddr, "dialback")
 	if err != nil {
 		return
 	}
 	y := YDialBack{x}
-	y.OfferAddr(serverAddr)
+	y.Offer(Offer{Addr: serverAddr, Load: Load()})
+}
+
+// AssembleClient discovers a server and returns the address of the first one to respond.
+func (a *Assembler) AssembleClient() n.Addr {
+	return a.AssembleClientOffers(0)[0].Addr
 }
 
-func (a *Assembler) AssembleClient() n.Addr { // XXX: Clients should get more than one offering.
+// AssembleClientOffers discovers servers, collecting their offers for the duration of window after the first one arrives.
+// Offers are returned in order of arrival.
+func (a *Assembler) AssembleClientOffers(window time.Duration) []Offer {
 	d, xd := NewDialBack()
 	circuit.Listen("dialback", xd)
 	go a.scatter("client")
-	return d.ObtainAddr()
+	return d.ObtainOffers(window)
 }
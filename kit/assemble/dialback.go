@@ -8,36 +8,105 @@
 package assemble
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/gocircuit/circuit/use/circuit"
 	"github.com/gocircuit/circuit/use/n"
 )
 
+// Offer is a server's response to a discovering client.
+type Offer struct {
+	Addr    n.Addr        // circuit address of the server
+	Load    float64       // load advertised by the server, as its load average per CPU
+	Latency time.Duration // time from the start of discovery to the arrival of the offer
+}
+
+// DialBack collects the offers of servers responding to a discovering client.
 type DialBack struct {
-	once sync.Once
-	ch chan n.Addr
+	start time.Time
+	once  sync.Once
+	first chan struct{} // closed on the arrival of the first offer
+	sync.Mutex
+	offers []Offer
 }
 
 func NewDialBack() (*DialBack, *XDialBack) {
-	d := &DialBack{ch: make(chan n.Addr, 1)}
+	d := &DialBack{start: time.Now(), first: make(chan struct{})}
 	xd := &XDialBack{d}
 	return d, xd
 }
 
+// ObtainAddr blocks until the first offer arrives and returns the address of the offering server.
 func (d *DialBack) ObtainAddr() n.Addr {
-	return <-d.ch
+	return d.ObtainOffers(0)[0].Addr
+}
+
+// ObtainOffers blocks until the first offer arrives, collects further offers for the duration of window,
+// and returns all offers in order of arrival.
+func (d *DialBack) ObtainOffers(window time.Duration) []Offer {
+	<-d.first
+	time.Sleep(window)
+	d.Lock()
+	defer d.Unlock()
+	return append([]Offer{}, d.offers...)
+}
+
+func (d *DialBack) offer(o Offer) {
+	d.Lock()
+	defer d.Unlock()
+	for _, p := range d.offers {
+		if p.Addr.String() == o.Addr.String() {
+			return // servers answer every trace of the client
+		}
+	}
+	o.Latency = time.Since(d.start)
+	d.offers = append(d.offers, o)
+	d.once.Do(func() {
+		close(d.first)
+	})
+}
+
+// SortByLatency orders offers by increasing latency.
+func SortByLatency(offers []Offer) {
+	sort.Stable(byLatency(offers))
+}
+
+// SortByLoad orders offers by increasing load, and offers of equal load by increasing latency.
+// Servers that do not advertise their load count as unloaded.
+func SortByLoad(offers []Offer) {
+	sort.Stable(byLoad(offers))
+}
+
+type byLatency []Offer
+
+func (x byLatency) Len() int           { return len(x) }
+func (x byLatency) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x byLatency) Less(i, j int) bool { return x[i].Latency < x[j].Latency }
+
+type byLoad []Offer
+
+func (x byLoad) Len() int      { return len(x) }
+func (x byLoad) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x byLoad) Less(i, j int) bool {
+	if x[i].Load != x[j].Load {
+		return x[i].Load < x[j].Load
+	}
+	return x[i].Latency < x[j].Latency
 }
 
 type XDialBack struct {
 	d *DialBack
 }
 
+// OfferAddr accepts an offer without load information, as made by older servers.
 func (xd *XDialBack) OfferAddr(addr n.Addr) {
-	xd.d.once.Do(func() {
-		xd.d.ch <- addr
-		close(xd.d.ch)
-	})
+	xd.d.offer(Offer{Addr: addr})
+}
+
+func (xd *XDialBack) Offer(o Offer) {
+	xd.d.offer(o)
 }
 
 func init() {
@@ -48,6 +117,16 @@ type YDialBack struct {
 	circuit.PermX
 }
 
+// Offer makes an offer to the client, falling back to an offer of just the address for older clients.
+func (y YDialBack) Offer(o Offer) {
+	defer func() {
+		if r := recover(); r != nil {
+			y.OfferAddr(o.Addr)
+		}
+	}()
+	y.Call("Offer", o)
+}
+
 func (y YDialBack) OfferAddr(addr n.Addr) {
 	defer func() {
 		recover()
@@ -0,0 +1,46 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package assemble
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gocircuit/circuit/use/n"
+)
+
+type testAddr string
+
+func (a testAddr) NetAddr() net.Addr    { return nil }
+func (a testAddr) String() string       { return string(a) }
+func (a testAddr) FileName() string     { return string(a) }
+func (a testAddr) WorkerID() n.WorkerID { return n.WorkerID(a) }
+
+func TestDialBack(t *testing.T) {
+	d, xd := NewDialBack()
+	go func() {
+		xd.Offer(Offer{Addr: testAddr("busy"), Load: 2})
+		time.Sleep(10 * time.Millisecond)
+		xd.OfferAddr(testAddr("busy")) // repeated offer
+		xd.Offer(Offer{Addr: testAddr("idle"), Load: 0.5})
+		xd.OfferAddr(testAddr("old"))
+	}()
+	offers := d.ObtainOffers(100 * time.Millisecond)
+	if len(offers) != 3 {
+		t.Fatalf("expecting 3 offers, got %v", offers)
+	}
+	SortByLatency(offers)
+	if offers[0].Addr.String() != "busy" || offers[1].Addr.String() != "idle" {
+		t.Errorf("latency order %v", offers)
+	}
+	SortByLoad(offers)
+	if offers[0].Addr.String() != "old" || offers[1].Addr.String() != "idle" || offers[2].Addr.String() != "busy" {
+		t.Errorf("load order %v", offers)
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package assemble
+
+import (
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Load returns the one-minute load average of this host per CPU, or zero where it is not available.
+func Load() float64 {
+	buf, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	f := strings.Fields(string(buf))
+	if len(f) == 0 {
+		return 0
+	}
+	avg, err := strconv.ParseFloat(f[0], 64)
+	if err != nil {
+		return 0
+	}
+	return avg / float64(runtime.NumCPU())
+}
@@ -8,12 +8,16 @@
 package assemble
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/json"
 )
 
 type TraceMsg struct {
-	Origin string // "server" or "client"
-	Addr string // address of origin
+	Origin  string // "server" or "client"
+	Addr    string // address of origin
+	Cluster string `json:",omitempty"` // name of the cluster of origin; empty for the default cluster
+	Sig     []byte `json:",omitempty"` // signature of the above by the cluster key, if any
 }
 
 func (m *TraceMsg) Encode() []byte {
@@ -31,3 +35,22 @@ func Decode(p []byte) (*TraceMsg, error) {
 	}
 	return m, nil
 }
+
+// Sign signs the message with key.
+func (m *TraceMsg) Sign(key []byte) {
+	m.Sig = m.mac(key)
+}
+
+// Verify returns true if the message is signed with key.
+func (m *TraceMsg) Verify(key []byte) bool {
+	return hmac.Equal(m.Sig, m.mac(key))
+}
+
+func (m *TraceMsg) mac(key []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, s := range []string{m.Origin, m.Addr, m.Cluster} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,43 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package assemble
+
+import (
+	"testing"
+)
+
+func TestTraceCluster(t *testing.T) {
+	key := []byte("production")
+	a := &Assembler{cluster: "prod", key: key}
+	m := &TraceMsg{Origin: "server", Addr: "circuit://10.0.0.1:11022/1/Q0000000000000001", Cluster: "prod"}
+	if a.accept(m) {
+		t.Errorf("unsigned trace accepted")
+	}
+	m.Sign(key)
+	w, err := Decode(m.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.accept(w) {
+		t.Errorf("signed trace rejected")
+	}
+	w.Addr = "circuit://10.0.0.2:11022/1/Q0000000000000001"
+	if a.accept(w) {
+		t.Errorf("altered trace accepted")
+	}
+	m.Sign([]byte("staging"))
+	if a.accept(m) {
+		t.Errorf("trace signed with another key accepted")
+	}
+	if (&Assembler{}).accept(m) {
+		t.Errorf("trace of another cluster accepted")
+	}
+	if !(&Assembler{}).accept(&TraceMsg{Origin: "client", Addr: m.Addr}) {
+		t.Errorf("trace of the default cluster rejected")
+	}
+}
@@ -9,6 +9,7 @@ package pubsub
 
 import (
 	"container/list"
+	"errors"
 	"runtime"
 	"sync"
 	
@@ -27,9 +28,24 @@ type PubSub struct {
 		sync.Mutex
 		member map[int]*queue
 		n int
+		seq int64 // sequence number of the most recently published value
+		hist []item // most recently published values, retained for resuming subscribers
 	}
 }
 
+// DefaultRetain is the number of most recently published values retained by a PubSub
+// for subscribers resuming from a sequence number.
+const DefaultRetain = 1024
+
+// Match reports whether a published value is of interest to a subscriber.
+type Match func(interface{}) bool
+
+// item is a published value, stamped with its sequence number.
+type item struct {
+	seq int64
+	v interface{}
+}
+
 // Summarize returns a list of items meant to summarize the history of the stream so far
 // for subscribers joining now.
 type Summarize func() []interface{}
@@ -49,6 +65,13 @@ func (ps *PubSub) Source() string {
 	return ps.name
 }
 
+// Seq returns the sequence number of the most recently published value that has been distributed to subscribers.
+func (ps *PubSub) Seq() int64 {
+	ps.down.Lock()
+	defer ps.down.Unlock()
+	return ps.down.seq
+}
+
 // Publish appends a value onto the infinite update stream.
 func (ps *PubSub) Publish(v interface{}) {
 	ps.up.Lock()
@@ -86,16 +109,24 @@ func (ps *PubSub) loop(src <-chan interface{}) {
 func (ps *PubSub) distribute(v interface{}) {
 	ps.down.Lock()
 	defer ps.down.Unlock()
+	ps.down.seq++
+	x := item{ps.down.seq, v}
+	if len(ps.down.hist) == DefaultRetain {
+		ps.down.hist = append(ps.down.hist[1:], x)
+	} else {
+		ps.down.hist = append(ps.down.hist, x)
+	}
 	for _, q := range ps.down.member {
-		q.distribute(v)
+		q.offer(x)
 	}
 }
 
 func (ps *PubSub) clunk() {
 	ps.down.Lock()
 	defer ps.down.Unlock()
-	for _, q := range ps.down.member {
+	for id, q := range ps.down.member {
 		q.clunk()
+		delete(ps.down.member, id)
 	}
 }
 
@@ -104,18 +135,40 @@ func (ps *PubSub) clunk() {
 // with a sequence of values summarizing all past history. Subsequent values come from the pubish stream.
 // Subscriptions are abandoned on garbage-collection.
 func (ps *PubSub) Subscribe() *Subscription {
+	sub, _ := ps.SubscribeFrom(0, nil)
+	return sub
+}
+
+// SubscribeFrom creates a new subscription, which receives only values accepted by match, if match is not nil.
+// If from is positive, the subscription resumes the stream right after the value with sequence number from,
+// replaying retained values instead of a summary. An error is returned if values following from
+// are no longer retained. Summary values are stamped with the sequence number of the most recently
+// published value, so that subscribers can later resume from the state the summary reflects.
+func (ps *PubSub) SubscribeFrom(from int64, match Match) (*Subscription, error) {
 	ps.down.Lock()
 	defer ps.down.Unlock()
-	q := newQueue(ps, ps.down.n)
-	ps.down.member[q.id] = q
-	ps.down.n++
-	// Prefix subscription's input stream with a summary of all history until now
-	if ps.down.sum != nil {
+	var replay []item
+	if from > 0 {
+		if from > ps.down.seq {
+			return nil, errors.New("sequence number not yet published")
+		}
+		if oldest := ps.down.seq - int64(len(ps.down.hist)) + 1; from < oldest-1 {
+			return nil, errors.New("values following sequence number no longer retained")
+		}
+		replay = ps.down.hist[len(ps.down.hist)-int(ps.down.seq-from):]
+	} else if ps.down.sum != nil {
+		// Prefix subscription's input stream with a summary of all history until now
 		for _, v := range ps.down.sum() {
-			q.distribute(v)
+			replay = append(replay, item{ps.down.seq, v})
 		}
 	}
-	return q.use()
+	q := newQueue(ps, ps.down.n, match)
+	ps.down.member[q.id] = q
+	ps.down.n++
+	for _, x := range replay {
+		q.offer(x)
+	}
+	return q.use(), nil
 }
 
 // scrub removes a subscription queue from the member table, only if 
@@ -130,24 +183,39 @@ func (ps *PubSub) scrub(id int) {
 	delete(ps.down.member, id)
 }
 
+// cancel removes a subscription queue from the member table and ends its stream.
+func (ps *PubSub) cancel(id int) {
+	ps.down.Lock()
+	defer ps.down.Unlock()
+	q, ok := ps.down.member[id]
+	if !ok {
+		return
+	}
+	delete(ps.down.member, id)
+	q.clunk()
+}
+
 // queue…
 type queue struct {
 	ps *PubSub
 	id int
-	ch1 chan<- interface{} // disribute() => loop()
-	ch2 <-chan interface{} // loop() => consume()
+	match Match
+	ch1 chan<- item // disribute() => loop()
+	ch2 <-chan item // loop() => consume()
 	sync.Mutex
 	nref int // number of references to this queue
 	pend int // number of buffered messages
+	seq int64 // sequence number of the last consumed message
 	closed bool // true if the source channel has reached EOF
 }
 
-func newQueue(ps *PubSub, id int) *queue {
-	ch1 := make(chan interface{}, 1)
-	ch2 := make(chan interface{}, 1)
+func newQueue(ps *PubSub, id int, match Match) *queue {
+	ch1 := make(chan item, 1)
+	ch2 := make(chan item, 1)
 	q := &queue{
 		ps: ps, 
 		id: id, 
+		match: match,
 		ch1: ch1, 
 		ch2: ch2,
 	}
@@ -170,6 +238,7 @@ func (q *queue) setClosed(v bool) {
 type Stat struct {
 	Source string
 	Pending int
+	Seq int64 // sequence number of the last consumed message
 	Closed bool
 }
 
@@ -179,6 +248,7 @@ func (q *queue) Peek() Stat {
 	return Stat{
 		Source: q.ps.Source(),
 		Pending: q.pend,
+		Seq: q.seq,
 		Closed: q.closed,
 	}
 }
@@ -187,13 +257,17 @@ func (q *queue) clunk() {
 	close(q.ch1)
 }
 
-func (q *queue) distribute(v interface{}) {
-	q.ch1 <- v
+// offer enqueues x, unless it is not accepted by the subscription's match.
+func (q *queue) offer(x item) {
+	if q.match != nil && !q.match(x.v) {
+		return
+	}
+	q.ch1 <- x
 }
 
 // loop churns messages from the main loop onto the internal buffer of this subscription,
 // and from there out to the consumer, as requested by calls to Consume.
-func (q *queue) loop(ch1 <-chan interface{}, ch2 chan<- interface{}) {
+func (q *queue) loop(ch1 <-chan item, ch2 chan<- item) {
 	var l list.List
 __preclose:
 	for {
@@ -206,7 +280,7 @@ __preclose:
 				}
 				l.PushFront(v)
 				q.addPend(1)
-			case ch2 <- w.Value: // consume
+			case ch2 <- w.Value.(item): // consume
 				l.Remove(w)
 				q.addPend(-1)
 			}
@@ -227,7 +301,7 @@ __preclose:
 			close(ch2)
 			return
 		}
-		ch2 <- w.Value
+		ch2 <- w.Value.(item)
 		l.Remove(w)
 		q.addPend(-1)
 	}
@@ -262,10 +336,21 @@ func (q *queue) use() *Subscription {
 }
 
 func (q *queue) Consume() (v interface{}, ok bool) {
-	v, ok = <-q.ch2
+	_, v, ok = q.ConsumeSeq()
 	return
 }
 
+func (q *queue) ConsumeSeq() (seq int64, v interface{}, ok bool) {
+	x, ok := <-q.ch2
+	if !ok {
+		return 0, nil, false
+	}
+	q.Lock()
+	defer q.Unlock()
+	q.seq = x.seq
+	return x.seq, x.v, true
+}
+
 // Subscription is the user's interface to consuming messages from a topic.
 type Subscription struct {
 	*queue // Consume(), Peek()
@@ -273,6 +358,7 @@ type Subscription struct {
 
 type Consumer interface {
 	Consume() (interface{}, bool)
+	ConsumeSeq() (int64, interface{}, bool)
 	Peek() Stat
 	Scrub()
 	X() circuit.X
@@ -286,7 +372,10 @@ func (s *Subscription) X() circuit.X {
 	return circuit.Ref(s)
 }
 
-func (s *Subscription) Scrub() {}
+// Scrub ends the subscription. Values already buffered are still consumed, after which Consume reports the end of the stream.
+func (s *Subscription) Scrub() {
+	s.queue.ps.cancel(s.queue.id)
+}
 
 func (s *Subscription) Peek() Stat {
 	return s.queue.Peek()
@@ -296,6 +385,11 @@ func (s *Subscription) Consume() (interface{}, bool) {
 	return s.queue.Consume()
 }
 
+// ConsumeSeq is like Consume, but it also returns the sequence number of the consumed value.
+func (s *Subscription) ConsumeSeq() (int64, interface{}, bool) {
+	return s.queue.ConsumeSeq()
+}
+
 // YSubscription is a client wrapper for cross-interface to *Subscription
 type YSubscription struct {
 	X circuit.X
@@ -310,8 +404,15 @@ func (y YSubscription) Consume() (interface{}, bool) {
 	return r[0], r[1].(bool)
 }
 
+func (y YSubscription) ConsumeSeq() (int64, interface{}, bool) {
+	r := y.X.Call("ConsumeSeq")
+	return r[0].(int64), r[1], r[2].(bool)
+}
+
 func (y YSubscription) IsDone() bool {
 	return true
 }
 
-func (y YSubscription) Scrub() {}
+func (y YSubscription) Scrub() {
+	y.X.Call("Scrub")
+}
@@ -0,0 +1,91 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package pubsub
+
+import (
+	"testing"
+)
+
+func even(v interface{}) bool {
+	return v.(int)%2 == 0
+}
+
+func TestSubscribeFrom(t *testing.T) {
+	ps := New("test", func() []interface{} { return []interface{}{-2} })
+	all := ps.Subscribe()
+	sub, err := ps.SubscribeFrom(0, even)
+	if err != nil {
+		t.Fatalf("subscribe (%s)", err)
+	}
+	if seq, v, _ := sub.ConsumeSeq(); seq != 0 || v.(int) != -2 {
+		t.Fatalf("expecting summary, got %d %v", seq, v)
+	}
+	if _, v, _ := all.ConsumeSeq(); v.(int) != -2 {
+		t.Fatalf("expecting summary, got %v", v)
+	}
+	for i := 1; i <= DefaultRetain+10; i++ {
+		ps.Publish(i)
+	}
+	for i := 1; i <= DefaultRetain+10; i++ {
+		if seq, v, _ := all.ConsumeSeq(); seq != int64(i) || v.(int) != i {
+			t.Fatalf("expecting %d, got %d %v", i, seq, v)
+		}
+	}
+	if seq, v, _ := sub.ConsumeSeq(); seq != 2 || v.(int) != 2 {
+		t.Fatalf("expecting filtered value 2, got %d %v", seq, v)
+	}
+	if sub.Peek().Seq != 2 {
+		t.Fatalf("peek reports wrong sequence number")
+	}
+	// resume within retained history
+	res, err := ps.SubscribeFrom(DefaultRetain+7, nil)
+	if err != nil {
+		t.Fatalf("resume (%s)", err)
+	}
+	for i := DefaultRetain + 8; i <= DefaultRetain+10; i++ {
+		if seq, _, _ := res.ConsumeSeq(); seq != int64(i) {
+			t.Fatalf("expecting resumed %d, got %d", i, seq)
+		}
+	}
+	if _, err = ps.SubscribeFrom(5, nil); err == nil {
+		t.Fatalf("expecting error resuming beyond retained history")
+	}
+	if _, err = ps.SubscribeFrom(DefaultRetain+11, nil); err == nil {
+		t.Fatalf("expecting error resuming from the future")
+	}
+	ps.Close()
+	if _, _, ok := res.ConsumeSeq(); ok {
+		t.Fatalf("expecting end of stream")
+	}
+}
+
+func TestScrub(t *testing.T) {
+	ps := New("test", nil)
+	sub, other := ps.Subscribe(), ps.Subscribe()
+	ps.Publish(1)
+	if _, v, _ := other.ConsumeSeq(); v.(int) != 1 { // 1 has been distributed
+		t.Fatalf("expecting 1, got %v", v)
+	}
+	sub.Scrub()
+	ps.Publish(2)
+	if _, v, ok := sub.ConsumeSeq(); !ok || v.(int) != 1 {
+		t.Fatalf("expecting buffered value 1, got %v", v)
+	}
+	if _, _, ok := sub.ConsumeSeq(); ok {
+		t.Fatalf("expecting end of scrubbed stream")
+	}
+	sub.Scrub()
+	if _, v, _ := other.ConsumeSeq(); v.(int) != 2 {
+		t.Fatalf("expecting 2, got %v", v)
+	}
+	ps.Close()
+	other.Scrub()
+	if _, _, ok := other.ConsumeSeq(); ok {
+		t.Fatalf("expecting end of stream")
+	}
+}
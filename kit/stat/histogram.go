@@ -7,6 +7,10 @@
 
 package stat
 
+import (
+	"math"
+)
+
 // Histogram is a simple static histogram structure.
 // It consumes a stream of floating point numbers and can output a histogram at any time.
 type Histogram struct {
@@ -50,10 +54,37 @@ func (h *Histogram) Put(x float64, weight float64) {
 	}
 	if x >= h.max {
 		h.bin[len(h.bin)-1].Weight += weight
+		return
 	}
 	h.bin[min(len(h.bin)-1, int((x-h.min)/h.width))].Weight += weight
 }
 
+// Weight returns the total weight of all samples in the histogram.
+func (h *Histogram) Weight() float64 {
+	var w float64
+	for _, b := range h.bin {
+		w += b.Weight
+	}
+	return w
+}
+
+// Quantile returns the value below which lies the fraction q of the total weight,
+// interpolating linearly within bins. It returns NaN if the histogram is empty.
+func (h *Histogram) Quantile(q float64) float64 {
+	total := h.Weight()
+	if total == 0 {
+		return math.NaN()
+	}
+	target, acc := q*total, 0.0
+	for _, b := range h.bin {
+		if b.Weight > 0 && acc+b.Weight >= target {
+			return b.X + h.width*(target-acc)/b.Weight
+		}
+		acc += b.Weight
+	}
+	return h.max
+}
+
 // Histogram returns the current histogram.
 func (h *Histogram) Histogram() []*Bin {
 	return h.bin
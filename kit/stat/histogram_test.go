@@ -0,0 +1,47 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2013 Petar Maymounkov <p@gocircuit.org>
+
+package stat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	h := NewHistogram(0, 100, 100)
+	if !math.IsNaN(h.Quantile(0.5)) {
+		t.Fatalf("empty histogram quantile should be NaN")
+	}
+	for i := 0; i < 100; i++ {
+		h.Put(float64(i), 1)
+	}
+	h.Put(1000, 1) // clamped to the last bin
+	if h.Weight() != 101 {
+		t.Fatalf("weight %g", h.Weight())
+	}
+	if q := h.Quantile(0.5); math.Abs(q-50.5) > 1 {
+		t.Fatalf("median %g", q)
+	}
+	if q := h.Quantile(0.99); q < 98 || q > 100 {
+		t.Fatalf("99th percentile %g", q)
+	}
+}
+
+func TestHistogramClamp(t *testing.T) {
+	h := NewHistogram(0, 10, 10)
+	h.Put(-5, 1)
+	h.Put(10, 2)
+	h.Put(50, 3)
+	bins := h.Histogram()
+	if bins[0].Weight != 1 || bins[9].Weight != 5 {
+		t.Fatalf("out-of-range samples not clamped to the end bins: %g %g", bins[0].Weight, bins[9].Weight)
+	}
+	if h.Weight() != 6 {
+		t.Fatalf("samples above the range counted more than once: weight %g", h.Weight())
+	}
+}
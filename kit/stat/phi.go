@@ -0,0 +1,91 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package stat
+
+import (
+	"math"
+	"time"
+)
+
+// PhiAccrual is a phi-accrual failure detector, after Hayashibara et al.
+// It keeps a sliding window of the inter-arrival times of heartbeats, and expresses
+// the suspicion that the monitored process has failed, given the time since its last heartbeat,
+// as phi = -log10 of the probability that a heartbeat arrives later still.
+// Inter-arrival times are assumed normally distributed.
+// PhiAccrual is not synchronized.
+type PhiAccrual struct {
+	window []float64 // recent inter-arrival times, in seconds
+	head   int
+	n      int
+	last   time.Time
+	minStd float64
+	pause  float64
+}
+
+// NewPhiAccrual creates a detector keeping size-many inter-arrival times.
+// The detector begins with the expectation that heartbeats arrive every first interval.
+// The standard deviation of inter-arrival times is deemed at least minStdDev, and
+// heartbeats are expected up to pause later than usual without raising suspicion.
+func NewPhiAccrual(size int, first, minStdDev, pause time.Duration) *PhiAccrual {
+	x := &PhiAccrual{
+		window: make([]float64, size),
+		minStd: minStdDev.Seconds(),
+		pause:  pause.Seconds(),
+	}
+	// Bootstrap with a mean of first and a standard deviation of a quarter of that.
+	x.add(first.Seconds() * 3 / 4)
+	x.add(first.Seconds() * 5 / 4)
+	return x
+}
+
+func (x *PhiAccrual) add(interval float64) {
+	x.window[x.head] = interval
+	x.head = (x.head + 1) % len(x.window)
+	if x.n < len(x.window) {
+		x.n++
+	}
+}
+
+// Heartbeat records the arrival of a heartbeat at time t.
+func (x *PhiAccrual) Heartbeat(t time.Time) {
+	if !x.last.IsZero() && t.After(x.last) {
+		x.add(t.Sub(x.last).Seconds())
+	}
+	if t.After(x.last) {
+		x.last = t
+	}
+}
+
+// Last returns the time of the latest heartbeat, or the zero time if none has arrived.
+func (x *PhiAccrual) Last() time.Time {
+	return x.last
+}
+
+// Phi returns the suspicion level at time t. It is zero before the first heartbeat.
+func (x *PhiAccrual) Phi(t time.Time) float64 {
+	if x.last.IsZero() {
+		return 0
+	}
+	var m Moment
+	m.Init()
+	for i := 0; i < x.n; i++ {
+		m.Add(x.window[i])
+	}
+	mean := m.Average() + x.pause
+	std := math.Sqrt(math.Max(m.Variance(), 0))
+	if std < x.minStd {
+		std = x.minStd
+	}
+	// Logistic approximation of the cumulative distribution function of the normal distribution.
+	y := (t.Sub(x.last).Seconds() - mean) / std
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if y > 0 {
+		return -math.Log10(e / (1 + e))
+	}
+	return -math.Log10(1 - 1/(1+e))
+}
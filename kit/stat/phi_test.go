@@ -0,0 +1,54 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package stat
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPhiAccrual(t *testing.T) {
+	x := NewPhiAccrual(100, time.Second, 50*time.Millisecond, 0)
+	t0 := time.Unix(1e9, 0)
+	if phi := x.Phi(t0); phi != 0 {
+		t.Fatalf("expecting no suspicion before the first heartbeat, got %v", phi)
+	}
+	// Heartbeats every 2s, with jitter, replace the bootstrap expectation of 1s.
+	tt := t0
+	for i := 0; i < 100; i++ {
+		tt = tt.Add(2*time.Second + time.Duration(rand.Intn(200)-100)*time.Millisecond)
+		x.Heartbeat(tt)
+	}
+	if x.Last() != tt {
+		t.Fatalf("last heartbeat %v, expecting %v", x.Last(), tt)
+	}
+	var prev float64
+	for _, d := range []time.Duration{time.Second, 2 * time.Second, 2200 * time.Millisecond, 2500 * time.Millisecond, 3 * time.Second} {
+		phi := x.Phi(tt.Add(d))
+		if phi < prev {
+			t.Fatalf("suspicion must grow with time, got %v after %v", phi, prev)
+		}
+		prev = phi
+	}
+	if phi := x.Phi(tt.Add(time.Second)); phi > 1 {
+		t.Errorf("early heartbeat suspected, phi %v", phi)
+	}
+	if phi := x.Phi(tt.Add(3 * time.Second)); phi < 8 {
+		t.Errorf("late heartbeat not suspected, phi %v", phi)
+	}
+	// A pause allowance defers suspicion.
+	y := NewPhiAccrual(100, 2*time.Second, 50*time.Millisecond, 2*time.Second)
+	y.Heartbeat(t0)
+	if phi := y.Phi(t0.Add(3 * time.Second)); phi > 1 {
+		t.Errorf("heartbeat within pause suspected, phi %v", phi)
+	}
+	if phi := y.Phi(t0.Add(10 * time.Second)); phi < 8 {
+		t.Errorf("heartbeat beyond pause not suspected, phi %v", phi)
+	}
+}
@@ -50,7 +50,7 @@ func (r *Runtime) TryDial(addr n.Addr, service string) (circuit.PermX, error) {
 		return nil, err
 	}
 
-	return r.importEitherPtr(retrn, addr)
+	return r.importEitherPtr(retrn, conn.Addr()) // the dialed address may lack the worker ID
 }
 
 func (r *Runtime) DialSelf(service string) interface{} {
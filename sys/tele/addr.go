@@ -9,7 +9,6 @@ package tele
 
 import (
 	"encoding/gob"
-	"fmt"
 	"net"
 	"net/url"
 	"strconv"
@@ -77,7 +76,17 @@ func (a *Addr) FileName() string {
 }
 
 // circuit://123.3.45.0:3456/2345/R1122334455667788
+//
+// A bare network address, like 123.3.45.0:3456, parses to an address without a worker ID,
+// which refers to whichever worker listens at that network address.
 func ParseAddr(s string) (*Addr, error) {
+	if !strings.Contains(s, "://") {
+		naddr, err := ParseNetAddr(s)
+		if err != nil {
+			return nil, err
+		}
+		return &Addr{TCP: naddr.(*net.TCPAddr)}, nil
+	}
 	u, err := url.Parse(s)
 	if err != nil {
 		return nil, err
@@ -93,7 +102,7 @@ func ParseAddr(s string) (*Addr, error) {
 	// Parse path
 	parts := strings.Split(u.Path, "/")
 	if len(parts) != 3 {
-		return nil, errors.NewError(fmt.Sprintf("parse path: %#v", parts))
+		return nil, errors.NewError("parse path: %#v", parts)
 	}
 	if parts[0] != "" {
 		return nil, errors.NewError("must start with slash")
@@ -0,0 +1,32 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package tele
+
+import (
+	"testing"
+)
+
+func TestParseAddr(t *testing.T) {
+	a, err := ParseAddr("127.0.0.1:11022")
+	if err != nil {
+		t.Fatalf("parse bare address (%s)", err)
+	}
+	if a.ID != "" || a.TCP.String() != "127.0.0.1:11022" {
+		t.Fatalf("unexpected address %#v", a)
+	}
+	s := "circuit://127.0.0.1:11022/123/Q4e16779fe039ecf3"
+	if a, err = ParseAddr(s); err != nil {
+		t.Fatalf("parse circuit address (%s)", err)
+	}
+	if a.PID != 123 || a.TCP.String() != "127.0.0.1:11022" || a.String() != s {
+		t.Fatalf("unexpected address %#v", a)
+	}
+	if _, err = ParseAddr("127.0.0.1"); err == nil {
+		t.Fatalf("address without a port parsed")
+	}
+}
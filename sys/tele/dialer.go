@@ -37,6 +37,11 @@ func (d *Dialer) Dial(addr n.Addr) (conn n.Conn, err error) {
 	d.Lock()
 	defer d.Unlock()
 	//
+	if addr.WorkerID() == "" {
+		if addr, err = d.resolve(addr.(*Addr)); err != nil {
+			return nil, err
+		}
+	}
 	workerID := addr.WorkerID()
 	s, present := d.open[workerID]
 	if !present {
@@ -47,7 +52,7 @@ func (d *Dialer) Dial(addr n.Addr) (conn n.Conn, err error) {
 		if err != nil {
 			return nil, err
 		}
-		if err = d.auth(addr, s.Dial()); err != nil {
+		if _, err = d.auth(addr, s.Dial()); err != nil {
 			s.Close()
 			return nil, err
 		}
@@ -93,23 +98,48 @@ func (d *Dialer) scrub(workerID n.WorkerID) {
 	delete(d.open, workerID)
 }
 
-func (d *Dialer) auth(addr n.Addr, conn *blend.Conn) error {
+// resolve returns the address of the worker listening at the network address of addr, which has no worker ID.
+func (d *Dialer) resolve(addr *Addr) (*Addr, error) {
+	s, err := d.sub.DialSession(addr.TCP, func() {})
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	welcome, err := d.auth(addr, s.Dial())
+	if err != nil {
+		return nil, err
+	}
+	if welcome == nil {
+		return nil, errors.NewError("remote worker does not report its address")
+	}
+	r := *welcome
+	tcp := *welcome.TCP
+	if tcp.IP.IsUnspecified() { // remote worker listens on all interfaces
+		tcp.IP = addr.TCP.IP
+	}
+	r.TCP = &tcp
+	return &r, nil
+}
+
+// auth introduces this worker to the remote one, and returns the address reported by the remote, if any.
+func (d *Dialer) auth(addr n.Addr, conn *blend.Conn) (*Addr, error) {
 	defer conn.Close()
 	if err := conn.Write(&HelloMsg{
 		SourceAddr: d.dialback,
 		TargetAddr: addr,
 	}); err != nil {
-		return err
+		return nil, err
 	}
 	msg, err := conn.Read()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	switch q := msg.(type) {
 	case *WelcomeMsg:
-		return nil
+		a, _ := q.Addr.(*Addr)
+		return a, nil
 	case *RejectMsg:
-		return errors.NewError("dial rejected by remote (%s)", errors.Unpack(q.Err))
+		return nil, errors.NewError("dial rejected by remote (%s)", errors.Unpack(q.Err))
 	}
-	return errors.NewError("unknown welcome response")
+	return nil, errors.NewError("unknown welcome response")
 }
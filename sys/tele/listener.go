@@ -78,7 +78,7 @@ func (l *Listener) handshake(conn *blend.Conn) (sourceAddr *Addr, err error) {
 		if err != nil {
 			conn.Write(&RejectMsg{err})
 		} else {
-			err = conn.Write(&WelcomeMsg{})
+			err = conn.Write(&WelcomeMsg{Addr: l.addr})
 		}
 	}()
 	hello, ok := msg.(*HelloMsg)
@@ -98,6 +98,9 @@ func (l *Listener) handshake(conn *blend.Conn) (sourceAddr *Addr, err error) {
 		log.Println("rejecting ", conn.RemoteAddr().String(), "unknown target address type")
 		return nil, errors.NewError("rejecting unknown target address type")
 	}
+	if la.WorkerID() == "" {
+		return da, nil // the dialer looks for whichever worker listens here
+	}
 	if la.WorkerID() != l.addr.WorkerID() {
 		log.Println("rejecting", conn.RemoteAddr().String(), "due to worker identity mismatch")
 		return nil, errors.NewError("rejecting worker identity mismatch, looks for %s, got %s", la.WorkerID(), l.addr.WorkerID())
@@ -134,7 +137,11 @@ type HelloMsg struct {
 	TargetAddr n.Addr
 }
 
-type WelcomeMsg struct{}
+// WelcomeMsg accepts a dialer. It carries the address of the accepting worker,
+// so that dialers of bare network addresses can learn the worker's identity.
+type WelcomeMsg struct {
+	Addr n.Addr
+}
 
 type RejectMsg struct {
 	Err error
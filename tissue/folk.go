@@ -20,6 +20,13 @@ type Folk struct {
 	ch chan FolkAvatar // Services pending to be opened
 }
 
+// Topic returns the topic of this folk.
+func (folk *Folk) Topic() string {
+	return folk.topic
+}
+
+// Opened returns the peers of this folk. The ID of each peer is the kin ID of its server,
+// rather than the receiver ID of the remote folk service.
 func (folk *Folk) Opened() []FolkAvatar {
 	neighbors := folk.neighborhood.View()
 	r := make([]FolkAvatar, len(neighbors))
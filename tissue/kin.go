@@ -8,6 +8,7 @@
 package tissue
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -21,6 +22,7 @@ import (
 // Kin is a service that maintains connectivity to a small set of 'neighbor' circuits.
 type Kin struct {
 	kinav        KinAvatar // Permanent cluster-wide unique ID for this kin
+	cfg          Config
 	neighborhood *Neighborhood
 	rip          chan KinAvatar // denouncements of newly discovered deceased kins
 	sync.Mutex
@@ -30,10 +32,15 @@ type Kin struct {
 
 const ServiceName = "kin"
 
-func NewKin() (k *Kin, xkin XKin, rip <-chan KinAvatar) {
+// ErrSelf is returned by ReJoin when the address to join is that of this kin.
+var ErrSelf = errors.New("cannot join self")
+
+// NewKin creates a kin service, whose topology is governed by cfg, which must be valid.
+func NewKin(cfg Config) (k *Kin, xkin XKin, rip <-chan KinAvatar) {
 	k = &Kin{
+		cfg:          cfg,
 		neighborhood: NewNeighborhood(),
-		rip:          make(chan KinAvatar, ExpansionHigh),
+		rip:          make(chan KinAvatar, cfg.ExpansionHigh),
 		topic:        make(map[string]FolkAvatar),
 	}
 	// Create a KinAvatar for this system.
@@ -52,12 +59,16 @@ func (k *Kin) ReJoin(join n.Addr) (err error) {
 			err = fmt.Errorf("panic joining: %v", r)
 		}
 	}()
+	x := circuit.Dial(join, ServiceName)
+	if x.Addr().WorkerID() == k.kinav.X.Addr().WorkerID() {
+		return ErrSelf
+	}
 	ykin := YKin{
 		KinAvatar{
-			X: circuit.Dial(join, ServiceName),
+			X: x,
 		},
 	}
-	for _, peer := range ykin.Join(k.chooseBoundary(Spread), Spread) {
+	for _, peer := range ykin.Join(k.chooseBoundary(k.cfg.Spread), k.cfg.Spread) {
 		peer = k.remember(peer)
 	}
 	return nil
@@ -74,7 +85,7 @@ func (k *Kin) chooseBoundary(spread int) []KinAvatar {
 	for i := 0; i+1 < spread; i++ {
 		// Take a random walk starting from this node and save the terminal node.
 		// This simulates a random node sample from the network.
-		peerAvatar := XKin{k}.Walk(Depth)
+		peerAvatar := XKin{k}.Walk(k.cfg.Depth)
 		if Avatar(peerAvatar).IsNil() {
 			continue
 		}
@@ -105,13 +116,13 @@ func (k *Kin) remember(peer KinAvatar) KinAvatar {
 	k.neighborhood.Add(Avatar(peer))
 	for _, folk := range k.users() {
 		p := YKin{peer}.Attach(folk.topic)
+		p.ID = peer.ID // use the kin ID
 		p.X = ForwardPanic(
 			p.X,
 			func(interface{}) {
 				k.forget(p.ID)
 			},
 		)
-		p.ID = peer.ID // use the kin ID
 		folk.addPeer(p)
 	}
 	k.shrink()
@@ -120,7 +131,7 @@ func (k *Kin) remember(peer KinAvatar) KinAvatar {
 
 // If the neighborhood is too big, shrink shrinks it to size ExpansionHigh.
 func (k *Kin) shrink() {
-	for i := 0; i < k.neighborhood.Len()-ExpansionHigh; i++ {
+	for i := 0; i < k.neighborhood.Len()-k.cfg.ExpansionHigh; i++ {
 		av, ok := k.neighborhood.ScrubRandom()
 		if !ok {
 			return
@@ -147,13 +158,33 @@ func (k *Kin) Avatar() KinAvatar {
 	return k.kinav
 }
 
+// Neighbors returns the kin peers currently in the neighborhood of this kin.
+func (k *Kin) Neighbors() []KinAvatar {
+	view := k.neighborhood.View()
+	r := make([]KinAvatar, len(view))
+	for i, av := range view {
+		r[i] = KinAvatar(av)
+	}
+	return r
+}
+
+// Folk returns the folk of every topic attached to this kin.
+func (k *Kin) Folk() []*Folk {
+	return k.users()
+}
+
+// Config returns the topology parameters of this kin.
+func (k *Kin) Config() Config {
+	return k.cfg
+}
+
 // If the neighborhood is too small, expand chooses random peers to refill it.
 func (k *Kin) expand() {
-	if k.neighborhood.Len() < ExpansionLow {
+	if k.neighborhood.Len() >= k.cfg.ExpansionLow {
 		return
 	}
-	for i := 0; i < ExpansionHigh-k.neighborhood.Len(); i++ {
-		w := XKin{k}.Walk(Depth)                        // Choose a random peer, using a random walk
+	for i := 0; i < k.cfg.ExpansionHigh-k.neighborhood.Len(); i++ {
+		w := XKin{k}.Walk(k.cfg.Depth)                  // Choose a random peer, using a random walk
 		if Avatar(w).IsNil() || w.ID == k.Avatar().ID { // Compare just IDs, in case we got pointers to ourselves from elsewhere
 			continue // If peer is nil or self, ignore it
 		}
@@ -176,6 +207,7 @@ func (k *Kin) Attach(topic string, folkAvatar FolkAvatar) *Folk {
 	for _, av := range neighbors {
 		kinAvatar := KinAvatar(av)
 		if folkAvatar := (YKin{kinAvatar}).Attach(topic); folkAvatar.X != nil {
+			folkAvatar.ID = kinAvatar.ID // use the kin ID, as neighbors lacking the topic are skipped
 			peers = append(peers, folkAvatar)
 		}
 	}
@@ -190,10 +222,9 @@ func (k *Kin) Attach(topic string, folkAvatar FolkAvatar) *Folk {
 		kin:          k,
 		ch:           make(chan FolkAvatar, len(peers)), // make sure initial set can be sent unblocked
 	}
-	for i, peer := range peers {
+	for _, peer := range peers {
 		// Rig the peers to be removed from the folk when their method calls cause panic
-		key := neighbors[i].ID
-		peer.ID = key
+		key := peer.ID
 		peer.X = ForwardPanic(
 			peer.X,
 			func(interface{}) {
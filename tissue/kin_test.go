@@ -0,0 +1,66 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package tissue
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/gocircuit/circuit/kit/lang"
+	rt "github.com/gocircuit/circuit/sys/lang"
+	"github.com/gocircuit/circuit/use/circuit"
+)
+
+// testPeer is a remote kin whose random walks end at fresh peers.
+type testPeer struct {
+	circuit.PermX
+	walks *int
+	lk    *sync.Mutex
+}
+
+func newTestPeer(walks *int, lk *sync.Mutex) KinAvatar {
+	return KinAvatar{X: testPeer{walks: walks, lk: lk}, ID: lang.ChooseReceiverID()}
+}
+
+func (p testPeer) Call(proc string, in ...interface{}) []interface{} {
+	switch proc {
+	case "Walk":
+		p.lk.Lock()
+		*p.walks++
+		p.lk.Unlock()
+		return []interface{}{newTestPeer(p.walks, p.lk)}
+	case "Attach":
+		return []interface{}{FolkAvatar{}}
+	}
+	panic("unexpected call " + proc)
+}
+
+var bindOnce sync.Once
+
+func TestExpand(t *testing.T) {
+	bindOnce.Do(func() { circuit.Bind(rt.New(rt.NewSandbox())) })
+	var (
+		walks int
+		lk    sync.Mutex
+	)
+	k, _, _ := NewKin(Config{ExpansionLow: 3, ExpansionHigh: 5, Spread: 1, Depth: 40})
+	k.neighborhood.Add(Avatar(newTestPeer(&walks, &lk)))
+	k.expand()
+	if n := k.neighborhood.Len(); n < 3 || n > 5 {
+		t.Fatalf("expecting a neighborhood refilled to between 3 and 5 peers, got %d", n)
+	}
+	lk.Lock()
+	before := walks
+	lk.Unlock()
+	k.expand()
+	lk.Lock()
+	defer lk.Unlock()
+	if walks != before {
+		t.Fatalf("expand walked, although the neighborhood was large enough")
+	}
+}
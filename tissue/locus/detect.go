@@ -0,0 +1,102 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package locus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gocircuit/circuit/kit/pubsub"
+	"github.com/gocircuit/circuit/kit/stat"
+	"github.com/gocircuit/circuit/tissue/tube"
+)
+
+// detectorWindow is the number of heartbeat inter-arrival times kept for each peer.
+const detectorWindow = 100
+
+// detector judges the liveness of peers from their heartbeats, which are the updates of their peer records.
+// Peers whose suspicion level reaches the suspect threshold are suspected, until their next heartbeat.
+// Peers whose suspicion level reaches the failure threshold are reported as failed.
+type detector struct {
+	cfg Config
+	sync.Mutex
+	peer    map[string]*stat.PhiAccrual
+	suspect map[string]bool
+	pub     *pubsub.PubSub // records of newly suspected peers
+}
+
+func newDetector(cfg Config) *detector {
+	return &detector{
+		cfg:     cfg,
+		peer:    make(map[string]*stat.PhiAccrual),
+		suspect: make(map[string]bool),
+		pub:     pubsub.New("suspect", nil),
+	}
+}
+
+// heartbeat records the arrival of a new revision of a peer record.
+func (d *detector) heartbeat(r *tube.Record) {
+	d.Lock()
+	defer d.Unlock()
+	x, ok := d.peer[r.Key]
+	if !ok {
+		first := d.cfg.Announce
+		if p, ok := r.Value.(*Peer); ok && p.Announce > 0 {
+			first = p.Announce // as announced by the peer
+		}
+		x = stat.NewPhiAccrual(detectorWindow, first, first/4, d.cfg.Expire-d.cfg.Announce)
+		d.peer[r.Key] = x
+	}
+	x.Heartbeat(r.Updated)
+	delete(d.suspect, r.Key)
+}
+
+// judge updates the suspicion of the peers with records rr at time now, and returns the records of failed peers.
+// Peers without records are no longer tracked.
+func (d *detector) judge(rr []*tube.Record, now time.Time) (failed []*tube.Record) {
+	d.Lock()
+	defer d.Unlock()
+	live := make(map[string]bool)
+	for _, r := range rr {
+		live[r.Key] = true
+		x, ok := d.peer[r.Key]
+		if !ok {
+			continue
+		}
+		phi := x.Phi(now)
+		if phi >= d.cfg.FailPhi {
+			failed = append(failed, r)
+		}
+		if phi >= d.cfg.SuspectPhi && !d.suspect[r.Key] {
+			d.suspect[r.Key] = true
+			d.pub.Publish(r)
+		}
+	}
+	for key := range d.peer {
+		if !live[key] {
+			delete(d.peer, key)
+			delete(d.suspect, key)
+		}
+	}
+	return failed
+}
+
+// forget stops tracking the peer with the given key.
+func (d *detector) forget(key string) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.peer, key)
+	delete(d.suspect, key)
+}
+
+// suspected returns true if the peer with the given key is suspected.
+func (d *detector) suspected(key string) bool {
+	d.Lock()
+	defer d.Unlock()
+	return d.suspect[key]
+}
@@ -0,0 +1,196 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package locus
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gocircuit/circuit/anchor"
+	"github.com/gocircuit/circuit/kit/pubsub"
+	"github.com/gocircuit/circuit/tissue"
+	"github.com/gocircuit/circuit/tissue/tube"
+	"github.com/gocircuit/circuit/use/n"
+)
+
+// lostPeer is a departed peer, which may have been separated from this server by a network partition.
+type lostPeer struct {
+	addr  n.Addr
+	lost  time.Time
+	tried time.Time // time of the last attempt to rejoin the peer
+}
+
+// healer remembers the addresses of departed peers and periodically attempts to rejoin them.
+// A peer that departed because of a network partition, rather than a failure, is reachable
+// again once connectivity returns. Rejoining it merges the two sides of the partition.
+//
+// Departures of many peers at about the same time are reported as a partition,
+// and successful rejoins are reported as merges, both as events of the server element.
+// Peers that withdrew from the circuit, when drained, are neither retried nor counted as departures.
+type healer struct {
+	cfg     Config
+	kin     *tissue.Kin
+	publish func(kind, detail string) // publishes an event of the server element
+	sync.Mutex
+	lost        map[string]*lostPeer
+	size        int         // number of known peers, other than self
+	departed    []time.Time // recent departures
+	partitioned bool
+}
+
+func newHealer(cfg Config, kin *tissue.Kin, term *anchor.Terminal) *healer {
+	return &healer{
+		cfg: cfg,
+		kin: kin,
+		publish: func(kind, detail string) {
+			term.Publish(anchor.Server, kind, detail)
+		},
+		lost: make(map[string]*lostPeer),
+	}
+}
+
+// loopArrivals updates the healer with peers joining the view of this server.
+func (h *healer) loopArrivals(sub *pubsub.Subscription, self string) {
+	for {
+		v, ok := sub.Consume()
+		if !ok {
+			return
+		}
+		if r := v.(*tube.Record); r.Key != self {
+			h.arrive(r.Key)
+		}
+	}
+}
+
+// loopDepartures updates the healer with peers leaving the view of this server.
+func (h *healer) loopDepartures(sub *pubsub.Subscription, self string) {
+	for {
+		v, ok := sub.Consume()
+		if !ok {
+			return
+		}
+		r := v.(*tube.Record)
+		p, ok := r.Value.(*Peer)
+		switch {
+		case !ok || r.Key == self:
+		case p.Left:
+			h.leave(r.Key)
+		default:
+			h.depart(r.Key, p.Kin.X.Addr(), time.Now())
+		}
+	}
+}
+
+func (h *healer) arrive(key string) {
+	h.Lock()
+	defer h.Unlock()
+	h.size++
+	delete(h.lost, key)
+}
+
+// leave accounts for a peer that withdrew from the circuit.
+func (h *healer) leave(key string) {
+	h.Lock()
+	defer h.Unlock()
+	if h.size > 0 {
+		h.size--
+	}
+	delete(h.lost, key)
+}
+
+// depart remembers a departed peer and reports a partition, if at least half of the peers known
+// before the departures of the last two expire durations have departed, and at least two.
+func (h *healer) depart(key string, addr n.Addr, now time.Time) {
+	h.Lock()
+	defer h.Unlock()
+	if h.size > 0 {
+		h.size--
+	}
+	h.lost[key] = &lostPeer{addr: addr, lost: now}
+	recent := h.departed[:0]
+	for _, t := range h.departed {
+		if now.Sub(t) < 2*h.cfg.Expire {
+			recent = append(recent, t)
+		}
+	}
+	h.departed = append(recent, now)
+	n := len(h.departed)
+	if h.partitioned || n < 2 || 2*n < n+h.size {
+		return
+	}
+	h.partitioned = true
+	detail := fmt.Sprintf("lost %d of %d peers", n, n+h.size)
+	log.Println("Partition suspected,", detail)
+	h.publish(anchor.EventPartition, detail)
+}
+
+// probeTimeout bounds the time spent probing a lost peer.
+const probeTimeout = time.Second
+
+// loopHeal attempts to rejoin one lost peer every heal interval, taking the lost peers in turn.
+// Rejoining dials the peer through the circuit transport, which serializes dials,
+// so the peer is first probed directly, with a short timeout, and rejoined only if it is reachable.
+func (h *healer) loopHeal() {
+	for {
+		time.Sleep(h.cfg.Heal)
+		key, p := h.next(time.Now())
+		if p == nil || !probe(p.addr) {
+			continue // nobody lost or still unreachable
+		}
+		if err := h.kin.ReJoin(p.addr); err != nil {
+			continue
+		}
+		h.merge(key)
+	}
+}
+
+// probe returns true if the peer at addr accepts connections.
+func probe(addr n.Addr) bool {
+	conn, err := net.DialTimeout("tcp", addr.NetAddr().String(), probeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// next forgets the peers lost longer than the remember duration ago, and returns the remaining
+// peer whose rejoin was attempted least recently, or nil if none remain.
+func (h *healer) next(now time.Time) (key string, q *lostPeer) {
+	h.Lock()
+	defer h.Unlock()
+	for k, p := range h.lost {
+		if now.Sub(p.lost) > h.cfg.Remember {
+			delete(h.lost, k)
+			continue
+		}
+		if q == nil || p.tried.Before(q.tried) {
+			key, q = k, p
+		}
+	}
+	if q == nil {
+		h.partitioned = false
+		return "", nil
+	}
+	q.tried = now
+	return key, q
+}
+
+func (h *healer) merge(key string) {
+	h.Lock()
+	delete(h.lost, key)
+	if len(h.lost) == 0 {
+		h.partitioned = false
+	}
+	h.Unlock()
+	log.Println("Rejoined lost peer", key)
+	h.publish(anchor.EventMerge, key)
+}
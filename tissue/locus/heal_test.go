@@ -0,0 +1,121 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package locus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocircuit/circuit/anchor"
+)
+
+// newTestHealer returns a healer that knows of the given number of peers and records the events it publishes.
+func newTestHealer(peers int, events *[]string) *healer {
+	h := &healer{
+		cfg: DefaultConfig,
+		publish: func(kind, detail string) {
+			*events = append(*events, kind+" "+detail)
+		},
+		lost: make(map[string]*lostPeer),
+	}
+	for i := 0; i < peers; i++ {
+		h.arrive(string(rune('a' + i)))
+	}
+	return h
+}
+
+func TestHealPartition(t *testing.T) {
+	var events []string
+	h := newTestHealer(4, &events)
+	now := time.Now()
+	h.depart("a", nil, now)
+	if len(events) != 0 {
+		t.Fatalf("single departure reported as %v", events)
+	}
+	h.depart("b", nil, now.Add(time.Second))
+	if len(events) != 1 || events[0] != anchor.EventPartition+" lost 2 of 4 peers" {
+		t.Fatalf("expecting a partition of 2 of 4 peers, got %v", events)
+	}
+	h.depart("c", nil, now.Add(2*time.Second))
+	if len(events) != 1 {
+		t.Fatalf("partition reported again, %v", events)
+	}
+
+	// Departures further apart than twice the expire duration are failures.
+	events = nil
+	h = newTestHealer(4, &events)
+	h.depart("a", nil, now)
+	h.depart("b", nil, now.Add(2*h.cfg.Expire))
+	if len(events) != 0 {
+		t.Fatalf("spread departures reported as %v", events)
+	}
+
+	// Peers that withdrew from the circuit are not departures.
+	h = newTestHealer(4, &events)
+	h.leave("a")
+	h.leave("b")
+	h.depart("c", nil, now)
+	if len(events) != 0 {
+		t.Fatalf("withdrawals reported as %v", events)
+	}
+	if h.size != 1 || len(h.lost) != 1 {
+		t.Fatalf("expecting 1 known and 1 lost peer, got %d and %d", h.size, len(h.lost))
+	}
+}
+
+func TestHealLost(t *testing.T) {
+	var events []string
+	h := newTestHealer(4, &events)
+	now := time.Now()
+	h.depart("a", nil, now)
+	h.depart("b", nil, now.Add(time.Second))
+	h.depart("c", nil, now.Add(time.Hour/2))
+
+	// Lost peers are retried in turn.
+	tried := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		key, p := h.next(now.Add(time.Hour/2 + time.Duration(i)*time.Second))
+		if p == nil || tried[key] {
+			t.Fatalf("expecting a lost peer not yet retried, got %q", key)
+		}
+		tried[key] = true
+	}
+
+	// A peer that arrives again is no longer lost.
+	h.arrive("c")
+	if _, ok := h.lost["c"]; ok {
+		t.Fatalf("arrived peer still lost")
+	}
+
+	// Peers lost longer ago than the remember duration are forgotten.
+	if key, p := h.next(now.Add(h.cfg.Remember + time.Hour/4)); p != nil {
+		t.Fatalf("peer %s not forgotten", key)
+	}
+	if len(h.lost) != 0 || h.partitioned {
+		t.Fatalf("expecting no lost peers and no partition, got %d lost and partition %v", len(h.lost), h.partitioned)
+	}
+}
+
+func TestHealMerge(t *testing.T) {
+	var events []string
+	h := newTestHealer(4, &events)
+	now := time.Now()
+	h.depart("a", nil, now)
+	h.depart("b", nil, now)
+	h.merge("a")
+	if !h.partitioned {
+		t.Fatalf("partition ended with a lost peer remaining")
+	}
+	h.merge("b")
+	if h.partitioned || len(h.lost) != 0 {
+		t.Fatalf("partition not ended after merging all lost peers")
+	}
+	if len(events) != 3 || events[1] != anchor.EventMerge+" a" || events[2] != anchor.EventMerge+" b" {
+		t.Fatalf("expecting a partition and two merges, got %v", events)
+	}
+}
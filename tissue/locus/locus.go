@@ -8,12 +8,16 @@
 package locus
 
 import (
+	"errors"
 	"log"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/gocircuit/circuit/anchor"
+	"github.com/gocircuit/circuit/element/dns"
 	srv "github.com/gocircuit/circuit/element/server"
+	"github.com/gocircuit/circuit/element/table"
 	"github.com/gocircuit/circuit/kit/pubsub"
 	"github.com/gocircuit/circuit/tissue"
 	"github.com/gocircuit/circuit/tissue/tube"
@@ -23,37 +27,128 @@ import (
 // Locus is a device that listens to the join/leave events reported by the tissue social
 // system, and maintains an asynchronously-readable current list of known peers.
 type Locus struct {
-	Peer *Peer      // Client peer enclosure for this circuit locus
+	Peer *Peer // Client peer enclosure for this circuit locus
+	cfg  Config
+	det  *detector  // Failure detector for peers
 	tube *tube.Tube // Kinfolk broadcasting system
+	dns  *tube.Tube // Records of replicated nameservers
+	tbl  *tube.Tube // Entries of replicated tables
+	sync.Mutex
+	rev  tube.Rev // revision of the last announcement of this server
+	left bool     // set once this server has withdrawn from the circuit
 }
 
-// NewLocus creates a new locus device.
-func NewLocus(kin *tissue.Kin, rip <-chan tissue.KinAvatar) XLocus {
+// Config holds the timing of the membership protocol.
+//
+// Each announcement of a peer is a heartbeat for a phi-accrual failure detector, which learns the
+// distribution of the intervals between the peer's heartbeats. The suspicion level phi of a peer
+// grows with the time since its last heartbeat, from about zero while the peer is on time.
+// A phi of p means that a heartbeat as late would have been seen once in 10^p intervals.
+type Config struct {
+	// Announce is the interval between announcements of this server's peer record.
+	Announce time.Duration
+	// Expire is the age at which peer records that have not been re-announced begin to raise suspicion.
+	// Heartbeats up to Expire-Announce later than usual are tolerated.
+	Expire time.Duration
+	// Peers are suspected when their suspicion level reaches SuspectPhi, until their next heartbeat.
+	SuspectPhi float64
+	// Peers are declared dead, and forgotten, when their suspicion level reaches FailPhi.
+	FailPhi float64
+	// Heal is the interval between attempts to rejoin departed peers, which may have been separated
+	// from this server by a network partition. Zero disables healing.
+	Heal time.Duration
+	// Remember is the duration for which departed peers are remembered and retried.
+	Remember time.Duration
+	// Entropy is the interval between reconciliations of the tubes of this server with those of
+	// a random peer, which repair updates lost in transit. Zero disables reconciliation.
+	Entropy time.Duration
+}
+
+// DefaultConfig holds the default membership timing, suitable for local networks.
+var DefaultConfig = Config{
+	Announce:   2 * time.Second,
+	Expire:     4 * time.Second,
+	SuspectPhi: 3,
+	FailPhi:    8,
+	Heal:       10 * time.Second,
+	Remember:   time.Hour,
+	Entropy:    30 * time.Second,
+}
+
+// Validate returns an error if the timing of c is not consistent.
+func (c Config) Validate() error {
+	switch {
+	case c.Announce <= 0:
+		return errors.New("announce interval must be positive")
+	case c.Expire < 2*c.Announce:
+		return errors.New("expire duration must be at least twice the announce interval")
+	case c.SuspectPhi <= 0:
+		return errors.New("suspect threshold must be positive")
+	case c.FailPhi < c.SuspectPhi:
+		return errors.New("failure threshold must be at least the suspect threshold")
+	case c.Heal < 0:
+		return errors.New("heal interval must not be negative")
+	case c.Remember < 0:
+		return errors.New("remember duration must not be negative")
+	case c.Entropy < 0:
+		return errors.New("entropy interval must not be negative")
+	}
+	return nil
+}
+
+// NewLocus creates a new locus device, whose membership timing is governed by cfg, which must be valid.
+func NewLocus(kin *tissue.Kin, rip <-chan tissue.KinAvatar, cfg Config) XLocus {
 	locus := &Locus{
+		cfg:  cfg,
+		det:  newDetector(cfg),
 		tube: tube.NewTube(kin, "locus"),
+		dns:  tube.NewTube(kin, dns.ReplicaTopic),
+		tbl:  tube.NewTube(kin, table.ReplicaTopic),
 	}
 	term, xterm := anchor.NewTerm(kin.Avatar().ID.String(), locus)
-	term.Attach(anchor.Server, srv.New(kin))
+	drain := func(spec srv.DrainSpec) {
+		locus.drain(term, spec)
+	}
+	term.Attach(anchor.Server, srv.New(kin, cfg.Announce, cfg.Expire, drain, locus.tube, locus.dns, locus.tbl))
+	term.Revive()
 	locus.Peer = &Peer{
 		// It is crucial to use permanent cross-references, and not
 		// "plain" ones within values stored inside the tube table. If
 		// cross-references are used, they are managed by the cross-
 		// garbage collection system and therefore connections to ALL
 		// underlying workers are maintained superfluously.
-		Kin:  kin.Avatar(),
-		Term: xterm,
+		Kin:      kin.Avatar(),
+		Term:     xterm,
+		Announce: cfg.Announce,
+	}
+	heartbeats, err := locus.tube.NewUpdates(0, nil)
+	if err != nil {
+		panic(err)
 	}
 	go locus.loopRIP(rip)
-	go locus.loopAnnounceAndExpire()
+	go locus.loopHeartbeats(heartbeats)
+	go locus.loopAnnounceAndDetect()
+	go table.Reap(locus.tbl, cfg.Announce)
+	go dns.Reap(locus.dns, cfg.Announce)
+	if cfg.Entropy > 0 {
+		for _, t := range []*tube.Tube{locus.tube, locus.dns, locus.tbl} {
+			go t.AntiEntropy(cfg.Entropy)
+		}
+	}
+	if cfg.Heal > 0 {
+		locus.heal(kin, term)
+	}
 	return XLocus{locus}
 }
 
-// GetPeers asynchronously returns the current known list of live peers.
+// GetPeers asynchronously returns the current known list of live peers, including suspected ones.
 func (locus *Locus) GetPeers() []*Peer {
 	rr := locus.tube.BulkRead()
 	s := make([]*Peer, len(rr))
 	for i, r := range rr {
-		s[i] = r.Value.(*Peer)
+		p := *r.Value.(*Peer)
+		p.Suspect = locus.det.suspected(r.Key)
+		s[i] = &p
 	}
 	return s
 }
@@ -76,35 +171,128 @@ func (a *peerSubscription) X() circuit.X {
 }
 
 func (a *peerSubscription) Consume() (interface{}, bool) {
-	v, ok := a.Consumer.Consume()
+	_, v, ok := a.ConsumeSeq()
+	return v, ok
+}
+
+func (a *peerSubscription) ConsumeSeq() (int64, interface{}, bool) {
+	seq, v, ok := a.Consumer.ConsumeSeq()
 	if !ok {
-		return nil, false
+		return 0, nil, false
 	}
-	return path.Join("/", v.(*tube.Record).Key), true
+	return seq, peerPath(v), true
 }
 
-func (locus *Locus) NewArrivals() pubsub.Consumer {
-	return &peerSubscription{locus.tube.NewArrivals()}
+// peerPath returns the anchor path of the server whose peer record is v.
+func peerPath(v interface{}) string {
+	return path.Join("/", v.(*tube.Record).Key)
 }
 
-func (locus *Locus) NewDepartures() pubsub.Consumer {
-	return &peerSubscription{locus.tube.NewDepartures()}
+// peerMatch adapts a match on server anchor paths to a match on peer records.
+func peerMatch(match pubsub.Match) pubsub.Match {
+	if match == nil {
+		return nil
+	}
+	return func(v interface{}) bool {
+		return match(peerPath(v))
+	}
 }
 
-// loopAnnounceAndExpire writes a new version of this server's peer record to the tube view every 2 seconds,
-// then it iterates through all peer records in the tube view, forgetting those older than 4 seconds.
-func (locus *Locus) loopAnnounceAndExpire() {
-	const GarbageDuration = time.Second * 4
-	var rev tube.Rev
+func (locus *Locus) NewArrivals(from int64, match pubsub.Match) (pubsub.Consumer, error) {
+	sub, err := locus.tube.NewArrivals(from, peerMatch(match))
+	if err != nil {
+		return nil, err
+	}
+	return &peerSubscription{sub}, nil
+}
+
+func (locus *Locus) NewDepartures(from int64, match pubsub.Match) (pubsub.Consumer, error) {
+	sub, err := locus.tube.NewDepartures(from, peerMatch(match))
+	if err != nil {
+		return nil, err
+	}
+	return &peerSubscription{sub}, nil
+}
+
+// NewSuspects returns a subscription to the stream of servers that become suspected of failure.
+// Suspected servers may recover, without departing, or depart later.
+func (locus *Locus) NewSuspects(from int64, match pubsub.Match) (pubsub.Consumer, error) {
+	sub, err := locus.det.pub.SubscribeFrom(from, peerMatch(match))
+	if err != nil {
+		return nil, err
+	}
+	return &peerSubscription{sub}, nil
+}
+
+// loopAnnounceAndDetect writes a new version of this server's peer record to the tube view every announce interval,
+// then it iterates through all peer records in the tube view, denouncing the peers deemed failed by the failure detector.
+func (locus *Locus) loopAnnounceAndDetect() {
+	self := locus.Peer.Key()
 	for {
-		rev++
-		// log.Printf("(Re)announcing ourselves (%s,%d,%v)", locus.Peer.Key(), rev, locus.Peer)
-		locus.tube.Write(locus.Peer.Key(), rev, locus.Peer)
+		// log.Printf("(Re)announcing ourselves (%s,%v)", locus.Peer.Key(), locus.Peer)
+		if !locus.announce(self) {
+			return
+		}
 		//
-		time.Sleep(GarbageDuration / 2)
-		deadline := time.Now().Add(-GarbageDuration)
+		time.Sleep(locus.cfg.Announce)
+		var peers []*tube.Record
 		for _, r := range locus.tube.BulkRead() {
-			locus.tube.Forget(r.Key, 0, deadline)
+			if r.Key != self {
+				peers = append(peers, r)
+			}
+		}
+		for _, r := range locus.det.judge(peers, time.Now()) {
+			locus.denounce(r)
+		}
+	}
+}
+
+// announce writes the next revision of this server's peer record, unless the server has withdrawn from the circuit.
+func (locus *Locus) announce(self string) bool {
+	locus.Lock()
+	defer locus.Unlock()
+	if locus.left {
+		return false
+	}
+	locus.rev++
+	locus.tube.Write(self, locus.rev, locus.Peer)
+	return true
+}
+
+// withdraw stops the announcements of this server and removes its peer record from the tube,
+// returning after the removal has been pushed to the peers of this server.
+// The removed record is marked as left first, so that peers do not mistake the departure for a failure.
+func (locus *Locus) withdraw() {
+	locus.Lock()
+	locus.left = true
+	locus.rev++
+	rev := locus.rev
+	locus.Unlock()
+	log.Println("Withdrawing from the circuit")
+	self, p := locus.Peer.Key(), *locus.Peer
+	p.Left = true
+	locus.tube.WriteSync(self, rev, &p)
+	locus.tube.ScrubSync(self, 0, time.Time{})
+}
+
+// drain stops the elements of this server, as described by spec, and withdraws the server from the circuit.
+func (locus *Locus) drain(term *anchor.Terminal, spec srv.DrainSpec) {
+	if n := term.Drain(spec.Signal, spec.Wait); n > 0 {
+		log.Printf("%d processes still running", n)
+	}
+	locus.withdraw()
+}
+
+// loopHeartbeats feeds the updates of peer records to the failure detector.
+func (locus *Locus) loopHeartbeats(sub *pubsub.Subscription) {
+	self := locus.Peer.Key()
+	for {
+		v, ok := sub.Consume()
+		if !ok {
+			return
+		}
+		if r := v.(*tube.Record); r.Key != self {
+			locus.det.heartbeat(r)
 		}
 	}
 }
@@ -115,16 +303,60 @@ func (locus *Locus) loopRIP(rip <-chan tissue.KinAvatar) {
 		if !ok {
 			panic("u")
 		}
-		locus.denounce(kinAvatar)
+		peer := &Peer{Kin: kinAvatar}
+		if r := locus.tube.Lookup(peer.Key()); r != nil {
+			locus.denounce(r)
+		}
 	}
 }
 
-func (locus *Locus) denounce(kinAvatar tissue.KinAvatar) {
-	peer := &Peer{Kin: kinAvatar}
-	log.Println("Denouncing", peer.Key())
-	r := locus.tube.Lookup(peer.Key())
-	if r == nil {
-		return
+// denounce removes the peer record r from the tube, unless it has been updated since, and
+// asks the peers of this server to do the same.
+func (locus *Locus) denounce(r *tube.Record) {
+	log.Println("Denouncing", r.Key)
+	locus.det.forget(r.Key)
+	locus.tube.Scrub(r.Key, r.Rev, r.Updated)
+}
+
+// Hosts returns the live servers of the circuit, as currently known to this locus.
+func (locus *Locus) Hosts() []anchor.Host {
+	peers := locus.GetPeers()
+	r := make([]anchor.Host, 0, len(peers))
+	for _, p := range peers {
+		r = append(r, anchor.Host{
+			ID:   p.Key(),
+			Addr: p.Kin.X.Addr().NetAddr(),
+			Term: p.Term,
+		})
+	}
+	return r
+}
+
+// Tube returns the tube shared by all servers under the given topic, or nil if there is none.
+// Every server attaches to the same topics, so that the tubes form a connected overlay.
+func (locus *Locus) Tube(topic string) *tube.Tube {
+	switch topic {
+	case dns.ReplicaTopic:
+		return locus.dns
+	case table.ReplicaTopic:
+		return locus.tbl
+	}
+	return nil
+}
+
+// heal starts rejoining departed peers, which may be on the other side of a network partition.
+func (locus *Locus) heal(kin *tissue.Kin, term *anchor.Terminal) {
+	h := newHealer(locus.cfg, kin, term)
+	arrivals, err := locus.tube.NewArrivals(0, nil)
+	if err != nil {
+		panic(err)
+	}
+	departures, err := locus.tube.NewDepartures(0, nil)
+	if err != nil {
+		panic(err)
 	}
-	locus.tube.Scrub(peer.Key(), r.Rev, r.Updated)
+	self := locus.Peer.Key()
+	go h.loopArrivals(arrivals, self)
+	go h.loopDepartures(departures, self)
+	go h.loopHeal()
 }
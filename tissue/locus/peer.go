@@ -9,6 +9,7 @@ package locus
 
 import (
 	"encoding/gob"
+	"time"
 
 	"github.com/gocircuit/circuit/kit/lang"
 	"github.com/gocircuit/circuit/tissue"
@@ -18,8 +19,11 @@ import (
 // Peer encloses a cross-interface to the tissue system of a circuit worker, as well as
 // a cross-interface to its exported resource hierarchy.
 type Peer struct {
-	Kin  tissue.KinAvatar // Cross-interface to the kin system at this locus
-	Term circuit.PermX    // Cross-interface to anchor.XTerminal
+	Kin      tissue.KinAvatar // Cross-interface to the kin system at this locus
+	Term     circuit.PermX    // Cross-interface to anchor.XTerminal
+	Announce time.Duration    // Interval between announcements of this peer
+	Suspect  bool             // Set in views of the peers that are suspected of failure
+	Left     bool             // Set in the final record of a peer that withdrew from the circuit
 }
 
 func (i Peer) Key() string {
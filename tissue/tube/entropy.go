@@ -0,0 +1,281 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package tube
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Anti-entropy
+//
+// Writes reach the peers of a tube by push only. An update that is lost in transit, for instance
+// because a peer was briefly unreachable, leaves the views of the two tubes divergent.
+// Periodically, each tube reconciles its view with that of a random downstream peer, in three steps
+// of decreasing cost: the tubes compare the root hashes of their views; if these differ,
+// they compare the hashes of the buckets the keys are distributed over; and finally they exchange
+// the records of the buckets that differ, each tube keeping the later revision of every key.
+//
+// Records that are forgotten are remembered for ForgetMemory, so that reconciliation with peers
+// that have not forgotten them yet does not revive them.
+
+// DigestBuckets is the number of buckets the keys of a tube are distributed over for reconciliation.
+const DigestBuckets = 64
+
+// ForgetMemory is the duration for which forgotten records are not revived by reconciliation.
+const ForgetMemory = 10 * time.Minute
+
+// Digest holds a hash of the keys and revisions of the records in each bucket of a tube view.
+type Digest []uint64
+
+// Sum returns the root hash of the digest.
+func (d Digest) Sum() uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	for _, x := range d {
+		binary.BigEndian.PutUint64(b[:], x)
+		h.Write(b[:])
+	}
+	return h.Sum64()
+}
+
+// Diff returns the buckets whose hashes differ in d and e.
+func (d Digest) Diff(e Digest) (buckets []int) {
+	for i := range d {
+		if i >= len(e) || d[i] != e[i] {
+			buckets = append(buckets, i)
+		}
+	}
+	return
+}
+
+// bucket returns the digest bucket of key.
+func bucket(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % DigestBuckets)
+}
+
+// hashRecord returns the hash of the key and revision of r.
+// Bucket hashes combine record hashes with exclusive-or, so they do not depend on the order of records.
+func hashRecord(r *Record) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(r.Key))
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(r.Rev))
+	h.Write(b[:])
+	return h.Sum64()
+}
+
+func digest(img []*Record) Digest {
+	d := make(Digest, DigestBuckets)
+	for _, r := range img {
+		d[bucket(r.Key)] ^= hashRecord(r)
+	}
+	return d
+}
+
+// Digest returns the digest of the current view.
+func (t *Tube) Digest() Digest {
+	return digest(t.BulkRead())
+}
+
+// Pull returns the records of the current view that belong to the given buckets.
+func (t *Tube) Pull(buckets []int) []*Record {
+	want := make(map[int]bool)
+	for _, b := range buckets {
+		want[b] = true
+	}
+	var r []*Record
+	for _, rec := range t.BulkRead() {
+		if want[bucket(rec.Key)] {
+			r = append(r, rec)
+		}
+	}
+	return r
+}
+
+// RepairStat holds counters of the reconciliation traffic of a tube.
+type RepairStat struct {
+	Rounds   int64 // reconciliations attempted
+	Diverged int64 // reconciliations that found the views divergent
+	Buckets  int64 // divergent buckets exchanged
+	Pulled   int64 // records received from peers that changed the local view
+	Pushed   int64 // records sent to peers whose views were missing or behind
+}
+
+// repair accounts for the reconciliation of a tube, and remembers its forgotten records.
+type repair struct {
+	sync.Mutex
+	stat   RepairStat
+	forgot map[string]forgotten
+}
+
+type forgotten struct {
+	rev Rev
+	at  time.Time
+}
+
+func (r *repair) forget(key string, rev Rev, now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	if r.forgot == nil {
+		r.forgot = make(map[string]forgotten)
+	}
+	r.forgot[key] = forgotten{rev: rev, at: now}
+}
+
+// forgotten returns true if rec is a revision of a record that was recently forgotten.
+func (r *repair) forgotten(rec *Record, now time.Time) bool {
+	r.Lock()
+	defer r.Unlock()
+	f, ok := r.forgot[rec.Key]
+	return ok && rec.Rev <= f.rev && now.Sub(f.at) < ForgetMemory
+}
+
+// expire drops forgotten records older than ForgetMemory.
+func (r *repair) expire(now time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	for key, f := range r.forgot {
+		if now.Sub(f.at) >= ForgetMemory {
+			delete(r.forgot, key)
+		}
+	}
+}
+
+func (r *repair) count(f func(*RepairStat)) {
+	r.Lock()
+	defer r.Unlock()
+	f(&r.stat)
+}
+
+// RepairStat returns the counters of the reconciliation traffic of this tube.
+func (t *Tube) RepairStat() RepairStat {
+	t.repair.Lock()
+	defer t.repair.Unlock()
+	return t.repair.stat
+}
+
+// AntiEntropy reconciles the view of this tube with that of a random downstream peer every interval.
+func (t *Tube) AntiEntropy(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		t.repair.expire(time.Now())
+		peers := t.folk.Opened()
+		if len(peers) == 0 {
+			continue
+		}
+		t.reconcile(YTube{peers[rand.Intn(len(peers))]})
+	}
+}
+
+// reconcile brings the views of this tube and of peer up to date with each other.
+func (t *Tube) reconcile(peer YTube) {
+	t.repair.count(func(s *RepairStat) { s.Rounds++ })
+	local := t.Digest()
+	sum, ok := peer.Sum()
+	if !ok || sum == local.Sum() {
+		return
+	}
+	remote := peer.Digest()
+	if remote == nil {
+		return
+	}
+	buckets := local.Diff(remote)
+	if len(buckets) == 0 {
+		return
+	}
+	theirs, ok := peer.Pull(buckets)
+	if !ok {
+		return
+	}
+	// Pull the records that are newer at the peer
+	pulled := t.Merge(theirs)
+	// Push the records that are newer here
+	rev := make(map[string]Rev)
+	for _, r := range theirs {
+		rev[r.Key] = r.Rev
+	}
+	var push []*Record
+	for _, r := range t.Pull(buckets) {
+		if cur, ok := rev[r.Key]; !ok || cur < r.Rev {
+			push = append(push, r)
+		}
+	}
+	if len(push) > 0 {
+		peer.Merge(push)
+	}
+	t.repair.count(func(s *RepairStat) {
+		s.Diverged++
+		s.Buckets += int64(len(buckets))
+		s.Pulled += int64(pulled)
+		s.Pushed += int64(len(push))
+	})
+}
+
+// Merge updates the local view with the given records of a peer, except for recently forgotten ones,
+// and pushes the resulting changes downstream. It returns the number of records that changed the view.
+func (t *Tube) Merge(bulk []*Record) int {
+	now := time.Now()
+	t.Lock()
+	defer t.Unlock()
+	changed := make([]*Record, 0, len(bulk))
+	for _, r := range bulk {
+		if t.repair.forgotten(r, now) {
+			continue
+		}
+		if t.view.Update(r) {
+			changed = append(changed, r)
+		}
+	}
+	if len(changed) > 0 {
+		go t.bulkWriteSync(changed)
+	}
+	return len(changed)
+}
+
+// Sum returns false if the peer is unreachable.
+func (y YTube) Sum() (sum uint64, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	return y.av.X.Call("Sum")[0].(uint64), true
+}
+
+// Digest returns nil if the peer is unreachable.
+func (y YTube) Digest() (d Digest) {
+	defer func() {
+		if r := recover(); r != nil {
+			d = nil
+		}
+	}()
+	return y.av.X.Call("Digest")[0].(Digest)
+}
+
+// Pull returns false if the peer is unreachable.
+func (y YTube) Pull(buckets []int) (bulk []*Record, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	return y.av.X.Call("Pull", buckets)[0].([]*Record), true
+}
+
+func (y YTube) Merge(bulk []*Record) {
+	defer func() {
+		recover()
+	}()
+	y.av.X.Call("Merge", bulk)
+}
@@ -0,0 +1,128 @@
+// Copyright 2013 The Go Circuit Project
+// Use of this source code is governed by the license for
+// The Go Circuit Project, found in the LICENSE file.
+//
+// Authors:
+//   2014 Petar Maymounkov <p@gocircuit.org>
+
+package tube
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gocircuit/circuit/kit/lang"
+	rt "github.com/gocircuit/circuit/sys/lang"
+	"github.com/gocircuit/circuit/tissue"
+	"github.com/gocircuit/circuit/use/circuit"
+)
+
+func TestDigest(t *testing.T) {
+	var a, b []*Record
+	for i := 0; i < 100; i++ {
+		r := &Record{Key: fmt.Sprintf("k%d", i), Rev: Rev(i + 1)}
+		a = append(a, r)
+		b = append([]*Record{r}, b...)
+	}
+	da, db := digest(a), digest(b)
+	if da.Sum() != db.Sum() || len(da.Diff(db)) != 0 {
+		t.Fatalf("digests of the same records in different order differ")
+	}
+	b[0] = &Record{Key: b[0].Key, Rev: b[0].Rev + 1}
+	db = digest(b)
+	if da.Sum() == db.Sum() {
+		t.Fatalf("digests of different revisions agree")
+	}
+	diff := da.Diff(db)
+	if len(diff) != 1 || diff[0] != bucket(b[0].Key) {
+		t.Fatalf("expecting bucket %d to differ, got %v", bucket(b[0].Key), diff)
+	}
+}
+
+func TestForgetMemory(t *testing.T) {
+	var r repair
+	now := time.Now()
+	r.forget("k", 5, now)
+	if !r.forgotten(&Record{Key: "k", Rev: 5}, now) {
+		t.Errorf("forgotten revision revived")
+	}
+	if r.forgotten(&Record{Key: "k", Rev: 6}, now) {
+		t.Errorf("later revision taken for forgotten")
+	}
+	if r.forgotten(&Record{Key: "k", Rev: 5}, now.Add(ForgetMemory)) {
+		t.Errorf("forgotten revision remembered past ForgetMemory")
+	}
+	r.expire(now.Add(ForgetMemory))
+	if len(r.forgot) != 0 {
+		t.Errorf("forgotten record not expired")
+	}
+}
+
+// testPeer is a remote tube that forwards the reconciliation calls to a local tube.
+type testPeer struct {
+	circuit.PermX
+	t *Tube
+}
+
+func (p testPeer) Call(proc string, in ...interface{}) []interface{} {
+	x := XTube{p.t}
+	switch proc {
+	case "Sum":
+		return []interface{}{x.Sum()}
+	case "Digest":
+		return []interface{}{x.Digest()}
+	case "Pull":
+		return []interface{}{x.Pull(in[0].([]int))}
+	case "Merge":
+		return []interface{}{x.Merge(in[0].([]*Record))}
+	}
+	panic("unexpected call " + proc)
+}
+
+func TestReconcile(t *testing.T) {
+	circuit.Bind(rt.New(rt.NewSandbox()))
+	k, _, _ := tissue.NewKin(tissue.DefaultConfig)
+	a, b := NewTube(k, "a"), NewTube(k, "b")
+	peer := YTube{tissue.FolkAvatar{X: testPeer{t: b}, ID: lang.ChooseReceiverID()}}
+
+	a.WriteSync("same", 1, nil)
+	b.WriteSync("same", 1, nil)
+	a.WriteSync("newer", 2, nil) // pushed
+	b.WriteSync("newer", 1, nil)
+	a.WriteSync("local", 1, nil)  // pushed
+	b.WriteSync("remote", 1, nil) // pulled
+	a.WriteSync("forgotten", 1, nil)
+	b.WriteSync("forgotten", 1, nil)
+	a.Forget("forgotten", 0, time.Time{}) // not revived
+	buckets := len(a.Digest().Diff(b.Digest()))
+
+	a.reconcile(peer)
+	if r := a.Lookup("forgotten"); r != nil {
+		t.Fatalf("forgotten record revived")
+	}
+	for _, key := range []string{"same", "newer", "local", "remote"} {
+		ra, rb := a.Lookup(key), b.Lookup(key)
+		if ra == nil || rb == nil || ra.Rev != rb.Rev {
+			t.Fatalf("views disagree on %s", key)
+		}
+	}
+	if r := b.Lookup("newer"); r.Rev != 2 {
+		t.Fatalf("expecting revision 2 of newer record, got %d", r.Rev)
+	}
+	want := RepairStat{Rounds: 1, Diverged: 1, Buckets: int64(buckets), Pulled: 1, Pushed: 2}
+	if s := a.RepairStat(); s != want {
+		t.Fatalf("expecting repair counters %+v, got %+v", want, s)
+	}
+
+	// Once the peer forgets the record as well, the views agree.
+	b.Forget("forgotten", 0, time.Time{})
+	if a.Digest().Sum() != b.Digest().Sum() {
+		t.Fatalf("views diverge after reconciliation")
+	}
+	a.reconcile(peer)
+	want.Rounds++
+	if s := a.RepairStat(); s != want {
+		t.Fatalf("expecting repair counters %+v, got %+v", want, s)
+	}
+}
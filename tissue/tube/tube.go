@@ -21,10 +21,12 @@ import (
 // Tube is a folk data structure that maintains a key-value set sorted by key.
 // …
 type Tube struct {
+	topic  string
 	av     tissue.FolkAvatar // Avatar to this tube
 	folk   *tissue.Folk   // Folk interface of this tube to the kin system
 	sync.Mutex
 	view  *View
+	repair repair // Reconciliation with peers
 }
 
 func init() {
@@ -33,7 +35,7 @@ func init() {
 
 // NewTube…
 func NewTube(kin *tissue.Kin, topic string) *Tube {
-	t := &Tube{view: NewView()}
+	t := &Tube{topic: topic, view: NewView()}
 	t.av = tissue.FolkAvatar{
 		X:  circuit.PermRef(XTube{t}),
 		ID: lang.ComputeReceiverID(t),
@@ -48,16 +50,30 @@ func NewTube(kin *tissue.Kin, topic string) *Tube {
 	return t
 }
 
+// Topic returns the topic of this tube.
+func (t *Tube) Topic() string {
+	return t.topic
+}
+
 // NewArrivals returns a subscription for the stream of arriving peer identities.
-func (t *Tube) NewArrivals() *pubsub.Subscription {
-	return t.view.NewArrivals()
+func (t *Tube) NewArrivals(from int64, match pubsub.Match) (*pubsub.Subscription, error) {
+	return t.view.NewArrivals(from, match)
 }
 
 // NewDepartures returns a subscription for the stream of departing peer identities.
-func (t *Tube) NewDepartures() *pubsub.Subscription {
-	return t.view.NewDepartures()
+func (t *Tube) NewDepartures(from int64, match pubsub.Match) (*pubsub.Subscription, error) {
+	return t.view.NewDepartures(from, match)
+}
+
+// NewUpdates returns a subscription for the stream of records that change the tube.
+func (t *Tube) NewUpdates(from int64, match pubsub.Match) (*pubsub.Subscription, error) {
+	return t.view.NewUpdates(from, match)
 }
 
+// UpdateSeq returns the sequence number of the most recent update of the tube.
+func (t *Tube) UpdateSeq() int64 {
+	return t.view.UpdateSeq()
+}
 
 func (t *Tube) superscribe(peer tissue.FolkAvatar) {
 	// log.Printf("tube superscribing %s", peer.ID.String())
@@ -101,6 +117,22 @@ func (t *Tube) Write(key string, rev Rev, value interface{}) (changed bool) {
 	return
 }
 
+// WriteSync is like Write, but it returns only after the update has been pushed to the downstream peers.
+func (t *Tube) WriteSync(key string, rev Rev, value interface{}) (changed bool) {
+	t.Lock()
+	changed = t.view.Update(&Record{
+		Key:     key,
+		Rev:     rev,
+		Value:   value,
+		Updated: time.Now(),
+	})
+	t.Unlock()
+	if changed {
+		t.writeSync(key, rev, value)
+	}
+	return
+}
+
 // writeSync pushes an update to our downstream peering tubes.
 func (t *Tube) writeSync(key string, rev Rev, value interface{}) {
 	var wg sync.WaitGroup
@@ -161,18 +193,39 @@ func (t *Tube) Forget(key string, notAfterRev Rev, notAfterUpdated time.Time) bo
 	// }()
 	t.Lock()
 	defer t.Unlock()
-	return t.view.Forget(key, notAfterRev, notAfterUpdated)
+	return t.forget(key, notAfterRev, notAfterUpdated)
+}
+
+// forget removes the record for key from the view, as View.Forget does, and remembers it
+// so that reconciliation with peers does not revive it.
+func (t *Tube) forget(key string, notAfterRev Rev, notAfterUpdated time.Time) bool {
+	r := t.view.Lookup(key)
+	if r == nil || !t.view.Forget(key, notAfterRev, notAfterUpdated) {
+		return false
+	}
+	t.repair.forget(key, r.Rev, time.Now())
+	return true
 }
 
 // Scrub…
 func (t *Tube) Scrub(key string, notAfterRev Rev, notAfterUpdated time.Time) {
 	t.Lock()
 	defer t.Unlock()
-	if t.view.Forget(key, notAfterRev, notAfterUpdated) {
+	if t.forget(key, notAfterRev, notAfterUpdated) {
 		go t.scrubSync(key, notAfterRev, notAfterUpdated)
 	}
 }
 
+// ScrubSync is like Scrub, but it returns only after the scrub has been pushed to the downstream peers.
+func (t *Tube) ScrubSync(key string, notAfterRev Rev, notAfterUpdated time.Time) {
+	t.Lock()
+	forgot := t.forget(key, notAfterRev, notAfterUpdated)
+	t.Unlock()
+	if forgot {
+		t.scrubSync(key, notAfterRev, notAfterUpdated)
+	}
+}
+
 // scrub pushes a notification to scrub a record to our downstream peers.
 func (t *Tube) scrubSync(key string, notAfterRev Rev, notAfterUpdated time.Time) {
 	var wg sync.WaitGroup
@@ -20,6 +20,7 @@ import (
 type View struct {
 	arrive   *pubsub.PubSub
 	depart  *pubsub.PubSub
+	update  *pubsub.PubSub
 	sync.Mutex
 	lkp       map[string]int // record key => record index
 	img      []*Record // Current state of the record space known to us
@@ -31,17 +32,35 @@ func NewView() (v *View) {
 		lkp: make(map[string]int),
 	}
 	v.arrive, v.depart = pubsub.New("join", v.peek), pubsub.New("leave", nil)
+	v.update = pubsub.New("update", v.peek)
 	return
 }
 
 // NewArrivals returns a subscription for the stream of arriving peer identities.
-func (v *View) NewArrivals() *pubsub.Subscription {
-	return v.arrive.Subscribe()
+// If from is positive, the stream resumes after the arrival with sequence number from.
+// If match is not nil, only records it accepts are delivered.
+func (v *View) NewArrivals(from int64, match pubsub.Match) (*pubsub.Subscription, error) {
+	return v.arrive.SubscribeFrom(from, match)
 }
 
 // NewDepartures returns a subscription for the stream of departing peer identities.
-func (v *View) NewDepartures() *pubsub.Subscription {
-	return v.depart.Subscribe()
+// Arguments are as for NewArrivals.
+func (v *View) NewDepartures(from int64, match pubsub.Match) (*pubsub.Subscription, error) {
+	return v.depart.SubscribeFrom(from, match)
+}
+
+// NewUpdates returns a subscription for the stream of records that change the view,
+// both for new and for existing keys. Unless resuming, the stream begins with the current records.
+// Arguments are as for NewArrivals.
+func (v *View) NewUpdates(from int64, match pubsub.Match) (*pubsub.Subscription, error) {
+	return v.update.SubscribeFrom(from, match)
+}
+
+// UpdateSeq returns the sequence number of the most recent update, from which
+// a subscription to updates can resume. Updates are distributed asynchronously,
+// so the state of the view may reflect later updates.
+func (v *View) UpdateSeq() int64 {
+	return v.update.Seq()
 }
 
 // Dump returns a textual representation of the contents of this view
@@ -95,6 +114,7 @@ func (v *View) Update(r *Record) (changed bool) {
 	if !ok { // Report only keys that didn't exist
 		v.arrive.Publish(r.Clone())
 	}
+	v.update.Publish(r.Clone())
 	return true
 }
 
@@ -45,6 +45,22 @@ func (x XTube) Scrub(key string, notAfterRev Rev, notAfterUpdated time.Time) {
 	x.t.Scrub(key, notAfterRev, notAfterUpdated)
 }
 
+func (x XTube) Sum() uint64 {
+	return x.t.Digest().Sum()
+}
+
+func (x XTube) Digest() Digest {
+	return x.t.Digest()
+}
+
+func (x XTube) Pull(buckets []int) []*Record {
+	return x.t.Pull(buckets)
+}
+
+func (x XTube) Merge(bulk []*Record) int {
+	return x.t.Merge(bulk)
+}
+
 // YTube
 type YTube struct {
 	av tissue.FolkAvatar
@@ -8,6 +8,7 @@
 package tissue
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/gocircuit/circuit/kit/lang"
@@ -31,6 +32,43 @@ const (
 	Depth = 3 * 2 // Lazy random walk with stay-put probability one half
 )
 
+// Config holds the topology parameters of the tissue system.
+type Config struct {
+	// A neighborhood smaller than ExpansionLow is refilled with random peers, up to ExpansionHigh peers.
+	// Neighborhoods larger than ExpansionHigh are shrunk by evicting random peers.
+	ExpansionLow  int
+	ExpansionHigh int
+	// Spread is the number of random peers exchanged by two circuit workers when their networks join.
+	Spread int
+	// Depth is the number of random walk steps taken when sampling for a random circuit worker.
+	Depth int
+}
+
+// DefaultConfig holds the default topology parameters.
+var DefaultConfig = Config{
+	ExpansionLow:  ExpansionLow,
+	ExpansionHigh: ExpansionHigh,
+	Spread:        Spread,
+	Depth:         Depth,
+}
+
+// Validate returns an error if the parameters of c are not consistent.
+func (c Config) Validate() error {
+	switch {
+	case c.ExpansionLow < 1:
+		return errors.New("expansion low must be at least 1")
+	case c.ExpansionHigh < c.ExpansionLow:
+		return errors.New("expansion high must be at least expansion low")
+	case c.Spread < 1:
+		return errors.New("spread must be at least 1")
+	case c.Spread > c.ExpansionHigh:
+		return errors.New("spread must not exceed expansion high")
+	case c.Depth < 1:
+		return errors.New("depth must be at least 1")
+	}
+	return nil
+}
+
 // Avatar is a pair of a permanent cross-interface and an ID, identifying its underlying receiver uniquely.
 type Avatar struct {
 	X  circuit.PermX